@@ -19,3 +19,8 @@ TEST_TARGETS="$(eval "go list ./... ${EXCLUDED_TARGETS}")"
 
 # shellcheck disable=SC2086
 go test -tags test -shuffle=on -race -timeout="${TIMEOUT:-120s}" -coverprofile="coverage.out" -covermode="atomic" ${TEST_TARGETS}
+
+# The compression package, including its zstd stream types, falls back to a
+# pure Go zstd implementation when built without cgo. The race detector
+# requires cgo, so it isn't used here.
+CGO_ENABLED=0 go test -tags test -shuffle=on -timeout="${TIMEOUT:-120s}" ./utils/compression/...
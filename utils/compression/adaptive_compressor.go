@@ -0,0 +1,95 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import "fmt"
+
+// Tags that prefix payloads produced by an adaptive compressor. Both ends of an
+// adaptive compressor are fixed, so unlike [NewTaggedCompressor] the tag isn't
+// versioned.
+const (
+	smallTag byte = iota
+	largeTag
+)
+
+var (
+	_ Compressor     = (*adaptiveCompressor)(nil)
+	_ ConfigReporter = (*adaptiveCompressor)(nil)
+)
+
+// NewAdaptiveCompressor returns a Compressor that compresses messages shorter
+// than threshold with small and all other messages with large. Payloads are
+// tagged with the compressor that was used.
+//
+// The reported [CompressorConfig] is that of large, if it reports one, with the
+// threshold. If large reports a max size, it also bounds the messages
+// decompressed by small, so a peer can't bypass it by tagging a payload as
+// small.
+func NewAdaptiveCompressor(threshold int, small, large Compressor) Compressor {
+	var config CompressorConfig
+	if reporter, ok := large.(ConfigReporter); ok {
+		config = reporter.Config()
+	}
+	config.Threshold = threshold
+	return &adaptiveCompressor{
+		threshold: threshold,
+		config:    config,
+		small:     small,
+		large:     large,
+	}
+}
+
+// NewThresholdCompressor returns a Compressor that sends messages shorter than
+// threshold uncompressed and compresses all other messages with compressor.
+//
+// Payloads are tagged as with [NewAdaptiveCompressor], so the overhead of an
+// uncompressed message is exactly one byte.
+func NewThresholdCompressor(threshold int, compressor Compressor) Compressor {
+	return NewAdaptiveCompressor(threshold, NewNoCompressor(), compressor)
+}
+
+type adaptiveCompressor struct {
+	threshold int
+	config    CompressorConfig
+	small     Compressor
+	large     Compressor
+}
+
+func (a *adaptiveCompressor) Compress(msg []byte) ([]byte, error) {
+	tag, compressor := largeTag, a.large
+	if len(msg) < a.threshold {
+		tag, compressor = smallTag, a.small
+	}
+	compressed, err := compressor.Compress(msg)
+	if err != nil {
+		return nil, err
+	}
+	return withFlag(tag, compressed), nil
+}
+
+func (a *adaptiveCompressor) Decompress(msg []byte) ([]byte, error) {
+	if len(msg) == 0 {
+		return nil, fmt.Errorf("%w: missing tag", ErrInvalidFormat)
+	}
+
+	switch tag, payload := msg[0], msg[1:]; tag {
+	case smallTag:
+		decompressed, err := a.small.Decompress(payload)
+		if err != nil {
+			return nil, err
+		}
+		if maxSize := a.config.MaxSize; maxSize > 0 && int64(len(decompressed)) > maxSize {
+			return nil, fmt.Errorf("%w: (%d) > (%d)", ErrDecompressedMsgTooLarge, len(decompressed), maxSize)
+		}
+		return decompressed, nil
+	case largeTag:
+		return a.large.Decompress(payload)
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnknownTag, tag)
+	}
+}
+
+func (a *adaptiveCompressor) Config() CompressorConfig {
+	return a.config
+}
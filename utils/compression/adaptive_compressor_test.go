@@ -0,0 +1,112 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestAdaptiveCompressor(t *testing.T) {
+	const threshold = units.KiB
+
+	tests := []struct {
+		size        int
+		expectedTag byte
+	}{
+		{
+			size:        1,
+			expectedTag: smallTag,
+		},
+		{
+			size:        threshold - 1,
+			expectedTag: smallTag,
+		},
+		{
+			size:        threshold,
+			expectedTag: largeTag,
+		},
+		{
+			size:        threshold + 1,
+			expectedTag: largeTag,
+		},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.size), func(t *testing.T) {
+			require := require.New(t)
+
+			snappyCompressor, err := NewSnappyCompressor(maxMessageSize)
+			require.NoError(err)
+			zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+			require.NoError(err)
+			compressor := NewAdaptiveCompressor(threshold, snappyCompressor, zstdCompressor)
+
+			msg := bytes.Repeat([]byte{1}, test.size)
+			compressed, err := compressor.Compress(msg)
+			require.NoError(err)
+			require.Equal(test.expectedTag, compressed[0])
+
+			var expectedCompressed []byte
+			if test.expectedTag == smallTag {
+				expectedCompressed, err = snappyCompressor.Compress(msg)
+			} else {
+				expectedCompressed, err = zstdCompressor.Compress(msg)
+			}
+			require.NoError(err)
+			require.Equal(expectedCompressed, compressed[1:])
+
+			decompressed, err := compressor.Decompress(compressed)
+			require.NoError(err)
+			require.Equal(msg, decompressed)
+		})
+	}
+}
+
+func TestAdaptiveCompressorUnknownTag(t *testing.T) {
+	compressor := NewAdaptiveCompressor(units.KiB, NewNoCompressor(), NewNoCompressor())
+
+	_, err := compressor.Decompress([]byte{largeTag + 1})
+	require.ErrorIs(t, err, ErrUnknownTag)
+}
+
+func TestThresholdCompressorMaxSize(t *testing.T) {
+	require := require.New(t)
+
+	zstdCompressor, err := NewZstdCompressor(units.KiB)
+	require.NoError(err)
+	compressor := NewThresholdCompressor(64, zstdCompressor)
+
+	// A peer can tag any payload as small, not only those below the threshold.
+	msg := withFlag(smallTag, make([]byte, units.KiB+1))
+	_, err = compressor.Decompress(msg)
+	require.ErrorIs(err, ErrDecompressedMsgTooLarge)
+
+	msg = withFlag(smallTag, make([]byte, units.KiB))
+	decompressed, err := compressor.Decompress(msg)
+	require.NoError(err)
+	require.Len(decompressed, units.KiB)
+}
+
+func TestThresholdCompressorOverhead(t *testing.T) {
+	require := require.New(t)
+
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	compressor := NewThresholdCompressor(units.KiB, zstdCompressor)
+
+	msg := bytes.Repeat([]byte{1}, units.KiB-1)
+	compressed, err := compressor.Compress(msg)
+	require.NoError(err)
+	require.Len(compressed, len(msg)+1)
+	require.Equal(msg, compressed[1:])
+
+	decompressed, err := compressor.Decompress(compressed)
+	require.NoError(err)
+	require.Equal(msg, decompressed)
+}
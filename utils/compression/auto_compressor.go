@@ -0,0 +1,94 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import "fmt"
+
+// Flags that prefix payloads to indicate whether they are compressed.
+const (
+	rawFlag byte = iota
+	compressedFlag
+)
+
+var (
+	_ Compressor    = (*autoCompressor)(nil)
+	_ SizeEstimator = (*autoCompressor)(nil)
+)
+
+// NewAutoCompressor returns a Compressor that only uses compressor if doing so
+// shrinks the message. Payloads are prefixed with a flag byte indicating
+// whether they were compressed, so the result is never more than one byte
+// larger than the original message.
+func NewAutoCompressor(compressor Compressor) Compressor {
+	return &autoCompressor{
+		compressor: compressor,
+	}
+}
+
+type autoCompressor struct {
+	compressor Compressor
+}
+
+func (a *autoCompressor) Compress(msg []byte) ([]byte, error) {
+	out, compressed, err := CompressChecked(a.compressor, msg)
+	if err != nil {
+		return nil, err
+	}
+	if !compressed {
+		return withFlag(rawFlag, out), nil
+	}
+	return withFlag(compressedFlag, out), nil
+}
+
+func (a *autoCompressor) Decompress(msg []byte) ([]byte, error) {
+	return decompressFlagged(a.compressor, msg)
+}
+
+func (*autoCompressor) EstimateCompressedSize(msg []byte) int {
+	return 1 + len(msg)
+}
+
+// CompressChecked compresses msg with compressor and reports whether doing so
+// shrank it. If the compressed output isn't smaller than msg, msg itself is
+// returned along with false.
+func CompressChecked(compressor Compressor, msg []byte) ([]byte, bool, error) {
+	compressed, err := compressor.Compress(msg)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(compressed) >= len(msg) {
+		return msg, false, nil
+	}
+	return compressed, true, nil
+}
+
+// withFlag returns payload prefixed with flag.
+func withFlag(flag byte, payload []byte) []byte {
+	flagged := make([]byte, 1+len(payload))
+	flagged[0] = flag
+	copy(flagged[1:], payload)
+	return flagged
+}
+
+// decompressFlagged decompresses a payload prefixed with a flag byte, using
+// compressor if the flag indicates that the payload is compressed. Raw
+// payloads are held to the max size of compressor, if it reports one, so that
+// wrapping a compressor doesn't lift its limit.
+func decompressFlagged(compressor Compressor, msg []byte) ([]byte, error) {
+	if len(msg) == 0 {
+		return nil, fmt.Errorf("%w: missing flag", ErrInvalidFormat)
+	}
+
+	switch flag, payload := msg[0], msg[1:]; flag {
+	case rawFlag:
+		if maxSize, ok := reportedMaxSize(compressor); ok && int64(len(payload)) > maxSize {
+			return nil, fmt.Errorf("%w: (%d) > (%d)", ErrDecompressedMsgTooLarge, len(payload), maxSize)
+		}
+		return payload, nil
+	case compressedFlag:
+		return compressor.Decompress(payload)
+	default:
+		return nil, fmt.Errorf("%w: unknown flag %d", ErrInvalidFormat, flag)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestAutoCompressor(t *testing.T) {
+	tests := []struct {
+		name         string
+		msg          []byte
+		expectedFlag byte
+	}{
+		{
+			name:         "compressible",
+			msg:          bytes.Repeat([]byte("avalanche"), units.KiB),
+			expectedFlag: compressedFlag,
+		},
+		{
+			name:         "incompressible",
+			msg:          utils.RandomBytes(units.KiB),
+			expectedFlag: rawFlag,
+		},
+		{
+			name:         "empty",
+			msg:          []byte{},
+			expectedFlag: rawFlag,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+			require.NoError(err)
+			compressor := NewAutoCompressor(zstdCompressor)
+
+			compressed, err := compressor.Compress(test.msg)
+			require.NoError(err)
+			require.Equal(test.expectedFlag, compressed[0])
+			require.LessOrEqual(len(compressed), compressor.(SizeEstimator).EstimateCompressedSize(test.msg))
+
+			decompressed, err := compressor.Decompress(compressed)
+			require.NoError(err)
+			require.Equal(test.msg, decompressed)
+		})
+	}
+}
+
+func TestAutoCompressorInvalidFlag(t *testing.T) {
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(t, err)
+	compressor := NewAutoCompressor(zstdCompressor)
+
+	for _, msg := range [][]byte{nil, {compressedFlag + 1, 1, 2, 3}} {
+		_, err := compressor.Decompress(msg)
+		require.ErrorIs(t, err, ErrInvalidFormat)
+	}
+}
+
+func TestCompressChecked(t *testing.T) {
+	tests := []struct {
+		name               string
+		msg                []byte
+		expectedCompressed bool
+	}{
+		{
+			name:               "compressible",
+			msg:                bytes.Repeat([]byte("avalanche"), units.KiB),
+			expectedCompressed: true,
+		},
+		{
+			name:               "incompressible",
+			msg:                utils.RandomBytes(units.KiB),
+			expectedCompressed: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+			require.NoError(err)
+
+			out, compressed, err := CompressChecked(zstdCompressor, test.msg)
+			require.NoError(err)
+			require.Equal(test.expectedCompressed, compressed)
+			if !compressed {
+				require.Equal(test.msg, out)
+				return
+			}
+
+			require.Less(len(out), len(test.msg))
+			decompressed, err := zstdCompressor.Decompress(out)
+			require.NoError(err)
+			require.Equal(test.msg, decompressed)
+		})
+	}
+}
+
+func TestCompressCheckedError(t *testing.T) {
+	_, _, err := CompressChecked(errCompressor{err: errTest}, []byte("avalanche"))
+	require.ErrorIs(t, err, errTest)
+}
@@ -0,0 +1,231 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"io"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+const (
+	// autoTuningSamples is the number of messages in a size bucket that are
+	// compressed at each candidate level before a level is chosen.
+	autoTuningSamples = 4
+
+	// A lower level is chosen over the level with the best ratio if its ratio
+	// is within 1/autoTuningTolerance of the best.
+	autoTuningTolerance = 50
+)
+
+var (
+	_ Compressor       = (*AutoTuningCompressor)(nil)
+	_ StreamCompressor = (*AutoTuningCompressor)(nil)
+	_ AppendCompressor = (*AutoTuningCompressor)(nil)
+	_ SizeEstimator    = (*AutoTuningCompressor)(nil)
+
+	// autoTuningLevels are the candidate levels, in increasing order.
+	autoTuningLevels = [...]int{zstdBestSpeed, zstdDefaultCompression, 9, 19}
+
+	// autoTuningStreamLevel is the index of the level that streams are
+	// compressed with, since their size isn't known in advance.
+	autoTuningStreamLevel = 1
+)
+
+// AutoTuningStats describes how an [AutoTuningCompressor] compresses messages
+// of sizes in [MinSize, 2*MinSize).
+type AutoTuningStats struct {
+	MinSize int
+	// Tuned is true once Level has been chosen for the bucket.
+	Tuned  bool
+	Level  int
+	Levels []LevelStats
+}
+
+// LevelStats are the samples recorded for a candidate level.
+type LevelStats struct {
+	Level    int
+	Samples  int
+	BytesIn  uint64
+	BytesOut uint64
+	Duration time.Duration
+}
+
+// ratio returns the number of compressed bytes per uncompressed byte.
+func (l *LevelStats) ratio() float64 {
+	return float64(l.BytesOut) / float64(max(l.BytesIn, 1))
+}
+
+// AutoTuningCompressor is a zstd Compressor that learns which compression
+// level to use for each power of two message size.
+//
+// While a size bucket is being tuned, its messages are compressed at each
+// candidate level in turn. Afterwards, the bucket sticks to the lowest level
+// whose compression ratio was within 2% of the best ratio, so levels that
+// don't noticeably shrink messages don't cost CPU. Every level produces frames
+// that any zstd [Compressor] can decompress.
+//
+// Unlike other Compressors, Compress isn't deterministic: the same msg may be
+// compressed to different bytes depending on the messages that were
+// compressed before it.
+type AutoTuningCompressor struct {
+	compressors []zstdImplementation
+
+	lock    sync.Mutex
+	buckets [bits.UintSize + 1]autoTuningBucket
+}
+
+type autoTuningBucket struct {
+	tuned      bool
+	levelIndex int
+	levels     [len(autoTuningLevels)]LevelStats
+}
+
+func NewAutoTuningCompressor(maxSize int64) (*AutoTuningCompressor, error) {
+	a := &AutoTuningCompressor{
+		compressors: make([]zstdImplementation, len(autoTuningLevels)),
+	}
+	for i, level := range autoTuningLevels {
+		compressor, err := newDefaultZstdCompressor(maxSize, level)
+		if err != nil {
+			return nil, err
+		}
+		a.compressors[i] = compressor
+	}
+	return a, nil
+}
+
+func (a *AutoTuningCompressor) Compress(msg []byte) ([]byte, error) {
+	return a.AppendCompress(nil, msg)
+}
+
+func (a *AutoTuningCompressor) Decompress(msg []byte) ([]byte, error) {
+	return a.compressors[0].Decompress(msg)
+}
+
+// CompressStream compresses src at the default level. Streams aren't used to
+// tune the levels.
+func (a *AutoTuningCompressor) CompressStream(dst io.Writer, src io.Reader) error {
+	return a.compressors[autoTuningStreamLevel].CompressStream(dst, src)
+}
+
+func (a *AutoTuningCompressor) DecompressStream(dst io.Writer, src io.Reader) error {
+	return a.compressors[0].DecompressStream(dst, src)
+}
+
+func (a *AutoTuningCompressor) AppendCompress(dst, msg []byte) ([]byte, error) {
+	bucket := &a.buckets[bits.Len(uint(len(msg)))]
+
+	a.lock.Lock()
+	levelIndex := bucket.next()
+	a.lock.Unlock()
+
+	start := time.Now()
+	compressed, err := a.compressors[levelIndex].AppendCompress(dst, msg)
+	if err != nil {
+		return nil, err
+	}
+	duration := time.Since(start)
+
+	a.lock.Lock()
+	bucket.record(levelIndex, len(msg), len(compressed)-len(dst), duration)
+	a.lock.Unlock()
+	return compressed, nil
+}
+
+func (a *AutoTuningCompressor) AppendDecompress(dst, msg []byte) ([]byte, error) {
+	return a.compressors[0].AppendDecompress(dst, msg)
+}
+
+func (a *AutoTuningCompressor) EstimateCompressedSize(msg []byte) int {
+	// The bound doesn't depend on the level.
+	return a.compressors[0].EstimateCompressedSize(msg)
+}
+
+// Stats returns the state of every size bucket that has compressed at least
+// one message, ordered by size.
+func (a *AutoTuningCompressor) Stats() []AutoTuningStats {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	var stats []AutoTuningStats
+	for i := range a.buckets {
+		bucket := &a.buckets[i]
+		if bucket.samples() == 0 {
+			continue
+		}
+
+		var minSize int
+		if i > 0 {
+			minSize = 1 << (i - 1)
+		}
+		levels := make([]LevelStats, len(bucket.levels))
+		for j, level := range bucket.levels {
+			levels[j] = level
+			levels[j].Level = autoTuningLevels[j]
+		}
+		stats = append(stats, AutoTuningStats{
+			MinSize: minSize,
+			Tuned:   bucket.tuned,
+			Level:   autoTuningLevels[bucket.levelIndex],
+			Levels:  levels,
+		})
+	}
+	return stats
+}
+
+// next returns the index of the level that the next message should be
+// compressed with.
+func (b *autoTuningBucket) next() int {
+	if b.tuned {
+		return b.levelIndex
+	}
+
+	// Round-robin over the candidate levels while tuning.
+	next := 0
+	for i := range b.levels {
+		if b.levels[i].Samples < b.levels[next].Samples {
+			next = i
+		}
+	}
+	return next
+}
+
+func (b *autoTuningBucket) record(levelIndex, in, out int, duration time.Duration) {
+	level := &b.levels[levelIndex]
+	level.Samples++
+	level.BytesIn += uint64(in)
+	level.BytesOut += uint64(out)
+	level.Duration += duration
+
+	if b.tuned {
+		return
+	}
+	for i := range b.levels {
+		if b.levels[i].Samples < autoTuningSamples {
+			return
+		}
+	}
+
+	best := b.levels[0].ratio()
+	for i := range b.levels {
+		best = min(best, b.levels[i].ratio())
+	}
+	for i := range b.levels {
+		if b.levels[i].ratio() <= best*(1+1.0/autoTuningTolerance) {
+			b.tuned = true
+			b.levelIndex = i
+			return
+		}
+	}
+}
+
+func (b *autoTuningBucket) samples() int {
+	var samples int
+	for i := range b.levels {
+		samples += b.levels[i].Samples
+	}
+	return samples
+}
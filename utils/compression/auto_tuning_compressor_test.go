@@ -0,0 +1,79 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+// newTestText returns size bytes of text made of words from a small
+// vocabulary, which higher zstd levels compress noticeably better.
+func newTestText(rng *rand.Rand, size int) []byte {
+	words := []string{"avalanche", "snowman", "validator", "block", "vertex", "subnet", "chain", "peer", "gossip", "accept"}
+	var b strings.Builder
+	for b.Len() < size {
+		_, _ = b.WriteString(words[rng.Intn(len(words))])
+		_ = b.WriteByte(' ')
+	}
+	return []byte(b.String()[:size])
+}
+
+func TestAutoTuningCompressor(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewAutoTuningCompressor(maxMessageSize)
+	require.NoError(err)
+
+	rng := rand.New(rand.NewSource(0)) //#nosec G404
+	for range len(autoTuningLevels) * autoTuningSamples {
+		for _, msg := range [][]byte{
+			utils.RandomBytes(4 * units.KiB),
+			newTestText(rng, 64*units.KiB),
+		} {
+			compressed, err := compressor.Compress(msg)
+			require.NoError(err)
+			decompressed, err := compressor.Decompress(compressed)
+			require.NoError(err)
+			require.Equal(msg, decompressed)
+		}
+	}
+
+	stats := compressor.Stats()
+	require.Len(stats, 2)
+
+	incompressible := stats[0]
+	require.Equal(4*units.KiB, incompressible.MinSize)
+	require.True(incompressible.Tuned)
+	require.Equal(zstdBestSpeed, incompressible.Level)
+
+	compressible := stats[1]
+	require.Equal(64*units.KiB, compressible.MinSize)
+	require.True(compressible.Tuned)
+	require.Greater(compressible.Level, incompressible.Level)
+	for _, level := range compressible.Levels {
+		require.Equal(autoTuningSamples, level.Samples)
+	}
+}
+
+func TestAutoTuningCompressorLearning(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewAutoTuningCompressor(maxMessageSize)
+	require.NoError(err)
+
+	_, err = compressor.Compress(utils.RandomBytes(units.KiB))
+	require.NoError(err)
+
+	stats := compressor.Stats()
+	require.Len(stats, 1)
+	require.False(stats[0].Tuned)
+	require.Equal(1, stats[0].Levels[0].Samples)
+}
@@ -0,0 +1,114 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+)
+
+// CompressBatch compresses msgs together with compressor, which amortizes the
+// per-message overhead of the compression format and lets redundancy across
+// messages be exploited.
+//
+// The messages are framed as the number of messages followed by each
+// length-prefixed message before being compressed.
+func CompressBatch(compressor Compressor, msgs [][]byte) ([]byte, error) {
+	if uint64(len(msgs)) > math.MaxUint32 {
+		return nil, fmt.Errorf("%w: (%d messages)", ErrMsgTooLarge, len(msgs))
+	}
+
+	size := wrappers.IntLen
+	for _, msg := range msgs {
+		size += wrappers.IntLen + len(msg)
+	}
+	packer := wrappers.Packer{
+		MaxSize: size,
+		Bytes:   make([]byte, 0, size),
+	}
+	packer.PackInt(uint32(len(msgs)))
+	for _, msg := range msgs {
+		packer.PackBytes(msg)
+	}
+	if packer.Errored() {
+		return nil, packer.Err
+	}
+	return compressor.Compress(packer.Bytes)
+}
+
+// DecompressBatch decompresses msg, which must have been produced by
+// [CompressBatch], with decompressor. The returned messages alias a single
+// decompressed buffer.
+func DecompressBatch(decompressor Decompressor, msg []byte) ([][]byte, error) {
+	decompressed, err := decompressor.Decompress(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	packer := wrappers.Packer{
+		Bytes: decompressed,
+	}
+	numMsgs := packer.UnpackInt()
+	if packer.Errored() {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, packer.Err)
+	}
+	// Every message has a length prefix, which bounds the number of messages
+	// before any allocation is made.
+	if maxMsgs := len(decompressed) / wrappers.IntLen; int64(numMsgs) > int64(maxMsgs) {
+		return nil, fmt.Errorf("%w: (%d messages) > (%d messages)", ErrInvalidFormat, numMsgs, maxMsgs)
+	}
+
+	msgs := make([][]byte, numMsgs)
+	for i := range msgs {
+		msgs[i] = packer.UnpackBytes()
+	}
+	if packer.Errored() {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, packer.Err)
+	}
+	if packer.Offset != len(decompressed) {
+		return nil, fmt.Errorf("%w: %w: %d bytes", ErrInvalidFormat, ErrTrailingData, len(decompressed)-packer.Offset)
+	}
+	return msgs, nil
+}
+
+// CompressIndexed compresses each of msgs independently with compressor into
+// a single buffer and returns the offset at which each msg starts, so that any
+// msg can later be decompressed on its own with [DecompressAt].
+//
+// The msgs are written as the frames of a [FrameReader], so data can also be
+// read sequentially.
+func CompressIndexed(compressor Compressor, msgs [][]byte) ([]byte, []int, error) {
+	var (
+		data    []byte
+		offsets = make([]int, len(msgs))
+	)
+	for i, msg := range msgs {
+		offsets[i] = len(data)
+
+		var err error
+		data, err = appendFrame(data, compressor, msg, math.MaxUint32)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return data, offsets, nil
+}
+
+// DecompressAt decompresses the msg that starts at offset in data, which must
+// have been produced by [CompressIndexed]. If the msg was stored
+// uncompressed, the result aliases data.
+func DecompressAt(compressor Compressor, data []byte, offset int) ([]byte, error) {
+	if offset < 0 || offset > len(data)-wrappers.IntLen {
+		return nil, fmt.Errorf("%w: offset (%d) not in [0, %d]", ErrInvalidRange, offset, len(data)-wrappers.IntLen)
+	}
+	frame := data[offset+wrappers.IntLen:]
+	frameLen := binary.BigEndian.Uint32(data[offset:])
+	if uint64(frameLen) > uint64(len(frame)) {
+		return nil, fmt.Errorf("%w: frame length (%d) > (%d)", ErrInvalidFormat, frameLen, len(frame))
+	}
+	return decompressFlagged(compressor, frame[:frameLen])
+}
@@ -0,0 +1,212 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/units"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+)
+
+func TestCompressDecompressBatch(t *testing.T) {
+	tests := []struct {
+		name string
+		msgs [][]byte
+	}{
+		{
+			name: "empty batch",
+			msgs: [][]byte{},
+		},
+		{
+			name: "single message",
+			msgs: [][]byte{
+				newTestDictionaryMessage(0),
+			},
+		},
+		{
+			name: "mixed sizes",
+			msgs: [][]byte{
+				{},
+				{1},
+				newTestDictionaryMessage(1),
+				utils.RandomBytes(units.KiB),
+				newTestDictionaryMessage(2),
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := NewZstdCompressor(maxMessageSize)
+			require.NoError(err)
+
+			compressed, err := CompressBatch(compressor, test.msgs)
+			require.NoError(err)
+
+			msgs, err := DecompressBatch(compressor, compressed)
+			require.NoError(err)
+			require.Equal(test.msgs, msgs)
+		})
+	}
+}
+
+func TestCompressBatchSmallerThanIndividual(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+
+	var (
+		msgs           [][]byte
+		individualSize int
+	)
+	for i := 0; i < 100; i++ {
+		msg := newTestDictionaryMessage(i)
+		require.Less(len(msg), 128)
+		msgs = append(msgs, msg)
+
+		compressed, err := compressor.Compress(msg)
+		require.NoError(err)
+		individualSize += len(compressed)
+	}
+
+	compressed, err := CompressBatch(compressor, msgs)
+	require.NoError(err)
+	require.Less(len(compressed), individualSize/10)
+}
+
+func TestDecompressBatchInvalidFormat(t *testing.T) {
+	pack := func(numMsgs uint32, msgs ...[]byte) []byte {
+		packer := wrappers.Packer{MaxSize: maxMessageSize}
+		packer.PackInt(numMsgs)
+		for _, msg := range msgs {
+			packer.PackBytes(msg)
+		}
+		require.NoError(t, packer.Err)
+		return packer.Bytes
+	}
+
+	tests := []struct {
+		name        string
+		batch       []byte
+		expectedErr error
+	}{
+		{
+			name:        "missing count",
+			batch:       []byte{0, 0},
+			expectedErr: ErrInvalidFormat,
+		},
+		{
+			name:        "too many messages",
+			batch:       pack(2, []byte{1}),
+			expectedErr: ErrInvalidFormat,
+		},
+		{
+			name:        "huge count",
+			batch:       pack(1<<31, []byte{1}),
+			expectedErr: ErrInvalidFormat,
+		},
+		{
+			name:        "trailing data",
+			batch:       append(pack(1, []byte{1}), 0),
+			expectedErr: ErrTrailingData,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// The frame is passed through unchanged so that it can be
+			// constructed directly.
+			_, err := DecompressBatch(NewNoCompressor(), test.batch)
+			require.ErrorIs(t, err, test.expectedErr)
+		})
+	}
+}
+
+func TestCompressIndexed(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	msgs := [][]byte{
+		{},
+		{1},
+		newTestDictionaryMessage(0),
+		utils.RandomBytes(units.KiB),
+		bytes.Repeat([]byte("avalanche"), units.KiB),
+	}
+
+	data, offsets, err := CompressIndexed(compressor, msgs)
+	require.NoError(err)
+	require.Len(offsets, len(msgs))
+
+	// Each msg is decodable on its own, in any order.
+	for i := len(msgs) - 1; i >= 0; i-- {
+		msg, err := DecompressAt(compressor, data, offsets[i])
+		require.NoError(err)
+		require.Equal(msgs[i], msg)
+	}
+
+	// The frames can also be read sequentially.
+	reader := NewFrameReader(bytes.NewReader(data), compressor, math.MaxUint32)
+	for _, expected := range msgs {
+		msg, err := reader.Next()
+		require.NoError(err)
+		require.Equal(expected, msg)
+	}
+	_, err = reader.Next()
+	require.ErrorIs(err, io.EOF)
+}
+
+func TestDecompressAtInvalid(t *testing.T) {
+	compressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(t, err)
+	data, offsets, err := CompressIndexed(compressor, [][]byte{newTestDictionaryMessage(0)})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		data        []byte
+		offset      int
+		expectedErr error
+	}{
+		{
+			name:        "negative offset",
+			data:        data,
+			offset:      -1,
+			expectedErr: ErrInvalidRange,
+		},
+		{
+			name:        "offset past the end",
+			data:        data,
+			offset:      len(data),
+			expectedErr: ErrInvalidRange,
+		},
+		{
+			name:        "truncated frame",
+			data:        data[:len(data)-1],
+			offset:      offsets[0],
+			expectedErr: ErrInvalidFormat,
+		},
+		{
+			name:        "offset within a frame",
+			data:        data,
+			offset:      offsets[0] + 1,
+			expectedErr: ErrInvalidFormat,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := DecompressAt(compressor, test.data, test.offset)
+			require.ErrorIs(t, err, test.expectedErr)
+		})
+	}
+}
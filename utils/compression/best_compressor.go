@@ -0,0 +1,28 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+// BestCompressorFor returns the candidate that compresses sample the
+// smallest. Candidates that fail to compress sample are skipped. If no
+// candidate produces output smaller than sample, a Compressor that performs no
+// compression is returned.
+//
+// Ties are broken in favor of the earlier candidate.
+func BestCompressorFor(sample []byte, candidates []Compressor) Compressor {
+	var (
+		best     Compressor = &noCompressor{}
+		bestSize            = len(sample)
+	)
+	for _, candidate := range candidates {
+		compressed, err := candidate.Compress(sample)
+		if err != nil {
+			continue
+		}
+		if len(compressed) < bestSize {
+			best = candidate
+			bestSize = len(compressed)
+		}
+	}
+	return best
+}
@@ -0,0 +1,130 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+var benchmarkPayloads = map[string][]byte{
+	"random":     utils.RandomBytes(units.KiB),
+	"repetitive": bytes.Repeat([]byte{0}, units.KiB),
+	"json":       bytes.Repeat([]byte(`{"nodeID":"NodeID-111111111111111111116DBWJs","weight":2000,"uptime":0.99},`), 16),
+}
+
+func newBestCompressorCandidates(t testing.TB) []Compressor {
+	require := require.New(t)
+
+	snappyCompressor, err := NewSnappyCompressor(maxMessageSize)
+	require.NoError(err)
+	zstdCompressor, err := NewZstdCompressorWithLevel(maxMessageSize, zstdBestCompression)
+	require.NoError(err)
+	return []Compressor{
+		snappyCompressor,
+		zstdCompressor,
+	}
+}
+
+func TestBestCompressorFor(t *testing.T) {
+	candidates := newBestCompressorCandidates(t)
+	for name, sample := range benchmarkPayloads {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			var (
+				expected     Compressor = &noCompressor{}
+				expectedSize            = len(sample)
+			)
+			for _, candidate := range candidates {
+				compressed, err := candidate.Compress(sample)
+				require.NoError(err)
+				if len(compressed) < expectedSize {
+					expected = candidate
+					expectedSize = len(compressed)
+				}
+			}
+
+			require.Equal(expected, BestCompressorFor(sample, candidates))
+		})
+	}
+}
+
+func TestBestCompressorForPicksSmallest(t *testing.T) {
+	require := require.New(t)
+
+	candidates := newBestCompressorCandidates(t)
+	sample := benchmarkPayloads["json"]
+
+	// zstd at a high level compresses repetitive JSON much better than snappy.
+	require.Equal(candidates[1], BestCompressorFor(sample, candidates))
+}
+
+func TestBestCompressorForFallsBackToNoCompression(t *testing.T) {
+	tests := []struct {
+		name       string
+		sample     []byte
+		candidates []Compressor
+	}{
+		{
+			name:       "no candidates",
+			sample:     benchmarkPayloads["repetitive"],
+			candidates: nil,
+		},
+		{
+			name:       "incompressible",
+			sample:     benchmarkPayloads["random"],
+			candidates: newBestCompressorCandidates(t),
+		},
+		{
+			name:       "candidates fail",
+			sample:     make([]byte, maxMessageSize+1),
+			candidates: newBestCompressorCandidates(t),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.IsType(t, &noCompressor{}, BestCompressorFor(test.sample, test.candidates))
+		})
+	}
+}
+
+func BenchmarkBestCompressorFor(b *testing.B) {
+	candidates := newBestCompressorCandidates(b)
+	for name, sample := range benchmarkPayloads {
+		b.Run(name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				BestCompressorFor(sample, candidates)
+			}
+		})
+	}
+}
+
+func BenchmarkCompressPayloads(b *testing.B) {
+	for name, newCompressorFunc := range newCompressorFuncs {
+		if name == TypeNone.String() {
+			continue
+		}
+		for payloadName, payload := range benchmarkPayloads {
+			b.Run(fmt.Sprintf("%s_%s", name, payloadName), func(b *testing.B) {
+				require := require.New(b)
+
+				compressor, err := newCompressorFunc(maxMessageSize)
+				require.NoError(err)
+
+				b.SetBytes(int64(len(payload)))
+				for n := 0; n < b.N; n++ {
+					_, err := compressor.Compress(payload)
+					require.NoError(err)
+				}
+			})
+		}
+	}
+}
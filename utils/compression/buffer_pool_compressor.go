@@ -0,0 +1,76 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+var _ Compressor = (*bufferPoolCompressor)(nil)
+
+// NewBufferPoolCompressor returns a Compressor that allocates its results from
+// an external byte-slice pool rather than the heap.
+//
+// get must return a slice with a capacity of at least the requested size.
+// Results are backed by slices returned from get, and callers should return
+// them with put once they are no longer used. If a result doesn't fit in the
+// slice returned from get, the slice is returned with put and the result is
+// allocated by compressor instead.
+//
+// Compressed results are sized with [SizeEstimator] if compressor implements
+// it. Decompressed sizes aren't known in advance, so decompressed results
+// start from a slice of the compressed size.
+func NewBufferPoolCompressor(
+	compressor AppendCompressor,
+	get func(int) []byte,
+	put func([]byte),
+) Compressor {
+	b := &bufferPoolCompressor{
+		compressor: compressor,
+		get:        get,
+		put:        put,
+	}
+	if estimator, ok := compressor.(SizeEstimator); ok {
+		b.estimateCompressedSize = estimator.EstimateCompressedSize
+	} else {
+		b.estimateCompressedSize = func(msg []byte) int {
+			return len(msg)
+		}
+	}
+	return b
+}
+
+type bufferPoolCompressor struct {
+	compressor             AppendCompressor
+	get                    func(int) []byte
+	put                    func([]byte)
+	estimateCompressedSize func([]byte) int
+}
+
+func (b *bufferPoolCompressor) Compress(msg []byte) ([]byte, error) {
+	return b.append(b.estimateCompressedSize(msg), msg, b.compressor.AppendCompress)
+}
+
+func (b *bufferPoolCompressor) Decompress(msg []byte) ([]byte, error) {
+	return b.append(len(msg), msg, b.compressor.AppendDecompress)
+}
+
+func (b *bufferPoolCompressor) append(
+	size int,
+	msg []byte,
+	appendFunc func(dst, msg []byte) ([]byte, error),
+) ([]byte, error) {
+	buf := b.get(size)
+	result, err := appendFunc(buf[:0], msg)
+	if err != nil {
+		b.put(buf)
+		return nil, err
+	}
+	if !sharesArray(result, buf) {
+		// The result didn't fit, so buf is unused.
+		b.put(buf)
+	}
+	return result, nil
+}
+
+// sharesArray returns true if a and b are backed by the same array.
+func sharesArray(a, b []byte) bool {
+	return cap(a) > 0 && cap(b) > 0 && &a[:cap(a)][cap(a)-1] == &b[:cap(b)][cap(b)-1]
+}
@@ -0,0 +1,91 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+// testBufferPool records the buffers it hands out and gets back. Every buffer
+// it returns has a capacity of extra bytes more than requested.
+type testBufferPool struct {
+	extra int
+	gets  int
+	puts  [][]byte
+}
+
+func (p *testBufferPool) get(size int) []byte {
+	p.gets++
+	return make([]byte, 0, max(size+p.extra, 0))
+}
+
+func (p *testBufferPool) put(buf []byte) {
+	p.puts = append(p.puts, buf)
+}
+
+func TestBufferPoolCompressor(t *testing.T) {
+	require := require.New(t)
+
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	pool := &testBufferPool{}
+	compressor := NewBufferPoolCompressor(zstdCompressor.(AppendCompressor), pool.get, pool.put)
+
+	msg := bytes.Repeat([]byte("avalanche"), units.KiB)
+	compressed, err := compressor.Compress(msg)
+	require.NoError(err)
+	// The compressed size is estimated, so the pooled buffer is used.
+	require.Equal(1, pool.gets)
+	require.Empty(pool.puts)
+
+	decompressed, err := compressor.Decompress(compressed)
+	require.NoError(err)
+	require.Equal(msg, decompressed)
+	// The decompressed msg doesn't fit in a buffer of the compressed size, so
+	// the pooled buffer is given back.
+	require.Equal(2, pool.gets)
+	require.Len(pool.puts, 1)
+	require.False(sharesArray(decompressed, pool.puts[0]))
+}
+
+func TestBufferPoolCompressorFits(t *testing.T) {
+	require := require.New(t)
+
+	pool := &testBufferPool{
+		extra: maxMessageSize,
+	}
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	compressor := NewBufferPoolCompressor(zstdCompressor.(AppendCompressor), pool.get, pool.put)
+
+	msg := bytes.Repeat([]byte("avalanche"), units.KiB)
+	compressed, err := compressor.Compress(msg)
+	require.NoError(err)
+	decompressed, err := compressor.Decompress(compressed)
+	require.NoError(err)
+	require.Equal(msg, decompressed)
+
+	// Both results are backed by pooled buffers, so none are given back.
+	require.Equal(2, pool.gets)
+	require.Empty(pool.puts)
+}
+
+func TestBufferPoolCompressorError(t *testing.T) {
+	require := require.New(t)
+
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	pool := &testBufferPool{}
+	compressor := NewBufferPoolCompressor(zstdCompressor.(AppendCompressor), pool.get, pool.put)
+
+	_, err = compressor.Decompress([]byte{0xff, 0xff, 0xff, 0xff, 0xff})
+	require.ErrorIs(err, ErrInvalidFormat)
+	require.Equal(1, pool.gets)
+	require.Len(pool.puts, 1)
+}
@@ -0,0 +1,64 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+)
+
+var (
+	_ Compressor = (*checksumCompressor)(nil)
+
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+
+	crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// NewChecksumCompressor returns a Compressor that prefixes messages compressed
+// by compressor with a CRC-32C of the uncompressed message, which is verified
+// on Decompress.
+//
+// The zstd format only optionally includes a checksum, which isn't written by
+// the zstd Compressor, so corruption may otherwise go undetected.
+func NewChecksumCompressor(compressor Compressor) Compressor {
+	return &checksumCompressor{
+		compressor: compressor,
+	}
+}
+
+type checksumCompressor struct {
+	compressor Compressor
+}
+
+func (c *checksumCompressor) Compress(msg []byte) ([]byte, error) {
+	compressed, err := c.compressor.Compress(msg)
+	if err != nil {
+		return nil, err
+	}
+	checksummed := make([]byte, wrappers.IntLen+len(compressed))
+	binary.BigEndian.PutUint32(checksummed, crc32.Checksum(msg, crc32cTable))
+	copy(checksummed[wrappers.IntLen:], compressed)
+	return checksummed, nil
+}
+
+func (c *checksumCompressor) Decompress(msg []byte) ([]byte, error) {
+	if len(msg) < wrappers.IntLen {
+		return nil, fmt.Errorf("%w: missing checksum", ErrInvalidFormat)
+	}
+
+	decompressed, err := c.compressor.Decompress(msg[wrappers.IntLen:])
+	if err != nil {
+		return nil, err
+	}
+	expected := binary.BigEndian.Uint32(msg)
+	if actual := crc32.Checksum(decompressed, crc32cTable); actual != expected {
+		return nil, fmt.Errorf("%w: expected %#08x but got %#08x", ErrChecksumMismatch, expected, actual)
+	}
+	return decompressed, nil
+}
@@ -0,0 +1,64 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksumCompressor(t *testing.T) {
+	require := require.New(t)
+
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	compressor := NewChecksumCompressor(zstdCompressor)
+
+	msg := bytes.Repeat([]byte("avalanche"), 1024)
+	compressed, err := compressor.Compress(msg)
+	require.NoError(err)
+
+	decompressed, err := compressor.Decompress(compressed)
+	require.NoError(err)
+	require.Equal(msg, decompressed)
+
+	// A bit flip must never result in a different message being returned.
+	// Some bits of the zstd frame header don't affect the decompressed output,
+	// so flipping them is allowed to succeed.
+	for i := range compressed {
+		corrupted := slices.Clone(compressed)
+		corrupted[i] ^= 0x01
+
+		decompressed, err := compressor.Decompress(corrupted)
+		switch {
+		case err == nil:
+			require.Equal(msg, decompressed, "byte %d", i)
+		case !errors.Is(err, ErrInvalidFormat):
+			require.ErrorIs(err, ErrChecksumMismatch, "byte %d", i)
+		}
+	}
+}
+
+func TestChecksumCompressorMismatch(t *testing.T) {
+	require := require.New(t)
+
+	// Without compression, corrupted payloads can only be detected by the
+	// checksum.
+	compressor := NewChecksumCompressor(NewNoCompressor())
+
+	msg := []byte("avalanche")
+	compressed, err := compressor.Compress(msg)
+	require.NoError(err)
+
+	compressed[len(compressed)-1] ^= 0x01
+	_, err = compressor.Decompress(compressed)
+	require.ErrorIs(err, ErrChecksumMismatch)
+
+	_, err = compressor.Decompress(compressed[:3])
+	require.ErrorIs(err, ErrInvalidFormat)
+}
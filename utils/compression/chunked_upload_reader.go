@@ -0,0 +1,77 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+var _ io.Reader = (*chunkedUploadReader)(nil)
+
+// NewChunkedUploadReader returns a reader of the frames produced by splitting
+// src into chunks of chunkSize bytes, except for the last chunk which may be
+// shorter, and compressing each chunk separately. The frames can be read back
+// with a [FrameReader], and can be decompressed independently of each other.
+//
+// This allows large inputs, such as snapshots, to be streamed to storage with
+// io.Copy without holding more than one chunk in memory.
+func NewChunkedUploadReader(src io.Reader, chunkSize int, compressor Compressor) (io.Reader, error) {
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidBlockSize, chunkSize)
+	}
+	return &chunkedUploadReader{
+		src:        src,
+		compressor: compressor,
+		chunk:      make([]byte, chunkSize),
+	}, nil
+}
+
+type chunkedUploadReader struct {
+	src        io.Reader
+	compressor Compressor
+	chunk      []byte
+	// frame holds the compressed chunk that hasn't been read yet.
+	frame []byte
+	// err is returned once all the frames have been read.
+	err error
+}
+
+func (c *chunkedUploadReader) Read(p []byte) (int, error) {
+	for len(c.frame) == 0 {
+		if c.err != nil {
+			return 0, c.err
+		}
+		c.nextFrame()
+	}
+	n := copy(p, c.frame)
+	c.frame = c.frame[n:]
+	return n, nil
+}
+
+// nextFrame reads the next chunk from src and compresses it. If src ends, the
+// frame of the final chunk is still produced before [io.EOF] is returned.
+func (c *chunkedUploadReader) nextFrame() {
+	n, err := io.ReadFull(c.src, c.chunk)
+	switch {
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		c.err = io.EOF
+	case err != nil:
+		c.err = err
+		return
+	}
+	if n == 0 {
+		return
+	}
+
+	// Frames are only limited by what their length prefix can encode.
+	frame, err := appendFrame(c.frame[:0], c.compressor, c.chunk[:n], math.MaxUint32)
+	if err != nil {
+		c.err = err
+		return
+	}
+	c.frame = frame
+}
@@ -0,0 +1,77 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"math/rand"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestChunkedUploadReader(t *testing.T) {
+	const chunkSize = 64 * units.KiB
+
+	rng := rand.New(rand.NewSource(0)) // #nosec G404
+	src := newTestText(rng, units.MiB+1)
+	compressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(t, err)
+
+	sources := map[string]io.Reader{
+		"bytes":    bytes.NewReader(src),
+		"one byte": iotest.OneByteReader(bytes.NewReader(src)),
+	}
+	for name, source := range sources {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			reader, err := NewChunkedUploadReader(source, chunkSize, compressor)
+			require.NoError(err)
+
+			var uploaded bytes.Buffer
+			_, err = io.Copy(&uploaded, reader)
+			require.NoError(err)
+
+			// Every chunk can be decompressed on its own.
+			frames := NewFrameReader(&uploaded, compressor, math.MaxUint32)
+			for start := 0; start < len(src); start += chunkSize {
+				chunk, err := frames.Next()
+				require.NoError(err)
+				require.Equal(src[start:min(start+chunkSize, len(src))], chunk)
+			}
+			_, err = frames.Next()
+			require.Equal(io.EOF, err)
+		})
+	}
+}
+
+func TestChunkedUploadReaderEmpty(t *testing.T) {
+	require := require.New(t)
+
+	reader, err := NewChunkedUploadReader(bytes.NewReader(nil), units.KiB, NewNoCompressor())
+	require.NoError(err)
+
+	uploaded, err := io.ReadAll(reader)
+	require.NoError(err)
+	require.Empty(uploaded)
+}
+
+func TestChunkedUploadReaderSourceError(t *testing.T) {
+	reader, err := NewChunkedUploadReader(errReader{err: errTest}, units.KiB, NewNoCompressor())
+	require.NoError(t, err)
+
+	_, err = io.ReadAll(reader)
+	require.ErrorIs(t, err, errTest)
+}
+
+func TestNewChunkedUploadReaderInvalidChunkSize(t *testing.T) {
+	_, err := NewChunkedUploadReader(bytes.NewReader(nil), 0, NewNoCompressor())
+	require.ErrorIs(t, err, ErrInvalidBlockSize)
+}
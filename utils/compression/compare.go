@@ -0,0 +1,79 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// AlgoResult is the result of compressing a sample with one algorithm.
+type AlgoResult struct {
+	// Name is the name the algorithm is registered with.
+	Name string
+	// CompressedSize is the length of the compressed sample.
+	CompressedSize int
+	// Ratio is the length of the sample divided by CompressedSize, so higher
+	// is better.
+	Ratio              float64
+	CompressDuration   time.Duration
+	DecompressDuration time.Duration
+	// Err is set if the algorithm failed to round trip the sample, in which
+	// case the other results are unset.
+	Err error
+}
+
+// CompareAlgorithms compresses and decompresses sample with every registered
+// compressor and returns the results sorted by name.
+//
+// Each algorithm is only run once, so durations are indicative. Callers that
+// need precise timings should use a benchmark.
+func CompareAlgorithms(sample []byte) []AlgoResult {
+	names := registeredNames()
+	results := make([]AlgoResult, len(names))
+	for i, name := range names {
+		results[i] = compareAlgorithm(name, sample)
+	}
+	return results
+}
+
+func compareAlgorithm(name string, sample []byte) AlgoResult {
+	result := AlgoResult{
+		Name: name,
+	}
+	compressor, err := NewCompressorByName(name, int64(len(sample)))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	start := time.Now()
+	compressed, err := compressor.Compress(sample)
+	compressDuration := time.Since(start)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	start = time.Now()
+	decompressed, err := compressor.Decompress(compressed)
+	decompressDuration := time.Since(start)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if !bytes.Equal(sample, decompressed) {
+		result.Err = fmt.Errorf("%w: decompressed (%d) bytes, expected (%d)", ErrRoundTripMismatch, len(decompressed), len(sample))
+		return result
+	}
+
+	result.CompressedSize = len(compressed)
+	if len(compressed) != 0 {
+		result.Ratio = float64(len(sample)) / float64(len(compressed))
+	}
+	result.CompressDuration = compressDuration
+	result.DecompressDuration = decompressDuration
+	return result
+}
@@ -0,0 +1,93 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestCompareAlgorithms(t *testing.T) {
+	require := require.New(t)
+
+	sample := bytes.Repeat([]byte("avalanche"), 10*units.KiB)
+	results := CompareAlgorithms(sample)
+
+	names := make([]string, len(results))
+	for i, result := range results {
+		names[i] = result.Name
+
+		require.NoError(result.Err)
+		require.Positive(result.CompressedSize)
+		require.Positive(result.Ratio)
+	}
+	require.Equal(registeredNames(), names)
+}
+
+func TestCompareAlgorithmsReportsErrors(t *testing.T) {
+	require := require.New(t)
+
+	const name = "test"
+	t.Cleanup(func() {
+		registryLock.Lock()
+		defer registryLock.Unlock()
+
+		delete(registry, name)
+	})
+	require.NoError(RegisterCompressor(name, func(int64) (Compressor, error) {
+		return errCompressor{err: errTest}, nil
+	}))
+
+	for _, result := range CompareAlgorithms([]byte("avalanche")) {
+		if result.Name == name {
+			require.ErrorIs(result.Err, errTest)
+			require.Zero(result.CompressedSize)
+		} else {
+			require.NoError(result.Err)
+		}
+	}
+}
+
+// corruptingCompressor flips the first bit of every decompressed msg.
+type corruptingCompressor struct {
+	Compressor
+}
+
+func (c corruptingCompressor) Decompress(msg []byte) ([]byte, error) {
+	decompressed, err := c.Compressor.Decompress(msg)
+	if err != nil || len(decompressed) == 0 {
+		return decompressed, err
+	}
+	decompressed = bytes.Clone(decompressed)
+	decompressed[0] ^= 1
+	return decompressed, nil
+}
+
+func TestCompareAlgorithmsReportsMismatch(t *testing.T) {
+	require := require.New(t)
+
+	const name = "test"
+	t.Cleanup(func() {
+		registryLock.Lock()
+		defer registryLock.Unlock()
+
+		delete(registry, name)
+	})
+	require.NoError(RegisterCompressor(name, func(int64) (Compressor, error) {
+		return corruptingCompressor{Compressor: NewNoCompressor()}, nil
+	}))
+
+	for _, result := range CompareAlgorithms([]byte("avalanche")) {
+		if result.Name == name {
+			require.ErrorIs(result.Err, ErrRoundTripMismatch)
+			require.Zero(result.CompressedSize)
+		} else {
+			require.NoError(result.Err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"net"
+	"sync"
+)
+
+var _ net.Conn = (*compressedConn)(nil)
+
+// NewCompressedConn returns a connection that compresses each Write to conn as
+// a single frame and decompresses frames read from conn. Both ends of the
+// connection must be wrapped with the same compressor.
+//
+// See [FrameReader] for the format of frames. Frames with a length larger than
+// maxFrameSize are rejected with [ErrMsgTooLarge] when writing and
+// [ErrInvalidFormat] when reading, which bounds the memory a peer can make us
+// allocate.
+//
+// Reads return [io.EOF] if conn ends between frames and
+// [io.ErrUnexpectedEOF] if it ends within one. An error from Write may leave a
+// partial frame on conn, after which the connection should be closed.
+func NewCompressedConn(conn net.Conn, compressor Compressor, maxFrameSize uint32) net.Conn {
+	return &compressedConn{
+		Conn:         conn,
+		compressor:   compressor,
+		maxFrameSize: maxFrameSize,
+		frames:       NewFrameReader(conn, compressor, maxFrameSize),
+	}
+}
+
+type compressedConn struct {
+	net.Conn
+	compressor   Compressor
+	maxFrameSize uint32
+
+	// writeLock ensures that concurrent frames aren't interleaved.
+	writeLock sync.Mutex
+
+	readLock sync.Mutex
+	frames   *FrameReader
+	// pending holds the decompressed bytes of the last frame that haven't
+	// been read yet.
+	pending []byte
+}
+
+func (c *compressedConn) Write(p []byte) (int, error) {
+	frame, err := appendFrame(nil, c.compressor, p, c.maxFrameSize)
+	if err != nil {
+		return 0, err
+	}
+
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	if _, err := c.Conn.Write(frame); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *compressedConn) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	c.readLock.Lock()
+	defer c.readLock.Unlock()
+
+	// Empty frames are skipped so that Read doesn't return 0 bytes without
+	// an error.
+	for len(c.pending) == 0 {
+		msg, err := c.frames.Next()
+		if err != nil {
+			return 0, err
+		}
+		c.pending = msg
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
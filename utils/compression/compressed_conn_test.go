@@ -0,0 +1,140 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func newTestCompressedConns(t *testing.T) (net.Conn, net.Conn) {
+	compressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(t, err)
+
+	client, server := net.Pipe()
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+	return NewCompressedConn(client, compressor, maxMessageSize), NewCompressedConn(server, compressor, maxMessageSize)
+}
+
+func TestCompressedConn(t *testing.T) {
+	require := require.New(t)
+
+	client, server := newTestCompressedConns(t)
+
+	msgs := [][]byte{
+		{1},
+		{},
+		newTestDictionaryMessage(0),
+		bytes.Repeat([]byte("avalanche"), 100*units.KiB),
+		utils.RandomBytes(10 * units.KiB),
+	}
+	errs := make(chan error, 1)
+	go func() {
+		for _, msg := range msgs {
+			if _, err := client.Write(msg); err != nil {
+				errs <- err
+				return
+			}
+		}
+		errs <- client.Close()
+	}()
+
+	// Messages are read across frame boundaries with a small buffer.
+	var received bytes.Buffer
+	_, err := io.CopyBuffer(&received, struct{ io.Reader }{server}, make([]byte, 1000))
+	require.NoError(err)
+	require.NoError(<-errs)
+	require.Equal(bytes.Join(msgs, nil), received.Bytes())
+}
+
+func TestCompressedConnSendsIncompressibleRaw(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	client, server := net.Pipe()
+	defer server.Close()
+	conn := NewCompressedConn(client, compressor, maxMessageSize)
+
+	msg := utils.RandomBytes(units.KiB)
+	go func() {
+		_, _ = conn.Write(msg)
+		_ = conn.Close()
+	}()
+
+	frame, err := io.ReadAll(server)
+	require.NoError(err)
+	require.Len(frame, 4+1+len(msg))
+	require.Equal(rawFlag, frame[4])
+	require.Equal(msg, frame[5:])
+}
+
+func TestCompressedConnReadErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		raw         []byte
+		expectedErr error
+	}{
+		{
+			name:        "clean EOF",
+			raw:         nil,
+			expectedErr: io.EOF,
+		},
+		{
+			name:        "partial header",
+			raw:         []byte{0, 0},
+			expectedErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:        "partial frame",
+			raw:         []byte{0, 0, 0, 10, rawFlag, 1},
+			expectedErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:        "frame too large",
+			raw:         []byte{0xff, 0xff, 0xff, 0xff},
+			expectedErr: ErrInvalidFormat,
+		},
+		{
+			name:        "unknown flag",
+			raw:         []byte{0, 0, 0, 1, 0xff},
+			expectedErr: ErrInvalidFormat,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer server.Close()
+			conn := NewCompressedConn(server, NewNoCompressor(), units.KiB)
+
+			go func() {
+				_, _ = client.Write(test.raw)
+				_ = client.Close()
+			}()
+
+			_, err := conn.Read(make([]byte, units.KiB))
+			require.ErrorIs(t, err, test.expectedErr)
+		})
+	}
+}
+
+func TestCompressedConnWriteTooLarge(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	conn := NewCompressedConn(client, NewNoCompressor(), units.KiB)
+	_, err := conn.Write(make([]byte, units.KiB))
+	require.ErrorIs(t, err, ErrMsgTooLarge)
+}
@@ -0,0 +1,72 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import "slices"
+
+// PolicyRule selects the compressor registered with Compressor for msgs of
+// type MsgType. If SizeBelow is positive, the rule only applies to msgs
+// shorter than SizeBelow bytes.
+type PolicyRule struct {
+	MsgType    uint16
+	SizeBelow  int
+	Compressor string
+}
+
+// CompressionPolicy chooses the compressor to use for a msg based on its type
+// and size, so that selection logic is configured in one place.
+//
+// CompressionPolicy is safe for concurrent use.
+type CompressionPolicy struct {
+	rules       []PolicyRule
+	defaultName string
+	// compressors contains the compressor created for each name, so that
+	// every compressor is only created once.
+	compressors map[string]Compressor
+}
+
+// NewCompressionPolicy returns a policy that applies the first matching rule
+// of rules, and uses the compressor registered with defaultName for msgs that
+// match no rule. Every compressor is created up front with maxSize, so names
+// that aren't registered are reported here rather than when choosing. rules is
+// copied, so it can be modified afterwards.
+func NewCompressionPolicy(rules []PolicyRule, defaultName string, maxSize int64) (*CompressionPolicy, error) {
+	p := &CompressionPolicy{
+		rules:       slices.Clone(rules),
+		defaultName: defaultName,
+		compressors: make(map[string]Compressor),
+	}
+	if err := p.create(defaultName, maxSize); err != nil {
+		return nil, err
+	}
+	for _, rule := range p.rules {
+		if err := p.create(rule.Compressor, maxSize); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *CompressionPolicy) create(name string, maxSize int64) error {
+	if _, ok := p.compressors[name]; ok {
+		return nil
+	}
+	compressor, err := NewCompressorByName(name, maxSize)
+	if err != nil {
+		return err
+	}
+	p.compressors[name] = compressor
+	return nil
+}
+
+// Choose returns the compressor to use for a msg of msgType that is size bytes
+// long.
+func (p *CompressionPolicy) Choose(msgType uint16, size int) Compressor {
+	for _, rule := range p.rules {
+		if rule.MsgType == msgType && (rule.SizeBelow <= 0 || size < rule.SizeBelow) {
+			return p.compressors[rule.Compressor]
+		}
+	}
+	return p.compressors[p.defaultName]
+}
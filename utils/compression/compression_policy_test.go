@@ -0,0 +1,130 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestCompressionPolicy(t *testing.T) {
+	const (
+		gossipType uint16 = iota
+		blockType
+		encryptedType
+		otherType
+	)
+
+	policy, err := NewCompressionPolicy(
+		[]PolicyRule{
+			{
+				MsgType:    gossipType,
+				SizeBelow:  units.KiB,
+				Compressor: SnappyName,
+			},
+			{
+				MsgType:    blockType,
+				Compressor: TypeZstd.String(),
+			},
+			{
+				MsgType:    encryptedType,
+				Compressor: TypeNone.String(),
+			},
+		},
+		TypeZstd.String(),
+		maxMessageSize,
+	)
+	require.NoError(t, err)
+
+	snappyCompressor, err := NewSnappyCompressor(maxMessageSize)
+	require.NoError(t, err)
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name               string
+		msgType            uint16
+		size               int
+		expectedCompressor Compressor
+	}{
+		{
+			name:               "small gossip",
+			msgType:            gossipType,
+			size:               units.KiB - 1,
+			expectedCompressor: snappyCompressor,
+		},
+		{
+			name:               "large gossip",
+			msgType:            gossipType,
+			size:               units.KiB,
+			expectedCompressor: zstdCompressor,
+		},
+		{
+			name:               "small block",
+			msgType:            blockType,
+			size:               1,
+			expectedCompressor: zstdCompressor,
+		},
+		{
+			name:               "large block",
+			msgType:            blockType,
+			size:               units.MiB,
+			expectedCompressor: zstdCompressor,
+		},
+		{
+			name:               "encrypted",
+			msgType:            encryptedType,
+			size:               units.KiB,
+			expectedCompressor: NewNoCompressor(),
+		},
+		{
+			name:               "unconfigured",
+			msgType:            otherType,
+			size:               1,
+			expectedCompressor: zstdCompressor,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor := policy.Choose(test.msgType, test.size)
+			require.IsType(test.expectedCompressor, compressor)
+			// Compressors are cached.
+			require.Same(compressor, policy.Choose(test.msgType, test.size))
+		})
+	}
+}
+
+func TestCompressionPolicyUnknownCompressor(t *testing.T) {
+	_, err := NewCompressionPolicy(
+		[]PolicyRule{
+			{
+				Compressor: "unregistered",
+			},
+		},
+		TypeNone.String(),
+		maxMessageSize,
+	)
+	require.ErrorIs(t, err, ErrUnknownCompressor)
+}
+
+func TestCompressionPolicyCopiesRules(t *testing.T) {
+	require := require.New(t)
+
+	rules := []PolicyRule{
+		{
+			Compressor: SnappyName,
+		},
+	}
+	policy, err := NewCompressionPolicy(rules, TypeNone.String(), maxMessageSize)
+	require.NoError(err)
+
+	// Modifying the rules after construction doesn't affect the policy.
+	rules[0].Compressor = "unregistered"
+	require.NotNil(policy.Choose(0, 1))
+}
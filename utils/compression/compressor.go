@@ -3,10 +3,271 @@
 
 package compression
 
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	ErrShortBuffer       = errors.New("short buffer")
+	ErrTruncatedStream   = errors.New("truncated stream")
+	ErrTruncatedTrailer  = errors.New("truncated trailer")
+	ErrRoundTripMismatch = errors.New("round trip mismatch")
+	ErrSelfTestFailed    = errors.New("compressor self test failed")
+
+	// selfTestMsg is short enough for any practical max size, and repetitive
+	// enough that every algorithm, and any dictionary, is exercised.
+	selfTestMsg = []byte(`{"chainID":"2q9e4r6Mu3U68nU1fYjgbR6JvwrRx36CohpAX5UQxse55x1Q5","height":1,"parentID":"2q9e4r6Mu3U68nU1fYjgbR6JvwrRx36CohpAX5UQxse55x1Q5"}`)
+)
+
 // Compressor compresss and decompresses messages.
 // Decompress is the inverse of Compress.
 // Decompress(Compress(msg)) == msg.
+//
+// Compressing an empty msg returns the minimal encoding of the algorithm,
+// which is only empty if no compression is performed, and decompressing that
+// encoding returns an empty msg. An empty input to Decompress is only valid if
+// no compression is performed, otherwise [ErrInvalidFormat] is returned.
+//
+// Unless documented otherwise, Compress is deterministic: compressing the same
+// msg with the same configuration returns identical bytes. The exception is
+// [AutoTuningCompressor], whose level depends on the messages it has already
+// compressed. The output may change across versions
+// of the underlying compression libraries, so compressed bytes should not be
+// used as a stable identifier across releases.
+//
+// Unless documented otherwise, implementations are safe for concurrent use.
 type Compressor interface {
 	Compress([]byte) ([]byte, error)
+	Decompressor
+}
+
+// Decompressor decompresses messages. Code that only receives messages should
+// depend on Decompressor rather than Compressor.
+type Decompressor interface {
 	Decompress([]byte) ([]byte, error)
 }
+
+// StreamCompressor compresses and decompresses streams without holding the
+// full input or output in memory.
+//
+// The stream format of an algorithm may differ from the format produced by
+// its [Compressor]. If an error is returned, dst may have been partially
+// written to.
+//
+// If src ends in the middle of the stream, DecompressStream returns
+// [ErrTruncatedStream]. The snappy stream formats have no end of stream marker,
+// so a snappy stream that is cut at a chunk boundary can't be detected as
+// truncated.
+//
+// CompressStream writes each block to dst as soon as it is complete, so a slow
+// dst blocks compression rather than letting output accumulate. Beyond the
+// state of the algorithm, at most about one block of input is buffered: 128
+// KiB for zstd, 64 KiB for deflate and snappy, and 1 MiB for s2.
+type StreamCompressor interface {
+	// CompressStream compresses all of src into dst.
+	CompressStream(dst io.Writer, src io.Reader) error
+	// DecompressStream decompresses all of src into dst.
+	DecompressStream(dst io.Writer, src io.Reader) error
+}
+
+// AppendCompressor compresses and decompresses into caller-provided buffers,
+// which lets callers reuse a scratch buffer across calls.
+//
+// The result is appended to dst and the extended buffer is returned. If dst
+// has sufficient capacity, the result aliases dst's backing array, so dst must
+// not be modified while the result is in use.
+type AppendCompressor interface {
+	// AppendCompress appends the compressed msg to dst.
+	AppendCompress(dst, msg []byte) ([]byte, error)
+	// AppendDecompress appends the decompressed msg to dst.
+	AppendDecompress(dst, msg []byte) ([]byte, error)
+}
+
+// SizeEstimator is implemented by compressors that can bound the size of their
+// output without compressing, which lets callers size buffers in advance.
+type SizeEstimator interface {
+	// EstimateCompressedSize returns an upper bound of the length of the
+	// result of compressing msg.
+	EstimateCompressedSize(msg []byte) int
+}
+
+// CompressorConfig is the configuration of a Compressor.
+type CompressorConfig struct {
+	// MaxSize bounds both the msgs that can be compressed and the size of
+	// decompressed msgs, or is 0 if the bound isn't known.
+	MaxSize int64
+	// Level is the compression level, or 0 if the algorithm has no levels.
+	Level int
+	// Threshold is the size below which msgs are compressed differently,
+	// typically not at all, or 0 if all msgs are compressed the same way.
+	Threshold int
+}
+
+// ConfigReporter is implemented by compressors that can report how they were
+// configured, which lets callers make decisions consistent with them, such as
+// sizing read buffers to the max decompressed size.
+type ConfigReporter interface {
+	Config() CompressorConfig
+}
+
+// reportedMaxSize returns the max size reported by compressor, if it reports
+// one.
+func reportedMaxSize(compressor Compressor) (int64, bool) {
+	reporter, ok := compressor.(ConfigReporter)
+	if !ok {
+		return 0, false
+	}
+	maxSize := reporter.Config().MaxSize
+	return maxSize, maxSize > 0
+}
+
+// DecompressTo decompresses msg into dst and returns the number of bytes
+// written. If the decompressed msg doesn't fit in dst, [ErrShortBuffer] is
+// returned and the contents of dst are unspecified.
+//
+// msg is always decompressed to its end, so filling dst exactly is only
+// successful if the decompressed msg ends there. If the compressed msg has
+// trailing data, an error is returned even if the output fits.
+func DecompressTo(compressor AppendCompressor, dst, msg []byte) (int, error) {
+	// Limiting the capacity prevents writes past the end of dst.
+	decompressed, err := compressor.AppendDecompress(dst[:0:len(dst)], msg)
+	if err != nil {
+		return 0, err
+	}
+	if len(decompressed) > len(dst) {
+		return 0, fmt.Errorf("%w: (%d) > (%d)", ErrShortBuffer, len(decompressed), len(dst))
+	}
+	return len(decompressed), nil
+}
+
+// CompressVerified compresses msg with compressor and decompresses the result
+// again before returning it. If the decompressed msg differs from msg or can't
+// be decompressed, [ErrRoundTripMismatch] is returned.
+//
+// Verifying doubles the cost of compressing, so it is intended for low volume
+// paths where a corrupted msg can't be tolerated, rather than for gossip.
+func CompressVerified(compressor Compressor, msg []byte) ([]byte, error) {
+	compressed, err := compressor.Compress(msg)
+	if err != nil {
+		return nil, err
+	}
+	decompressed, err := compressor.Decompress(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRoundTripMismatch, err)
+	}
+	if !bytes.Equal(msg, decompressed) {
+		return nil, fmt.Errorf("%w: decompressed (%d) bytes, expected (%d)", ErrRoundTripMismatch, len(decompressed), len(msg))
+	}
+	return compressed, nil
+}
+
+// SelfTest compresses and decompresses a known msg with compressor and
+// returns [ErrSelfTestFailed] if it doesn't round trip, which catches
+// misconfigured compressors, such as ones with mismatched dictionaries, before
+// they are used. It is intended to be called at startup.
+func SelfTest(compressor Compressor) error {
+	if _, err := CompressVerified(compressor, selfTestMsg); err != nil {
+		return fmt.Errorf("%w: %w", ErrSelfTestFailed, err)
+	}
+	return nil
+}
+
+// copyLimited copies from src to dst until either EOF is reached on src or
+// limit bytes have been copied. If src contains more than limit bytes, true
+// is returned and exactly limit bytes will have been copied.
+func copyLimited(dst io.Writer, src io.Reader, limit int64) (bool, error) {
+	n, err := io.Copy(dst, io.LimitReader(src, limit))
+	if err != nil || n < limit {
+		return false, err
+	}
+
+	var next [1]byte
+	switch _, err := io.ReadFull(src, next[:]); err {
+	case nil:
+		return true, nil
+	case io.EOF:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// appendLimited appends from src to dst until either EOF is reached on src or
+// limit bytes have been appended. If src contains more than limit bytes, true
+// is returned.
+//
+// Unlike [io.ReadAll], dst is not grown when it has exactly enough capacity
+// for the contents of src.
+func appendLimited(dst []byte, src io.Reader, limit int64) ([]byte, bool, error) {
+	// Read up to limit + 1 bytes so that exceeding the limit can be detected.
+	src = io.LimitReader(src, limit+1)
+
+	var (
+		start = len(dst)
+		probe [1]byte
+	)
+	for {
+		var (
+			n   int
+			err error
+		)
+		if len(dst) == cap(dst) {
+			// If dst is full, probe for more data before growing it.
+			n, err = src.Read(probe[:])
+			dst = append(dst, probe[:n]...)
+		} else {
+			n, err = src.Read(dst[len(dst):cap(dst)])
+			dst = dst[:len(dst)+n]
+		}
+
+		if int64(len(dst)-start) > limit {
+			return dst[:start+int(limit)], true, nil
+		}
+		switch {
+		case err == io.EOF:
+			return dst, false, nil
+		case err != nil:
+			return dst, false, err
+		}
+	}
+}
+
+// sourceReader records the first error returned by the source of a stream, so
+// that read errors can be told apart from decoding errors.
+type sourceReader struct {
+	reader io.Reader
+	err    error
+	eof    bool
+}
+
+func (s *sourceReader) Read(p []byte) (int, error) {
+	n, err := s.reader.Read(p)
+	switch {
+	case errors.Is(err, io.EOF):
+		s.eof = true
+	case err != nil && s.err == nil:
+		s.err = err
+	}
+	return n, err
+}
+
+// wrapErr returns the error reported by the source, if there was one.
+// Otherwise decodeErr is reported as [ErrInvalidFormat].
+//
+// Decoders only read past the end of the source when they expect more input,
+// so a decoding error after the source has been exhausted means that the
+// stream was cut short, which is additionally reported as
+// [ErrTruncatedStream].
+func (s *sourceReader) wrapErr(decodeErr error) error {
+	switch {
+	case s.err != nil:
+		return s.err
+	case s.eof:
+		return fmt.Errorf("%w: %w: %w", ErrInvalidFormat, ErrTruncatedStream, decodeErr)
+	default:
+		return fmt.Errorf("%w: %w", ErrInvalidFormat, decodeErr)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"context"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanchego/utils/timer/mockable"
+)
+
+// CompressorPool reuses Compressors that are expensive to create, such as
+// Compressors that hold large buffers or dictionaries.
+//
+// Unlike a [sync.Pool], at most maxIdle Compressors are retained between uses,
+// and idle Compressors are never freed by the garbage collector, so the memory
+// held by the pool is bounded and predictable.
+//
+// CompressorPool is safe for concurrent use.
+type CompressorPool struct {
+	factory func() Compressor
+	maxIdle int
+	clock   mockable.Clock
+
+	lock sync.Mutex
+	// idle is a stack of the idle Compressors, with the Compressor that has
+	// been idle for the longest at the bottom. Get takes the most recently
+	// returned Compressor, so after a burst, the surplus Compressors stay
+	// at the bottom and can be trimmed even while there is some traffic.
+	idle []idleCompressor
+}
+
+type idleCompressor struct {
+	compressor Compressor
+	since      time.Time
+}
+
+// NewCompressorPool returns a pool that retains up to maxIdle Compressors and
+// creates new Compressors with factory when none are idle. If maxIdle isn't
+// positive, no Compressors are retained.
+func NewCompressorPool(maxIdle int, factory func() Compressor) *CompressorPool {
+	maxIdle = max(maxIdle, 0)
+	return &CompressorPool{
+		factory: factory,
+		maxIdle: maxIdle,
+		idle:    make([]idleCompressor, 0, maxIdle),
+	}
+}
+
+// Get returns the most recently idle Compressor, or a new one if none are
+// idle.
+func (p *CompressorPool) Get() Compressor {
+	p.lock.Lock()
+	if n := len(p.idle); n > 0 {
+		c := p.idle[n-1]
+		p.idle[n-1] = idleCompressor{}
+		p.idle = p.idle[:n-1]
+		p.lock.Unlock()
+		return c.compressor
+	}
+	p.lock.Unlock()
+
+	return p.factory()
+}
+
+// Put returns c to the pool. If maxIdle Compressors are already idle, c is
+// released. c must not be used after it has been returned.
+func (p *CompressorPool) Put(c Compressor) {
+	if c == nil {
+		return
+	}
+
+	p.lock.Lock()
+	if len(p.idle) < p.maxIdle {
+		p.idle = append(p.idle, idleCompressor{compressor: c, since: p.clock.Time()})
+		p.lock.Unlock()
+		return
+	}
+	p.lock.Unlock()
+
+	release(c)
+}
+
+// Warm creates Compressors until n are idle, so that the first calls to Get
+// don't pay for creating them. At most maxIdle Compressors are retained.
+func (p *CompressorPool) Warm(n int) {
+	for min(n, p.maxIdle) > p.Idle() {
+		p.Put(p.factory())
+	}
+}
+
+// Idle returns the number of Compressors that are currently idle.
+func (p *CompressorPool) Idle() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return len(p.idle)
+}
+
+// Trim releases Compressors that have been idle for at least idleFor until at
+// most lowWater Compressors are idle, and returns the number of Compressors
+// released. Compressors that implement [io.Closer] are closed when released.
+//
+// The Compressors that have been idle for the longest are released first.
+func (p *CompressorPool) Trim(idleFor time.Duration, lowWater int) int {
+	p.lock.Lock()
+	var (
+		now      = p.clock.Time()
+		excess   = len(p.idle) - max(lowWater, 0)
+		released int
+	)
+	// The stack is ordered by how long its Compressors have been idle, so
+	// once a Compressor hasn't been idle for long enough, none of the ones
+	// above it have.
+	for released < excess && now.Sub(p.idle[released].since) >= idleFor {
+		released++
+	}
+	toRelease := slices.Clone(p.idle[:released])
+	p.idle = slices.Delete(p.idle, 0, released)
+	p.lock.Unlock()
+
+	for _, c := range toRelease {
+		release(c.compressor)
+	}
+	return released
+}
+
+// TrimPeriodically calls Trim every interval, releasing Compressors that have
+// been idle for at least interval down to lowWater, until ctx is done.
+func (p *CompressorPool) TrimPeriodically(ctx context.Context, interval time.Duration, lowWater int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.Trim(interval, lowWater)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func release(c Compressor) {
+	if closer, ok := c.(io.Closer); ok {
+		// There is nothing to do if releasing the Compressor fails.
+		_ = closer.Close()
+	}
+}
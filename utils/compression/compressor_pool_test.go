@@ -0,0 +1,270 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+)
+
+// newCountingFactory returns a factory of zstd Compressors and the number of
+// Compressors it has created.
+func newCountingFactory(t *testing.T) (func() Compressor, *atomic.Int64) {
+	created := &atomic.Int64{}
+	return func() Compressor {
+		created.Add(1)
+		compressor, err := NewZstdCompressor(maxMessageSize)
+		require.NoError(t, err)
+		return compressor
+	}, created
+}
+
+func TestCompressorPoolReuse(t *testing.T) {
+	require := require.New(t)
+
+	factory, created := newCountingFactory(t)
+	pool := NewCompressorPool(1, factory)
+
+	c := pool.Get()
+	require.Equal(int64(1), created.Load())
+	pool.Put(c)
+	require.Equal(1, pool.Idle())
+
+	require.Same(c, pool.Get())
+	require.Equal(int64(1), created.Load())
+	require.Zero(pool.Idle())
+}
+
+func TestCompressorPoolMaxIdle(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxIdle      int
+		expectedIdle int
+	}{
+		{
+			name:         "negative",
+			maxIdle:      -1,
+			expectedIdle: 0,
+		},
+		{
+			name:         "zero",
+			maxIdle:      0,
+			expectedIdle: 0,
+		},
+		{
+			name:         "below usage",
+			maxIdle:      2,
+			expectedIdle: 2,
+		},
+		{
+			name:         "above usage",
+			maxIdle:      10,
+			expectedIdle: 5,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			factory, created := newCountingFactory(t)
+			pool := NewCompressorPool(test.maxIdle, factory)
+
+			compressors := make([]Compressor, 5)
+			for i := range compressors {
+				compressors[i] = pool.Get()
+			}
+			for _, c := range compressors {
+				pool.Put(c)
+			}
+			require.Equal(test.expectedIdle, pool.Idle())
+
+			// Only the retained Compressors are reused.
+			for range compressors {
+				pool.Get()
+			}
+			require.Equal(int64(len(compressors)+len(compressors)-test.expectedIdle), created.Load())
+		})
+	}
+}
+
+func TestCompressorPoolWarm(t *testing.T) {
+	require := require.New(t)
+
+	factory, created := newCountingFactory(t)
+	pool := NewCompressorPool(4, factory)
+
+	pool.Warm(3)
+	require.Equal(3, pool.Idle())
+	require.Equal(int64(3), created.Load())
+
+	// Warming is capped by maxIdle.
+	pool.Warm(10)
+	require.Equal(4, pool.Idle())
+	require.Equal(int64(4), created.Load())
+
+	for range 4 {
+		pool.Get()
+	}
+	require.Equal(int64(4), created.Load())
+}
+
+func TestCompressorPoolPutNil(t *testing.T) {
+	factory, _ := newCountingFactory(t)
+	pool := NewCompressorPool(1, factory)
+	pool.Put(nil)
+	require.Zero(t, pool.Idle())
+}
+
+func TestCompressorPoolConcurrent(t *testing.T) {
+	require := require.New(t)
+
+	const maxIdle = 4
+	factory, _ := newCountingFactory(t)
+	pool := NewCompressorPool(maxIdle, factory)
+
+	var eg errgroup.Group
+	for i := range 16 {
+		msg := newTestDictionaryMessage(i)
+		eg.Go(func() error {
+			for range 10 {
+				c := pool.Get()
+				compressed, err := c.Compress(msg)
+				if err != nil {
+					return err
+				}
+				if _, err := c.Decompress(compressed); err != nil {
+					return err
+				}
+				pool.Put(c)
+			}
+			return nil
+		})
+	}
+	require.NoError(eg.Wait())
+	require.LessOrEqual(pool.Idle(), maxIdle)
+}
+
+// closingCompressor records whether it has been closed.
+type closingCompressor struct {
+	Compressor
+	closed bool
+}
+
+func (c *closingCompressor) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestCompressorPoolTrim(t *testing.T) {
+	require := require.New(t)
+
+	pool := NewCompressorPool(10, func() Compressor {
+		return &closingCompressor{Compressor: NewNoCompressor()}
+	})
+	now := time.Now()
+	pool.clock.Set(now)
+
+	compressors := make([]Compressor, 5)
+	for i := range compressors {
+		compressors[i] = pool.Get()
+	}
+	for _, c := range compressors[:4] {
+		pool.Put(c)
+	}
+
+	// Nothing has been idle for long enough to be released.
+	require.Zero(pool.Trim(time.Minute, 1))
+	require.Equal(4, pool.Idle())
+
+	now = now.Add(time.Minute)
+	pool.clock.Set(now)
+	pool.Put(compressors[4])
+
+	// Only the Compressors returned a minute ago are released, even though
+	// the low-water mark would allow one more to be.
+	require.Equal(4, pool.Trim(time.Minute, 0))
+	require.Equal(1, pool.Idle())
+	for i, c := range compressors {
+		require.Equal(i < 4, c.(*closingCompressor).closed)
+	}
+
+	// Once it has been idle for long enough, the low-water mark is kept.
+	now = now.Add(time.Minute)
+	pool.clock.Set(now)
+	require.Zero(pool.Trim(time.Minute, 1))
+	require.Same(compressors[4], pool.Get())
+}
+
+func TestCompressorPoolTrimAfterBurst(t *testing.T) {
+	require := require.New(t)
+
+	pool := NewCompressorPool(10, func() Compressor {
+		return &closingCompressor{Compressor: NewNoCompressor()}
+	})
+	now := time.Now()
+	pool.clock.Set(now)
+
+	// A burst leaves 8 Compressors idle.
+	burst := make([]Compressor, 8)
+	for i := range burst {
+		burst[i] = pool.Get()
+	}
+	for _, c := range burst {
+		pool.Put(c)
+	}
+
+	// A trickle of traffic keeps reusing the most recently returned
+	// Compressor, rather than refreshing every instance from the burst.
+	for range 10 {
+		now = now.Add(10 * time.Second)
+		pool.clock.Set(now)
+		pool.Put(pool.Get())
+	}
+
+	require.Equal(7, pool.Trim(time.Minute, 0))
+	require.Equal(1, pool.Idle())
+	for i, c := range burst {
+		require.Equal(i < 7, c.(*closingCompressor).closed)
+	}
+	require.Same(burst[7], pool.Get())
+}
+
+func TestCompressorPoolTrimNegativeLowWater(t *testing.T) {
+	require := require.New(t)
+
+	pool := NewCompressorPool(2, func() Compressor {
+		return NewNoCompressor()
+	})
+	pool.Warm(2)
+
+	require.Equal(2, pool.Trim(0, -1))
+	require.Zero(pool.Idle())
+}
+
+func TestCompressorPoolTrimPeriodically(t *testing.T) {
+	pool := NewCompressorPool(10, func() Compressor {
+		return NewNoCompressor()
+	})
+	for range 5 {
+		pool.Put(NewNoCompressor())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pool.TrimPeriodically(ctx, time.Millisecond, 2)
+	}()
+
+	require.Eventually(t, func() bool {
+		return pool.Idle() == 2
+	}, 10*time.Second, time.Millisecond)
+	cancel()
+	<-done
+}
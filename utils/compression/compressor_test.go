@@ -4,45 +4,80 @@
 package compression
 
 import (
+	"bytes"
+	"compress/flate"
+	"errors"
 	"fmt"
+	"io"
 	"math"
 	"runtime"
+	"slices"
 	"testing"
+	"time"
+	"unsafe"
 
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/s2"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/sync/errgroup"
 
 	_ "embed"
 
 	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/compression/compressionmock"
 	"github.com/ava-labs/avalanchego/utils/units"
 )
 
 const maxMessageSize = 2 * units.MiB // Max message size. Can't import due to cycle.
 
 var (
-	newCompressorFuncs = map[Type]func(maxSize int64) (Compressor, error){
-		TypeNone: func(int64) (Compressor, error) { //nolint:unparam // an error is needed to be returned to compile
+	errTest = errors.New("non-nil error")
+
+	newCompressorFuncs = map[string]func(maxSize int64) (Compressor, error){
+		TypeNone.String(): func(int64) (Compressor, error) { //nolint:unparam // an error is needed to be returned to compile
 			return NewNoCompressor(), nil
 		},
-		TypeZstd: NewZstdCompressor,
+		TypeZstd.String(): NewZstdCompressor,
+		"zstd_pooled":     NewPooledZstdCompressor,
+		SnappyName:        NewSnappyCompressor,
+		"zstd_dictionary": func(maxSize int64) (Compressor, error) {
+			return NewZstdCompressorWithDictionary(maxSize, testDictionary)
+		},
+		DeflateName: NewDeflateCompressor,
+		S2Name:      NewS2Compressor,
+		"zstd_go": func(maxSize int64) (Compressor, error) {
+			return newGoZstdCompressor(maxSize, zstdDefaultCompression)
+		},
+		"zstd_auto_tuning": func(maxSize int64) (Compressor, error) {
+			return NewAutoTuningCompressor(maxSize)
+		},
 	}
 
 	//go:embed zstd_zip_bomb.bin
 	zstdZipBomb []byte
 
-	zipBombs = map[Type][]byte{
-		TypeZstd: zstdZipBomb,
+	zipBombs = map[string][]byte{
+		TypeZstd.String(): zstdZipBomb,
+		"zstd_pooled":     zstdZipBomb,
+		"zstd_dictionary": zstdZipBomb,
+		"zstd_go":         zstdZipBomb,
+		// The snappy header declares a decompressed length larger than the
+		// max message size.
+		SnappyName:  snappy.Encode(nil, make([]byte, 2*maxMessageSize)),
+		DeflateName: newDeflateZipBomb(),
+		S2Name:      s2.Encode(nil, make([]byte, 2*maxMessageSize)),
 	}
 )
 
 func TestDecompressZipBombs(t *testing.T) {
-	for compressionType, zipBomb := range zipBombs {
+	for name, zipBomb := range zipBombs {
 		// Make sure that the hardcoded zip bomb would be a valid message.
 		require.Less(t, len(zipBomb), maxMessageSize)
 
-		newCompressorFunc := newCompressorFuncs[compressionType]
+		newCompressorFunc := newCompressorFuncs[name]
 
-		t.Run(compressionType.String(), func(t *testing.T) {
+		t.Run(name, func(t *testing.T) {
 			require := require.New(t)
 
 			compressor, err := newCompressorFunc(maxMessageSize)
@@ -67,8 +102,8 @@ func TestDecompressZipBombs(t *testing.T) {
 }
 
 func TestCompressDecompress(t *testing.T) {
-	for compressionType, newCompressorFunc := range newCompressorFuncs {
-		t.Run(compressionType.String(), func(t *testing.T) {
+	for name, newCompressorFunc := range newCompressorFuncs {
+		t.Run(name, func(t *testing.T) {
 			require := require.New(t)
 
 			data := utils.RandomBytes(4096)
@@ -107,12 +142,881 @@ func TestCompressDecompress(t *testing.T) {
 	}
 }
 
+func TestCompressDecompressConcurrent(t *testing.T) {
+	const (
+		numGoroutines = 16
+		numIterations = 32
+	)
+	for name, newCompressorFunc := range newCompressorFuncs {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := newCompressorFunc(maxMessageSize)
+			require.NoError(err)
+
+			var eg errgroup.Group
+			for i := 0; i < numGoroutines; i++ {
+				eg.Go(func() error {
+					for j := 0; j < numIterations; j++ {
+						data := utils.RandomBytes(units.KiB)
+						compressed, err := compressor.Compress(data)
+						if err != nil {
+							return err
+						}
+						decompressed, err := compressor.Decompress(compressed)
+						if err != nil {
+							return err
+						}
+						if !bytes.Equal(data, decompressed) {
+							return ErrRoundTripMismatch
+						}
+					}
+					return nil
+				})
+			}
+			require.NoError(eg.Wait())
+		})
+	}
+}
+
+func TestCompressDecompressStream(t *testing.T) {
+	const maxSize = 8 * units.MiB
+	for name, newCompressorFunc := range newCompressorFuncs {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := newCompressorFunc(maxSize)
+			require.NoError(err)
+			streamCompressor, ok := compressor.(StreamCompressor)
+			require.True(ok)
+
+			data := utils.RandomBytes(maxSize)
+			pipeReader, pipeWriter := io.Pipe()
+			go func() {
+				err := streamCompressor.CompressStream(pipeWriter, bytes.NewReader(data))
+				_ = pipeWriter.CloseWithError(err)
+			}()
+
+			var decompressed bytes.Buffer
+			require.NoError(streamCompressor.DecompressStream(&decompressed, pipeReader))
+			require.Equal(data, decompressed.Bytes())
+		})
+	}
+}
+
+// countingReader counts the bytes read from it.
+type countingReader struct {
+	reader io.Reader
+	read   int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.read += n
+	return n, err
+}
+
+// throttledWriter is a slow destination that records how far reading from src
+// got ahead of the bytes written to it.
+type throttledWriter struct {
+	buf      bytes.Buffer
+	src      *countingReader
+	maxAhead int
+}
+
+func (t *throttledWriter) Write(p []byte) (int, error) {
+	time.Sleep(100 * time.Microsecond)
+	n, err := t.buf.Write(p)
+	t.maxAhead = max(t.maxAhead, t.src.read-t.buf.Len())
+	return n, err
+}
+
+func TestCompressStreamBackpressure(t *testing.T) {
+	const (
+		maxSize = 16 * units.MiB
+		// The largest block of the stream formats is the 1 MiB block of s2,
+		// and up to 32 KiB more is read ahead by the copy buffer.
+		maxBuffered = units.MiB + 32*units.KiB
+	)
+	data := utils.RandomBytes(maxSize)
+	for name, newCompressorFunc := range newCompressorFuncs {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := newCompressorFunc(maxSize)
+			require.NoError(err)
+			streamCompressor := compressor.(StreamCompressor)
+
+			// Random data is incompressible, so each compressed byte written
+			// accounts for about one byte read.
+			src := &countingReader{reader: bytes.NewReader(data)}
+			dst := &throttledWriter{src: src}
+			require.NoError(streamCompressor.CompressStream(dst, src))
+			require.LessOrEqual(dst.maxAhead, maxBuffered)
+
+			var decompressed bytes.Buffer
+			require.NoError(streamCompressor.DecompressStream(&decompressed, &dst.buf))
+			require.Equal(data, decompressed.Bytes())
+		})
+	}
+}
+
+func TestStreamSizeLimiting(t *testing.T) {
+	for name, compressorFunc := range newCompressorFuncs {
+		if name == TypeNone.String() {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := compressorFunc(maxMessageSize)
+			require.NoError(err)
+			streamCompressor := compressor.(StreamCompressor)
+
+			data := make([]byte, maxMessageSize+1)
+			err = streamCompressor.CompressStream(io.Discard, bytes.NewReader(data))
+			require.ErrorIs(err, ErrMsgTooLarge)
+
+			compressor2, err := compressorFunc(2 * maxMessageSize)
+			require.NoError(err)
+			streamCompressor2 := compressor2.(StreamCompressor)
+
+			var compressed bytes.Buffer
+			require.NoError(streamCompressor2.CompressStream(&compressed, bytes.NewReader(data)))
+
+			var decompressed bytes.Buffer
+			err = streamCompressor.DecompressStream(&decompressed, &compressed)
+			require.ErrorIs(err, ErrDecompressedMsgTooLarge)
+			require.Equal(maxMessageSize, decompressed.Len())
+		})
+	}
+}
+
+func TestAppendCompressDecompress(t *testing.T) {
+	for name, newCompressorFunc := range newCompressorFuncs {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := newCompressorFunc(maxMessageSize)
+			require.NoError(err)
+			appendCompressor, ok := compressor.(AppendCompressor)
+			require.True(ok)
+
+			var (
+				prefix = []byte("prefix")
+				data   = utils.RandomBytes(units.KiB)
+			)
+			compressed, err := appendCompressor.AppendCompress(slices.Clone(prefix), data)
+			require.NoError(err)
+			require.Equal(prefix, compressed[:len(prefix)])
+
+			decompressed, err := compressor.Decompress(compressed[len(prefix):])
+			require.NoError(err)
+			require.Equal(data, decompressed)
+
+			decompressed, err = appendCompressor.AppendDecompress(slices.Clone(prefix), compressed[len(prefix):])
+			require.NoError(err)
+			require.Equal(append(slices.Clone(prefix), data...), decompressed)
+
+			// A scratch buffer with exactly enough capacity is reused.
+			scratch := make([]byte, 0, len(data))
+			decompressed, err = appendCompressor.AppendDecompress(scratch, compressed[len(prefix):])
+			require.NoError(err)
+			require.Equal(data, decompressed)
+			require.Equal(unsafe.SliceData(scratch), unsafe.SliceData(decompressed))
+		})
+	}
+}
+
+func TestAppendDecompressSizeLimiting(t *testing.T) {
+	for name, compressorFunc := range newCompressorFuncs {
+		if name == TypeNone.String() {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := compressorFunc(2 * maxMessageSize)
+			require.NoError(err)
+			compressed, err := compressor.Compress(make([]byte, maxMessageSize+1))
+			require.NoError(err)
+
+			compressor, err = compressorFunc(maxMessageSize)
+			require.NoError(err)
+			_, err = compressor.(AppendCompressor).AppendDecompress(nil, compressed)
+			require.ErrorIs(err, ErrDecompressedMsgTooLarge)
+		})
+	}
+}
+
+func TestDecompressInvalidFormat(t *testing.T) {
+	for name, newCompressorFunc := range newCompressorFuncs {
+		if name == TypeNone.String() {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := newCompressorFunc(maxMessageSize)
+			require.NoError(err)
+
+			// Fix the first bytes so the random prefix can't be a valid
+			// header. Five 0xff bytes ensure that the snappy length varint
+			// can't fit in a uint32.
+			msg := append([]byte{0xff, 0xff, 0xff, 0xff, 0xff}, utils.RandomBytes(units.KiB)...)
+
+			_, err = compressor.Decompress(msg)
+			require.ErrorIs(err, ErrInvalidFormat)
+
+			_, err = compressor.(AppendCompressor).AppendDecompress(nil, msg)
+			require.ErrorIs(err, ErrInvalidFormat)
+
+			err = compressor.(StreamCompressor).DecompressStream(io.Discard, bytes.NewReader(msg))
+			require.ErrorIs(err, ErrInvalidFormat)
+		})
+	}
+}
+
+func TestDecompressShortInput(t *testing.T) {
+	for name, newCompressorFunc := range newCompressorFuncs {
+		if name == TypeNone.String() {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := newCompressorFunc(maxMessageSize)
+			require.NoError(err)
+			compressed, err := compressor.Compress(bytes.Repeat([]byte("avalanche"), units.KiB))
+			require.NoError(err)
+
+			for _, n := range []int{0, 10, 11} {
+				for _, msg := range [][]byte{
+					make([]byte, n),
+					bytes.Repeat([]byte{0xff}, n),
+					compressed[:n],
+				} {
+					_, err = compressor.Decompress(msg)
+					require.ErrorIs(err, ErrInvalidFormat)
+
+					_, err = compressor.(AppendCompressor).AppendDecompress(nil, msg)
+					require.ErrorIs(err, ErrInvalidFormat)
+				}
+			}
+		})
+	}
+}
+
+func TestDecompressValidAfterMagicCheck(t *testing.T) {
+	for name, newCompressorFunc := range newCompressorFuncs {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := newCompressorFunc(maxMessageSize)
+			require.NoError(err)
+
+			// The payloads cover the block types that an encoder may start
+			// its output with, which must all pass the checks made before
+			// decoding. Empty msgs are covered by
+			// TestCompressDecompressEmpty.
+			for _, msg := range [][]byte{
+				{1},
+				bytes.Repeat([]byte("avalanche"), units.KiB),
+				utils.RandomBytes(units.KiB),
+			} {
+				compressed, err := compressor.Compress(msg)
+				require.NoError(err)
+
+				decompressed, err := compressor.Decompress(compressed)
+				require.NoError(err)
+				require.Equal(msg, decompressed)
+
+				decompressed, err = compressor.(AppendCompressor).AppendDecompress(nil, compressed)
+				require.NoError(err)
+				require.Equal(msg, decompressed)
+			}
+		})
+	}
+}
+
+func TestDecompressStreamSourceError(t *testing.T) {
+	for name, newCompressorFunc := range newCompressorFuncs {
+		if name == TypeNone.String() {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := newCompressorFunc(maxMessageSize)
+			require.NoError(err)
+
+			pipeReader, pipeWriter := io.Pipe()
+			require.NoError(pipeWriter.CloseWithError(errTest))
+
+			err = compressor.(StreamCompressor).DecompressStream(io.Discard, pipeReader)
+			require.ErrorIs(err, errTest)
+			require.NotErrorIs(err, ErrInvalidFormat)
+		})
+	}
+}
+
+func TestDecompressStreamTruncated(t *testing.T) {
+	for name, newCompressorFunc := range newCompressorFuncs {
+		if name == TypeNone.String() {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := newCompressorFunc(maxMessageSize)
+			require.NoError(err)
+			streamCompressor := compressor.(StreamCompressor)
+
+			var compressed bytes.Buffer
+			msg := bytes.Repeat([]byte("avalanche"), units.KiB)
+			require.NoError(streamCompressor.CompressStream(&compressed, bytes.NewReader(msg)))
+
+			for _, n := range []int{1, compressed.Len() / 2, compressed.Len() - 1} {
+				truncated := compressed.Bytes()[:n]
+				err := streamCompressor.DecompressStream(io.Discard, bytes.NewReader(truncated))
+				require.ErrorIs(err, ErrTruncatedStream)
+				require.ErrorIs(err, ErrInvalidFormat)
+			}
+		})
+	}
+}
+
+func TestCompressDecompressEmpty(t *testing.T) {
+	for name, newCompressorFunc := range newCompressorFuncs {
+		t.Run(name, func(t *testing.T) {
+			compressor, err := newCompressorFunc(maxMessageSize)
+			require.NoError(t, err)
+
+			for _, msg := range [][]byte{nil, {}} {
+				compressed, err := compressor.Compress(msg)
+				require.NoError(t, err)
+
+				decompressed, err := compressor.Decompress(compressed)
+				require.NoError(t, err)
+				require.Empty(t, decompressed)
+
+				decompressed, err = compressor.(AppendCompressor).AppendDecompress(nil, compressed)
+				require.NoError(t, err)
+				require.Empty(t, decompressed)
+
+				_, err = compressor.Decompress(msg)
+				_, appendErr := compressor.(AppendCompressor).AppendDecompress(nil, msg)
+				if name == TypeNone.String() {
+					require.NoError(t, err)
+					require.NoError(t, appendErr)
+				} else {
+					require.ErrorIs(t, err, ErrInvalidFormat)
+					require.ErrorIs(t, appendErr, ErrInvalidFormat)
+				}
+			}
+		})
+	}
+}
+
+func TestDecompressAfterError(t *testing.T) {
+	for name, newCompressorFunc := range newCompressorFuncs {
+		if name == TypeNone.String() {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := newCompressorFunc(maxMessageSize)
+			require.NoError(err)
+			largeCompressor, err := newCompressorFunc(2 * maxMessageSize)
+			require.NoError(err)
+
+			msg := utils.RandomBytes(units.KiB)
+			compressed, err := compressor.Compress(msg)
+			require.NoError(err)
+			tooLarge, err := largeCompressor.Compress(make([]byte, maxMessageSize+1))
+			require.NoError(err)
+
+			// No state is retained between calls, so a failed decompression
+			// must not affect later calls.
+			tests := []struct {
+				msg         []byte
+				expectedErr error
+			}{
+				{
+					msg:         append([]byte{0xff, 0xff, 0xff, 0xff, 0xff}, compressed...),
+					expectedErr: ErrInvalidFormat,
+				},
+				{
+					msg:         tooLarge,
+					expectedErr: ErrDecompressedMsgTooLarge,
+				},
+			}
+			for _, test := range tests {
+				_, err = compressor.Decompress(test.msg)
+				require.ErrorIs(err, test.expectedErr)
+
+				decompressed, err := compressor.Decompress(compressed)
+				require.NoError(err)
+				require.Equal(msg, decompressed)
+			}
+		})
+	}
+}
+
+// failingWriter fails every write, either by returning errTest or by
+// panicking.
+type failingWriter struct {
+	panics bool
+}
+
+func (f failingWriter) Write([]byte) (int, error) {
+	if f.panics {
+		panic(errTest)
+	}
+	return 0, errTest
+}
+
+func TestCompressAfterError(t *testing.T) {
+	for name, newCompressorFunc := range newCompressorFuncs {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := newCompressorFunc(maxMessageSize)
+			require.NoError(err)
+			streamCompressor := compressor.(StreamCompressor)
+
+			// The msg is large enough that compressors write to dst before
+			// the end of the stream, leaving any reused state mid-stream.
+			msg := utils.RandomBytes(units.MiB)
+
+			err = streamCompressor.CompressStream(failingWriter{}, bytes.NewReader(msg))
+			require.ErrorIs(err, errTest)
+			require.PanicsWithValue(errTest, func() {
+				_ = streamCompressor.CompressStream(failingWriter{panics: true}, bytes.NewReader(msg))
+			})
+
+			compressed, err := compressor.Compress(msg)
+			require.NoError(err)
+			decompressed, err := compressor.Decompress(compressed)
+			require.NoError(err)
+			require.Equal(msg, decompressed)
+
+			var stream bytes.Buffer
+			require.NoError(streamCompressor.CompressStream(&stream, bytes.NewReader(msg)))
+			var streamDecompressed bytes.Buffer
+			require.NoError(streamCompressor.DecompressStream(&streamDecompressed, &stream))
+			require.Equal(msg, streamDecompressed.Bytes())
+		})
+	}
+}
+
+func TestDecompressTrailingData(t *testing.T) {
+	for name, newCompressorFunc := range newCompressorFuncs {
+		if name == TypeNone.String() {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := newCompressorFunc(maxMessageSize)
+			require.NoError(err)
+
+			msg := bytes.Repeat([]byte("avalanche"), units.KiB)
+			compressed, err := compressor.Compress(msg)
+			require.NoError(err)
+
+			trailers := [][]byte{
+				{0, 0, 0, 0},
+				{0xde, 0xad, 0xbe, 0xef},
+				compressed, // A second frame
+			}
+			for _, trailer := range trailers {
+				withTrailer := append(slices.Clone(compressed), trailer...)
+
+				_, err = compressor.Decompress(withTrailer)
+				require.ErrorIs(err, ErrInvalidFormat)
+
+				_, err = compressor.(AppendCompressor).AppendDecompress(nil, withTrailer)
+				require.ErrorIs(err, ErrInvalidFormat)
+			}
+		})
+	}
+}
+
+func TestCompressDeterministic(t *testing.T) {
+	for name, newCompressorFunc := range newCompressorFuncs {
+		if name == "zstd_auto_tuning" {
+			// The chosen level depends on the previously compressed messages.
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := newCompressorFunc(maxMessageSize)
+			require.NoError(err)
+			otherCompressor, err := newCompressorFunc(maxMessageSize)
+			require.NoError(err)
+
+			msg := bytes.Repeat(utils.RandomBytes(units.KiB), units.KiB)
+			compressed, err := compressor.Compress(msg)
+			require.NoError(err)
+
+			for _, c := range []Compressor{compressor, otherCompressor} {
+				recompressed, err := c.Compress(msg)
+				require.NoError(err)
+				require.Equal(compressed, recompressed)
+
+				appended, err := c.(AppendCompressor).AppendCompress(nil, msg)
+				require.NoError(err)
+				require.Equal(compressed, appended)
+			}
+		})
+	}
+}
+
+func TestConfig(t *testing.T) {
+	zstdCompressor, err := NewZstdCompressorWithLevel(units.MiB, 3)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		newCompressor  func() (Compressor, error)
+		expectedConfig CompressorConfig
+	}{
+		{
+			name: "zstd",
+			newCompressor: func() (Compressor, error) {
+				return NewZstdCompressorWithLevel(units.MiB, 3)
+			},
+			expectedConfig: CompressorConfig{
+				MaxSize: units.MiB,
+				Level:   3,
+			},
+		},
+		{
+			name: "zstd_go",
+			newCompressor: func() (Compressor, error) {
+				return newGoZstdCompressor(units.MiB, 7)
+			},
+			expectedConfig: CompressorConfig{
+				MaxSize: units.MiB,
+				Level:   7,
+			},
+		},
+		{
+			name: "zstd_dictionary",
+			newCompressor: func() (Compressor, error) {
+				return NewZstdCompressorWithDictionary(units.MiB, testDictionary)
+			},
+			expectedConfig: CompressorConfig{
+				MaxSize: units.MiB,
+				Level:   zstdDefaultCompression,
+			},
+		},
+		{
+			name: "deflate_dictionary",
+			newCompressor: func() (Compressor, error) {
+				return NewDeflateCompressorWithDictionary(units.MiB, testDictionary)
+			},
+			expectedConfig: CompressorConfig{
+				MaxSize: units.MiB,
+				Level:   flate.BestCompression,
+			},
+		},
+		{
+			name: "snappy",
+			newCompressor: func() (Compressor, error) {
+				return NewSnappyCompressor(units.MiB)
+			},
+			expectedConfig: CompressorConfig{
+				MaxSize: units.MiB,
+			},
+		},
+		{
+			name: "threshold",
+			newCompressor: func() (Compressor, error) {
+				return NewThresholdCompressor(64, zstdCompressor), nil
+			},
+			expectedConfig: CompressorConfig{
+				MaxSize:   units.MiB,
+				Level:     3,
+				Threshold: 64,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := test.newCompressor()
+			require.NoError(err)
+			require.Equal(test.expectedConfig, compressor.(ConfigReporter).Config())
+		})
+	}
+}
+
+func TestEstimateCompressedSize(t *testing.T) {
+	msgs := [][]byte{
+		{},
+		{1},
+		utils.RandomBytes(100),
+		utils.RandomBytes(units.MiB),
+		bytes.Repeat([]byte("avalanche"), units.KiB),
+		append(bytes.Repeat([]byte{0}, units.MiB), utils.RandomBytes(units.MiB)...),
+	}
+	for name, newCompressorFunc := range newCompressorFuncs {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := newCompressorFunc(maxMessageSize)
+			require.NoError(err)
+			estimator := compressor.(SizeEstimator)
+
+			for _, msg := range msgs {
+				compressed, err := compressor.Compress(msg)
+				require.NoError(err)
+				require.GreaterOrEqual(estimator.EstimateCompressedSize(msg), len(compressed))
+			}
+		})
+	}
+}
+
+func TestDecompressor(t *testing.T) {
+	for name, newCompressorFunc := range newCompressorFuncs {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := newCompressorFunc(maxMessageSize)
+			require.NoError(err)
+
+			msg := bytes.Repeat([]byte("avalanche"), units.KiB)
+			compressed, err := compressor.Compress(msg)
+			require.NoError(err)
+
+			// Receive-only code only needs the narrower interface.
+			var decompressor Decompressor = compressor
+			decompressed, err := decompressor.Decompress(compressed)
+			require.NoError(err)
+			require.Equal(msg, decompressed)
+		})
+	}
+}
+
+func TestCompressVerified(t *testing.T) {
+	var (
+		msg        = []byte("avalanche")
+		compressed = []byte("compressed")
+	)
+	tests := []struct {
+		name               string
+		decompressed       []byte
+		decompressErr      error
+		expectedErr        error
+		expectedCompressed []byte
+	}{
+		{
+			name:               "round trip",
+			decompressed:       msg,
+			expectedCompressed: compressed,
+		},
+		{
+			name:         "corrupted",
+			decompressed: []byte("avalanchd"),
+			expectedErr:  ErrRoundTripMismatch,
+		},
+		{
+			name:         "truncated",
+			decompressed: msg[:len(msg)-1],
+			expectedErr:  ErrRoundTripMismatch,
+		},
+		{
+			name:          "undecompressable",
+			decompressErr: ErrInvalidFormat,
+			expectedErr:   ErrRoundTripMismatch,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+			ctrl := gomock.NewController(t)
+
+			compressor := compressionmock.NewCompressor(ctrl)
+			compressor.EXPECT().Compress(msg).Return(compressed, nil)
+			compressor.EXPECT().Decompress(compressed).Return(test.decompressed, test.decompressErr)
+
+			verified, err := CompressVerified(compressor, msg)
+			require.ErrorIs(err, test.expectedErr)
+			require.Equal(test.expectedCompressed, verified)
+		})
+	}
+}
+
+func TestCompressVerifiedCompressError(t *testing.T) {
+	_, err := CompressVerified(errCompressor{err: errTest}, []byte("avalanche"))
+	require.ErrorIs(t, err, errTest)
+	require.NotErrorIs(t, err, ErrRoundTripMismatch)
+}
+
+// mismatchedCompressor compresses and decompresses with different
+// Compressors.
+type mismatchedCompressor struct {
+	compressor   Compressor
+	decompressor Compressor
+}
+
+func (m mismatchedCompressor) Compress(msg []byte) ([]byte, error) {
+	return m.compressor.Compress(msg)
+}
+
+func (m mismatchedCompressor) Decompress(msg []byte) ([]byte, error) {
+	return m.decompressor.Decompress(msg)
+}
+
+func TestSelfTest(t *testing.T) {
+	newDeflate := func(dict []byte) Compressor {
+		compressor, err := NewDeflateCompressorWithDictionary(maxMessageSize, dict)
+		require.NoError(t, err)
+		return compressor
+	}
+	newZstd := func(dict []byte) Compressor {
+		compressor, err := NewZstdCompressorWithDictionary(maxMessageSize, dict)
+		require.NoError(t, err)
+		return compressor
+	}
+
+	tests := []struct {
+		name        string
+		compressor  Compressor
+		expectedErr error
+	}{
+		{
+			name:       "deflate dictionary",
+			compressor: newDeflate(testDictionary),
+		},
+		{
+			name:       "zstd dictionary",
+			compressor: newZstd(testDictionary),
+		},
+		{
+			// Raw deflate doesn't identify its dictionary, so the mismatch is
+			// only caught by comparing the output.
+			name: "mismatched deflate dictionaries",
+			compressor: mismatchedCompressor{
+				compressor:   newDeflate(testDictionary),
+				decompressor: newDeflate(otherTestDictionary),
+			},
+			expectedErr: ErrSelfTestFailed,
+		},
+		{
+			name: "mismatched zstd dictionaries",
+			compressor: mismatchedCompressor{
+				compressor:   newZstd(testDictionary),
+				decompressor: newZstd(otherTestDictionary),
+			},
+			expectedErr: ErrSelfTestFailed,
+		},
+		{
+			name:        "failing compressor",
+			compressor:  errCompressor{err: errTest},
+			expectedErr: ErrSelfTestFailed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.ErrorIs(t, SelfTest(test.compressor), test.expectedErr)
+		})
+	}
+	for name, newCompressorFunc := range newCompressorFuncs {
+		t.Run(name, func(t *testing.T) {
+			compressor, err := newCompressorFunc(maxMessageSize)
+			require.NoError(t, err)
+			require.NoError(t, SelfTest(compressor))
+		})
+	}
+}
+
+func TestDecompressTo(t *testing.T) {
+	msg := bytes.Repeat([]byte("avalanche"), units.KiB)
+	tests := []struct {
+		name        string
+		dstLen      int
+		expectedErr error
+	}{
+		{
+			name:   "exact fit",
+			dstLen: len(msg),
+		},
+		{
+			name:   "oversized",
+			dstLen: len(msg) + units.KiB,
+		},
+		{
+			// dst is filled, but one more byte of output remains.
+			name:        "undersized",
+			dstLen:      len(msg) - 1,
+			expectedErr: ErrShortBuffer,
+		},
+	}
+	for name, newCompressorFunc := range newCompressorFuncs {
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("%s/%s", name, test.name), func(t *testing.T) {
+				require := require.New(t)
+
+				compressor, err := newCompressorFunc(maxMessageSize)
+				require.NoError(err)
+				appendCompressor := compressor.(AppendCompressor)
+
+				compressed, err := compressor.Compress(msg)
+				require.NoError(err)
+
+				// The bytes after dst must not be written to.
+				buf := make([]byte, test.dstLen+1)
+				dst := buf[:test.dstLen]
+				n, err := DecompressTo(appendCompressor, dst, compressed)
+				require.ErrorIs(err, test.expectedErr)
+				require.Zero(buf[test.dstLen])
+				if test.expectedErr != nil {
+					return
+				}
+				require.Equal(len(msg), n)
+				require.Equal(msg, dst[:n])
+			})
+		}
+	}
+}
+
+func TestDecompressToTrailingData(t *testing.T) {
+	for name, newCompressorFunc := range newCompressorFuncs {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := newCompressorFunc(maxMessageSize)
+			require.NoError(err)
+
+			msg := bytes.Repeat([]byte("avalanche"), units.KiB)
+			compressed, err := compressor.Compress(msg)
+			require.NoError(err)
+			compressed = append(compressed, 0xde, 0xad, 0xbe, 0xef)
+
+			// The output of the valid prefix fits exactly, but the trailing
+			// data must not be silently dropped.
+			_, err = DecompressTo(compressor.(AppendCompressor), make([]byte, len(msg)), compressed)
+			if name == TypeNone.String() {
+				require.ErrorIs(err, ErrShortBuffer)
+			} else {
+				require.ErrorIs(err, ErrInvalidFormat)
+			}
+		})
+	}
+}
+
 func TestSizeLimiting(t *testing.T) {
-	for compressionType, compressorFunc := range newCompressorFuncs {
-		if compressionType == TypeNone {
+	for name, compressorFunc := range newCompressorFuncs {
+		if name == TypeNone.String() {
 			continue
 		}
-		t.Run(compressionType.String(), func(t *testing.T) {
+		t.Run(name, func(t *testing.T) {
 			require := require.New(t)
 
 			compressor, err := compressorFunc(maxMessageSize)
@@ -122,6 +1026,13 @@ func TestSizeLimiting(t *testing.T) {
 			_, err = compressor.Compress(data) // should be too large
 			require.ErrorIs(err, ErrMsgTooLarge)
 
+			// Messages of exactly the max size are allowed.
+			maxSizeCompressed, err := compressor.Compress(data[:maxMessageSize])
+			require.NoError(err)
+			maxSizeDecompressed, err := compressor.Decompress(maxSizeCompressed)
+			require.NoError(err)
+			require.Len(maxSizeDecompressed, maxMessageSize)
+
 			compressor2, err := compressorFunc(2 * maxMessageSize)
 			require.NoError(err)
 
@@ -138,11 +1049,11 @@ func TestSizeLimiting(t *testing.T) {
 // which leads to undefined decompress behavior due to integer overflow
 // in limit reader creation.
 func TestNewCompressorWithInvalidLimit(t *testing.T) {
-	for compressionType, compressorFunc := range newCompressorFuncs {
-		if compressionType == TypeNone {
+	for name, compressorFunc := range newCompressorFuncs {
+		if name == TypeNone.String() {
 			continue
 		}
-		t.Run(compressionType.String(), func(t *testing.T) {
+		t.Run(name, func(t *testing.T) {
 			_, err := compressorFunc(math.MaxInt64)
 			require.ErrorIs(t, err, ErrInvalidMaxSizeCompressor)
 		})
@@ -150,21 +1061,19 @@ func TestNewCompressorWithInvalidLimit(t *testing.T) {
 }
 
 func FuzzZstdCompressor(f *testing.F) {
-	fuzzHelper(f, TypeZstd)
+	fuzzHelper(f, TypeZstd.String())
 }
 
-func fuzzHelper(f *testing.F, compressionType Type) {
-	var (
-		compressor Compressor
-		err        error
-	)
-	switch compressionType {
-	case TypeZstd:
-		compressor, err = NewZstdCompressor(maxMessageSize)
-		require.NoError(f, err)
-	default:
-		require.FailNow(f, "Unknown compression type")
-	}
+func FuzzSnappyCompressor(f *testing.F) {
+	fuzzHelper(f, SnappyName)
+}
+
+func fuzzHelper(f *testing.F, name string) {
+	newCompressorFunc, ok := newCompressorFuncs[name]
+	require.True(f, ok, "Unknown compression type")
+
+	compressor, err := newCompressorFunc(maxMessageSize)
+	require.NoError(f, err)
 
 	f.Fuzz(func(t *testing.T, data []byte) {
 		require := require.New(t)
@@ -184,6 +1093,49 @@ func fuzzHelper(f *testing.F, compressionType Type) {
 	})
 }
 
+// FuzzDecompress feeds arbitrary bytes to every compressor. Decompress handles
+// untrusted peer bytes, so it must never panic and must only fail with
+// [ErrInvalidFormat] or [ErrDecompressedMsgTooLarge].
+func FuzzDecompress(f *testing.F) {
+	seeds := [][]byte{
+		{},
+		utils.RandomBytes(units.KiB),
+		bytes.Repeat([]byte("avalanche"), units.KiB),
+	}
+	for _, newCompressorFunc := range newCompressorFuncs {
+		compressor, err := newCompressorFunc(maxMessageSize)
+		require.NoError(f, err)
+
+		for _, seed := range seeds {
+			compressed, err := compressor.Compress(seed)
+			require.NoError(f, err)
+
+			f.Add(compressed)
+			if len(compressed) > 1 {
+				f.Add(compressed[:len(compressed)/2])
+			}
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for name, newCompressorFunc := range newCompressorFuncs {
+			compressor, err := newCompressorFunc(maxMessageSize)
+			require.NoError(t, err)
+
+			decompressed, err := compressor.Decompress(data)
+			if err != nil {
+				require.True(
+					t,
+					errors.Is(err, ErrInvalidFormat) || errors.Is(err, ErrDecompressedMsgTooLarge),
+					"%s: unexpected error: %v", name, err,
+				)
+				continue
+			}
+			require.LessOrEqual(t, len(decompressed), maxMessageSize, name)
+		}
+	})
+}
+
 func BenchmarkCompress(b *testing.B) {
 	sizes := []int{
 		0,
@@ -192,12 +1144,12 @@ func BenchmarkCompress(b *testing.B) {
 		units.MiB,
 		maxMessageSize,
 	}
-	for compressionType, newCompressorFunc := range newCompressorFuncs {
-		if compressionType == TypeNone {
+	for name, newCompressorFunc := range newCompressorFuncs {
+		if name == TypeNone.String() {
 			continue
 		}
 		for _, size := range sizes {
-			b.Run(fmt.Sprintf("%s_%d", compressionType, size), func(b *testing.B) {
+			b.Run(fmt.Sprintf("%s_%d", name, size), func(b *testing.B) {
 				require := require.New(b)
 
 				bytes := utils.RandomBytes(size)
@@ -220,12 +1172,12 @@ func BenchmarkDecompress(b *testing.B) {
 		units.MiB,
 		maxMessageSize,
 	}
-	for compressionType, newCompressorFunc := range newCompressorFuncs {
-		if compressionType == TypeNone {
+	for name, newCompressorFunc := range newCompressorFuncs {
+		if name == TypeNone.String() {
 			continue
 		}
 		for _, size := range sizes {
-			b.Run(fmt.Sprintf("%s_%d", compressionType, size), func(b *testing.B) {
+			b.Run(fmt.Sprintf("%s_%d", name, size), func(b *testing.B) {
 				require := require.New(b)
 
 				bytes := utils.RandomBytes(size)
@@ -243,3 +1195,24 @@ func BenchmarkDecompress(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkDecompressInvalid(b *testing.B) {
+	msg := []byte{0xff, 0xff, 0xff, 0xff, 0xff}
+	for name, newCompressorFunc := range newCompressorFuncs {
+		if name == TypeNone.String() {
+			continue
+		}
+		b.Run(name, func(b *testing.B) {
+			require := require.New(b)
+
+			compressor, err := newCompressorFunc(maxMessageSize)
+			require.NoError(err)
+
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				_, err := compressor.Decompress(msg)
+				require.ErrorIs(err, ErrInvalidFormat)
+			}
+		})
+	}
+}
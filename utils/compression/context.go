@@ -0,0 +1,91 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+// contextChunkSize bounds the amount of work done between checks of the
+// context.
+const contextChunkSize = 64 * units.KiB
+
+// CompressContext compresses msg using the stream format of c. If ctx is
+// cancelled before compression completes, ctx's error is returned.
+func CompressContext(ctx context.Context, c StreamCompressor, msg []byte) ([]byte, error) {
+	return streamContext(ctx, c.CompressStream, msg)
+}
+
+// DecompressContext decompresses msg using the stream format of c. If ctx is
+// cancelled before decompression completes, ctx's error is returned.
+func DecompressContext(ctx context.Context, c StreamCompressor, msg []byte) ([]byte, error) {
+	return streamContext(ctx, c.DecompressStream, msg)
+}
+
+func streamContext(
+	ctx context.Context,
+	stream func(dst io.Writer, src io.Reader) error,
+	msg []byte,
+) ([]byte, error) {
+	var (
+		dst bytes.Buffer
+		err = stream(
+			&contextWriter{ctx: ctx, writer: &dst},
+			&contextReader{ctx: ctx, reader: bytes.NewReader(msg)},
+		)
+	)
+	if err != nil {
+		// The context error may have been wrapped by the stream, so it is
+		// reported directly. A stream that completed before the context was
+		// cancelled is still returned.
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+	return dst.Bytes(), nil
+}
+
+// contextReader checks the context before reading each chunk.
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if len(p) > contextChunkSize {
+		p = p[:contextChunkSize]
+	}
+	return c.reader.Read(p)
+}
+
+// contextWriter checks the context before writing each chunk.
+type contextWriter struct {
+	ctx    context.Context
+	writer io.Writer
+}
+
+func (c *contextWriter) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		if err := c.ctx.Err(); err != nil {
+			return written, err
+		}
+		chunk := p[:min(len(p), contextChunkSize)]
+		n, err := c.writer.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
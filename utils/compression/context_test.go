@@ -0,0 +1,98 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestCompressDecompressContext(t *testing.T) {
+	for _, compressorName := range []string{"zstd", SnappyName} {
+		t.Run(compressorName, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := newCompressorFuncs[compressorName](maxMessageSize)
+			require.NoError(err)
+			streamCompressor := compressor.(StreamCompressor)
+
+			msg := bytes.Repeat([]byte("avalanche"), 100*units.KiB)
+			compressed, err := CompressContext(context.Background(), streamCompressor, msg)
+			require.NoError(err)
+
+			decompressed, err := DecompressContext(context.Background(), streamCompressor, compressed)
+			require.NoError(err)
+			require.Equal(msg, decompressed)
+		})
+	}
+}
+
+func TestDecompressContextCancelled(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	streamCompressor := compressor.(StreamCompressor)
+
+	compressed, err := CompressContext(context.Background(), streamCompressor, make([]byte, maxMessageSize))
+	require.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = DecompressContext(ctx, streamCompressor, compressed)
+	require.ErrorIs(err, context.Canceled)
+
+	_, err = CompressContext(ctx, streamCompressor, compressed)
+	require.ErrorIs(err, context.Canceled)
+}
+
+func TestStreamContextCancelledAfterCompletion(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	msg := []byte("avalanche")
+	out, err := streamContext(ctx, func(dst io.Writer, src io.Reader) error {
+		_, err := io.Copy(dst, src)
+		// The context is cancelled once the stream has completed.
+		cancel()
+		return err
+	}, msg)
+	require.NoError(err)
+	require.Equal(msg, out)
+}
+
+func TestContextWriterCancelledMidWrite(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var (
+		dst    bytes.Buffer
+		writer = &contextWriter{
+			ctx: ctx,
+			writer: writerFunc(func(p []byte) (int, error) {
+				// Cancel after the first chunk has been written.
+				cancel()
+				return dst.Write(p)
+			}),
+		}
+	)
+
+	n, err := writer.Write(make([]byte, 3*contextChunkSize))
+	require.ErrorIs(err, context.Canceled)
+	require.Equal(contextChunkSize, n)
+	require.Equal(contextChunkSize, dst.Len())
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
@@ -0,0 +1,55 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"fmt"
+	"time"
+)
+
+// CompressWithDeadline compresses msg with compressor, prefixed with the same
+// flag byte as [NewAutoCompressor] uses, so the result can be decompressed by
+// an auto compressor wrapping compressor.
+//
+// If compression takes longer than d, it is abandoned and msg is returned
+// uncompressed along with false. The abandoned compression isn't cancelled:
+// its goroutine runs until compressor returns and holds on to msg until then,
+// so compressor must be safe for concurrent use and msg must not be modified
+// until it has returned.
+//
+// If compressor reports a max size, msgs larger than it are rejected with
+// [ErrMsgTooLarge] rather than being returned uncompressed.
+func CompressWithDeadline(compressor Compressor, msg []byte, d time.Duration) ([]byte, bool, error) {
+	if maxSize, ok := reportedMaxSize(compressor); ok && int64(len(msg)) > maxSize {
+		return nil, false, fmt.Errorf("%w: (%d) > (%d)", ErrMsgTooLarge, len(msg), maxSize)
+	}
+
+	type result struct {
+		compressed []byte
+		err        error
+	}
+	// The channel is buffered so that an abandoned compression doesn't block
+	// forever.
+	results := make(chan result, 1)
+	go func() {
+		compressed, err := compressor.Compress(msg)
+		results <- result{
+			compressed: compressed,
+			err:        err,
+		}
+	}()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case r := <-results:
+		if r.err != nil {
+			return nil, false, r.err
+		}
+		return withFlag(compressedFlag, r.compressed), true, nil
+	case <-timer.C:
+		return withFlag(rawFlag, msg), false, nil
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestCompressWithDeadline(t *testing.T) {
+	require := require.New(t)
+
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+
+	msg := bytes.Repeat([]byte("avalanche"), units.KiB)
+	compressed, finished, err := CompressWithDeadline(zstdCompressor, msg, time.Minute)
+	require.NoError(err)
+	require.True(finished)
+	require.Equal(compressedFlag, compressed[0])
+
+	decompressed, err := NewAutoCompressor(zstdCompressor).Decompress(compressed)
+	require.NoError(err)
+	require.Equal(msg, decompressed)
+}
+
+func TestCompressWithDeadlineExceeded(t *testing.T) {
+	require := require.New(t)
+
+	const (
+		delay    = time.Second
+		deadline = 10 * time.Millisecond
+	)
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	slowCompressor := &delayedCompressor{
+		Compressor: zstdCompressor,
+		delay:      delay,
+	}
+
+	msg := bytes.Repeat([]byte("avalanche"), units.KiB)
+	start := time.Now()
+	compressed, finished, err := CompressWithDeadline(slowCompressor, msg, deadline)
+	elapsed := time.Since(start)
+	require.NoError(err)
+	require.False(finished)
+	require.GreaterOrEqual(elapsed, deadline)
+	require.Less(elapsed, delay)
+	require.Equal(rawFlag, compressed[0])
+	require.Equal(msg, compressed[1:])
+
+	decompressed, err := NewAutoCompressor(zstdCompressor).Decompress(compressed)
+	require.NoError(err)
+	require.Equal(msg, decompressed)
+}
+
+func TestCompressWithDeadlineMaxSize(t *testing.T) {
+	zstdCompressor, err := NewZstdCompressor(units.KiB)
+	require.NoError(t, err)
+
+	// The deadline passes immediately, so the msg would be returned
+	// uncompressed if the max size wasn't checked first.
+	_, _, err = CompressWithDeadline(zstdCompressor, make([]byte, units.KiB+1), 0)
+	require.ErrorIs(t, err, ErrMsgTooLarge)
+}
+
+func TestCompressWithDeadlineError(t *testing.T) {
+	_, _, err := CompressWithDeadline(errCompressor{err: errTest}, []byte("avalanche"), time.Minute)
+	require.ErrorIs(t, err, errTest)
+}
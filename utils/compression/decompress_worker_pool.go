@@ -0,0 +1,47 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+var _ Decompressor = (*DecompressWorkerPool)(nil)
+
+// DecompressWorkerPool shares a bounded set of worker Compressors between many
+// callers, such as the connections of a node, so that the memory held by the
+// workers is bounded while up to the configured number of messages are
+// decompressed in parallel.
+//
+// DecompressWorkerPool is safe for concurrent use.
+type DecompressWorkerPool struct {
+	factory func() Compressor
+	// workers holds the idle workers. A nil worker hasn't been created yet.
+	workers chan Compressor
+}
+
+// NewDecompressWorkerPool returns a pool of up to workers Compressors, which
+// are created with factory when they are first needed. If workers isn't
+// positive, a single worker is used.
+func NewDecompressWorkerPool(workers int, factory func() Compressor) *DecompressWorkerPool {
+	workers = max(workers, 1)
+	p := &DecompressWorkerPool{
+		factory: factory,
+		workers: make(chan Compressor, workers),
+	}
+	for range workers {
+		p.workers <- nil
+	}
+	return p
+}
+
+// Decompress decompresses msg with an idle worker, waiting for one to become
+// idle if they are all busy.
+func (p *DecompressWorkerPool) Decompress(msg []byte) ([]byte, error) {
+	worker := <-p.workers
+	if worker == nil {
+		worker = p.factory()
+	}
+	defer func() {
+		p.workers <- worker
+	}()
+
+	return worker.Decompress(msg)
+}
@@ -0,0 +1,72 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestDecompressWorkerPool(t *testing.T) {
+	require := require.New(t)
+
+	const workers = 4
+	compressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	// Every worker is the same Compressor, so that the concurrency across all
+	// of them is recorded.
+	var (
+		concurrency = &concurrencyCompressor{Compressor: compressor}
+		created     atomic.Int64
+	)
+	pool := NewDecompressWorkerPool(workers, func() Compressor {
+		created.Add(1)
+		return concurrency
+	})
+
+	var eg errgroup.Group
+	for i := range 32 {
+		eg.Go(func() error {
+			for j := range 10 {
+				msg := bytes.Repeat([]byte(fmt.Sprintf("%d-%d", i, j)), 100)
+				compressed, err := compressor.Compress(msg)
+				if err != nil {
+					return err
+				}
+				decompressed, err := pool.Decompress(compressed)
+				if err != nil {
+					return err
+				}
+				if !bytes.Equal(msg, decompressed) {
+					return fmt.Errorf("unexpected decompressed msg %q", decompressed)
+				}
+			}
+			return nil
+		})
+	}
+	require.NoError(eg.Wait())
+
+	require.LessOrEqual(concurrency.maxRunning.Load(), int64(workers))
+	require.LessOrEqual(created.Load(), int64(workers))
+}
+
+func TestDecompressWorkerPoolError(t *testing.T) {
+	require := require.New(t)
+
+	pool := NewDecompressWorkerPool(0, func() Compressor {
+		return errCompressor{err: errTest}
+	})
+
+	// The worker is returned to the pool after failing, so the single worker
+	// can be used again.
+	for range 2 {
+		_, err := pool.Decompress([]byte{1})
+		require.ErrorIs(err, errTest)
+	}
+}
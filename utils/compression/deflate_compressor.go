@@ -0,0 +1,258 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+)
+
+var (
+	_ Compressor       = (*deflateCompressor)(nil)
+	_ StreamCompressor = (*deflateCompressor)(nil)
+	_ AppendCompressor = (*deflateCompressor)(nil)
+	_ SizeEstimator    = (*deflateCompressor)(nil)
+	_ WriterCompressor = (*deflateCompressor)(nil)
+	_ ConfigReporter   = (*deflateCompressor)(nil)
+
+	ErrTrailingData = errors.New("trailing data")
+
+	errDeflateReservedBlock = fmt.Errorf("%w: reserved deflate block type", ErrInvalidFormat)
+)
+
+// NewDeflateCompressor returns a Compressor that uses the raw deflate format,
+// which avoids the 18 bytes of header and trailer added by gzip.
+//
+// Raw deflate has no magic number or checksum, so callers that may receive
+// payloads in multiple formats must tag deflate payloads, for example with
+// [NewTaggedCompressor]. Data after the end of the deflate stream is rejected
+// with [ErrTrailingData].
+func NewDeflateCompressor(maxSize int64) (Compressor, error) {
+	d, err := newDeflateCompressor(maxSize, flate.DefaultCompression, nil)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// NewDeflateCompressorWithDictionary returns a raw deflate Compressor that
+// uses dict as a preset dictionary.
+//
+// Raw deflate doesn't record which dictionary a message was compressed with,
+// so decompressing with a different dictionary may silently produce the wrong
+// output. Callers must identify the dictionary out of band, for example with a
+// [DictionaryRegistry].
+//
+// Compressors created with the same dictionary share a single copy of it and
+// their pooled writers, so creating one per connection is cheap.
+func NewDeflateCompressorWithDictionary(maxSize int64, dict []byte) (Compressor, error) {
+	d, err := newDeflateDictionaryCompressor(maxSize, dict)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// NewRawDeflateCompressor returns a Compressor for raw deflate prefixed with
+// the uncompressed length of the msg, encoded as a big-endian uint32, which is
+// the format sent by some peer implementations that aren't written in Go.
+//
+// The format is that of [NewSizePrefixedCompressor] applied to
+// [NewDeflateCompressor], so declared lengths larger than maxSize are rejected
+// before anything is decompressed. maxSize must fit in a uint32.
+func NewRawDeflateCompressor(maxSize int64) (Compressor, error) {
+	d, err := newDeflateCompressor(maxSize, flate.DefaultCompression, nil)
+	if err != nil {
+		return nil, err
+	}
+	return NewSizePrefixedCompressor(d, maxSize)
+}
+
+func newDeflateDictionaryCompressor(maxSize int64, dict []byte) (*deflateCompressor, error) {
+	// At lower levels, the standard library can emit short messages as stored
+	// blocks even when they match the dictionary. Dictionaries are intended
+	// for short messages, which are cheap to compress at the highest level.
+	return newDeflateCompressor(maxSize, flate.BestCompression, dict)
+}
+
+func newDeflateCompressor(maxSize int64, level int, dict []byte) (*deflateCompressor, error) {
+	if maxSize == math.MaxInt64 {
+		// See [newZstdCompressor] for why the max size must be less than
+		// [math.MaxInt64].
+		return nil, ErrInvalidMaxSizeCompressor
+	}
+
+	state := deflateStates.get(level, dict)
+	return &deflateCompressor{
+		maxSize: maxSize,
+		level:   level,
+		dict:    state.dict,
+		writers: &state.writers,
+	}, nil
+}
+
+type deflateCompressor struct {
+	maxSize int64
+	level   int
+	dict    []byte
+
+	// Deflate writers allocate large tables, so they are reused across calls
+	// and shared with the other compressors that have the same level and
+	// dictionary.
+	writers *sync.Pool // of *flate.Writer
+}
+
+func (d *deflateCompressor) Compress(msg []byte) ([]byte, error) {
+	return d.AppendCompress(nil, msg)
+}
+
+func (d *deflateCompressor) Decompress(msg []byte) ([]byte, error) {
+	if err := checkDeflateBlockType(msg); err != nil {
+		return nil, err
+	}
+
+	// [bytes.Reader] implements [io.ByteReader], so the deflate reader doesn't
+	// read past the end of the deflate stream.
+	source := bytes.NewReader(msg)
+	reader := flate.NewReaderDict(source, d.dict)
+	defer reader.Close()
+
+	// See [zstdCompressor.Decompress] for why maxSize + 1 bytes are read.
+	decompressed, err := io.ReadAll(io.LimitReader(reader, d.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+	if int64(len(decompressed)) > d.maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrDecompressedMsgTooLarge, len(decompressed), d.maxSize)
+	}
+	if source.Len() != 0 {
+		return nil, fmt.Errorf("%w: %w: %d bytes", ErrInvalidFormat, ErrTrailingData, source.Len())
+	}
+	return decompressed, nil
+}
+
+func (d *deflateCompressor) CompressStream(dst io.Writer, src io.Reader) error {
+	writer := d.writers.Get().(*flate.Writer)
+	defer d.writers.Put(writer)
+	writer.Reset(dst)
+
+	exceeded, err := copyLimited(writer, src, d.maxSize)
+	if err != nil {
+		return err
+	}
+	if exceeded {
+		return fmt.Errorf("%w: (> %d)", ErrMsgTooLarge, d.maxSize)
+	}
+	return writer.Close()
+}
+
+func (d *deflateCompressor) DecompressStream(dst io.Writer, src io.Reader) error {
+	// The deflate reader doesn't read past the end of the deflate stream from
+	// an [io.ByteReader], which allows trailing data to be detected.
+	source := &sourceReader{reader: src}
+	buffered := bufio.NewReader(source)
+	reader := flate.NewReaderDict(buffered, d.dict)
+	defer reader.Close()
+
+	exceeded, err := copyLimited(dst, reader, d.maxSize)
+	if err != nil {
+		return source.wrapErr(err)
+	}
+	if exceeded {
+		return fmt.Errorf("%w: (> %d)", ErrDecompressedMsgTooLarge, d.maxSize)
+	}
+	switch _, err := buffered.ReadByte(); err {
+	case nil:
+		return fmt.Errorf("%w: %w", ErrInvalidFormat, ErrTrailingData)
+	case io.EOF:
+		return nil
+	default:
+		return err
+	}
+}
+
+func (d *deflateCompressor) AppendCompress(dst, msg []byte) ([]byte, error) {
+	if int64(len(msg)) > d.maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrMsgTooLarge, len(msg), d.maxSize)
+	}
+
+	// Writes to the buffer are appended to dst.
+	buf := bytes.NewBuffer(dst)
+	writer := d.writers.Get().(*flate.Writer)
+	defer d.writers.Put(writer)
+	writer.Reset(buf)
+
+	if _, err := writer.Write(msg); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (d *deflateCompressor) AppendDecompress(dst, msg []byte) ([]byte, error) {
+	if err := checkDeflateBlockType(msg); err != nil {
+		return nil, err
+	}
+
+	source := bytes.NewReader(msg)
+	reader := flate.NewReaderDict(source, d.dict)
+	defer reader.Close()
+
+	decompressed, exceeded, err := appendLimited(dst, reader, d.maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+	if exceeded {
+		return nil, fmt.Errorf("%w: (> %d)", ErrDecompressedMsgTooLarge, d.maxSize)
+	}
+	if source.Len() != 0 {
+		return nil, fmt.Errorf("%w: %w: %d bytes", ErrInvalidFormat, ErrTrailingData, source.Len())
+	}
+	return decompressed, nil
+}
+
+func (d *deflateCompressor) Writer() *MessageWriter {
+	buf := &bytes.Buffer{}
+	writer := d.writers.Get().(*flate.Writer)
+	writer.Reset(buf)
+	return newMessageWriter(buf, writer, func() error {
+		defer d.writers.Put(writer)
+		return writer.Close()
+	}, d.maxSize)
+}
+
+func (d *deflateCompressor) Config() CompressorConfig {
+	return CompressorConfig{
+		MaxSize: d.maxSize,
+		Level:   d.level,
+	}
+}
+
+func (*deflateCompressor) EstimateCompressedSize(msg []byte) int {
+	// This is the bound used by zlib's compressBound, which covers the
+	// overhead of emitting incompressible input in stored blocks.
+	n := len(msg)
+	return n + n>>12 + n>>14 + n>>25 + 13
+}
+
+// checkDeflateBlockType rejects msgs whose first deflate block has the
+// reserved block type. Raw deflate has no magic number, so this is the only
+// check that can be made before the reader, which allocates its decoding
+// tables, is created.
+func checkDeflateBlockType(msg []byte) error {
+	// The first bit of a block is the final block flag, followed by the 2 bit
+	// block type, of which 3 is reserved.
+	if len(msg) > 0 && (msg[0]>>1)&0x3 == 0x3 {
+		return errDeflateReservedBlock
+	}
+	return nil
+}
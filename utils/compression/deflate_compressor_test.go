@@ -0,0 +1,213 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/hex"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func newDeflateZipBomb() []byte {
+	var buf bytes.Buffer
+	writer, _ := flate.NewWriter(&buf, flate.BestCompression)
+	_, _ = writer.Write(make([]byte, 2*maxMessageSize))
+	_ = writer.Close()
+	return buf.Bytes()
+}
+
+// TestDeflateCompressorVectors pins the output of the deflate compressor, so
+// that changes to how writers are configured or reused can't silently change
+// the encoding. Every vector must also be decodable by the standard library.
+func TestDeflateCompressorVectors(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      []byte
+		expected string
+	}{
+		{
+			name:     "empty",
+			msg:      []byte{},
+			expected: "0300",
+		},
+		{
+			name:     "small",
+			msg:      []byte("avalanche"),
+			expected: "000900f6ff6176616c616e6368650300",
+		},
+		{
+			name:     "large repetitive",
+			msg:      bytes.Repeat([]byte("avalanche"), units.KiB),
+			expected: "ecc6b10900200c04c0591f112c2465e6cf1c81eb2e9d9f3aef0200000000000000000000000000000000c03acc00",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := NewDeflateCompressor(maxMessageSize)
+			require.NoError(err)
+
+			expected, err := hex.DecodeString(test.expected)
+			require.NoError(err)
+
+			// Compress twice to cover writers that are reused from the pool.
+			for range 2 {
+				compressed, err := compressor.Compress(test.msg)
+				require.NoError(err)
+				require.Equal(expected, compressed)
+			}
+
+			decompressed, err := compressor.Decompress(expected)
+			require.NoError(err)
+			require.Equal(test.msg, decompressed)
+
+			reader := flate.NewReader(bytes.NewReader(expected))
+			decompressed, err = io.ReadAll(reader)
+			require.NoError(err)
+			require.NoError(reader.Close())
+			require.Equal(test.msg, decompressed)
+		})
+	}
+}
+
+// TestRawDeflateCompressorInterop decodes payloads whose deflate streams were
+// produced by zlib 1.2.13 with a raw window (wbits -15) at level 9, rather
+// than by the standard library. Each vector is the big-endian uncompressed
+// length followed by the deflate stream.
+func TestRawDeflateCompressorInterop(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		msg     []byte
+	}{
+		{
+			name:    "empty",
+			payload: "000000000300",
+			msg:     []byte{},
+		},
+		{
+			name:    "small",
+			payload: "000000094b2c4bcc49cc4bce480500",
+			msg:     []byte("avalanche"),
+		},
+		{
+			name:    "repetitive",
+			payload: "000000c84b2c4bcc49cc4bce4855481cd22c00",
+			msg:     bytes.Repeat([]byte("avalanche "), 20),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := NewRawDeflateCompressor(maxMessageSize)
+			require.NoError(err)
+
+			payload, err := hex.DecodeString(test.payload)
+			require.NoError(err)
+			decompressed, err := compressor.Decompress(payload)
+			require.NoError(err)
+			require.Equal(test.msg, decompressed)
+
+			// The encoding differs from zlib's, but must round trip.
+			compressed, err := compressor.Compress(test.msg)
+			require.NoError(err)
+			require.Equal(payload[:4], compressed[:4])
+			decompressed, err = compressor.Decompress(compressed)
+			require.NoError(err)
+			require.Equal(test.msg, decompressed)
+		})
+	}
+}
+
+func TestRawDeflateCompressorLengthPrefix(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewRawDeflateCompressor(units.KiB)
+	require.NoError(err)
+
+	// The declared length is checked before decompressing.
+	_, err = compressor.Decompress([]byte{0x00, 0x00, 0x04, 0x01, 0x03, 0x00})
+	require.ErrorIs(err, ErrDecompressedMsgTooLarge)
+
+	// A declared length that doesn't match the deflate stream is rejected.
+	_, err = compressor.Decompress([]byte{0x00, 0x00, 0x00, 0x01, 0x03, 0x00})
+	require.ErrorIs(err, ErrInvalidFormat)
+
+	_, err = NewRawDeflateCompressor(math.MaxUint32 + 1)
+	require.ErrorIs(err, ErrInvalidMaxSizeCompressor)
+}
+
+func TestDeflateCompressorSmallerThanGzip(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewDeflateCompressor(maxMessageSize)
+	require.NoError(err)
+
+	msg := newTestDictionaryMessage(0)[:100]
+	msg = append(msg, msg...)
+	require.Len(msg, 200)
+
+	compressed, err := compressor.Compress(msg)
+	require.NoError(err)
+
+	var gzipped bytes.Buffer
+	writer := gzip.NewWriter(&gzipped)
+	_, err = writer.Write(msg)
+	require.NoError(err)
+	require.NoError(writer.Close())
+
+	// gzip wraps the same deflate stream with a 10 byte header and an 8 byte
+	// trailer.
+	require.Equal(gzipped.Len()-18, len(compressed))
+}
+
+func TestDeflateCompressorTruncated(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewDeflateCompressor(maxMessageSize)
+	require.NoError(err)
+
+	compressed, err := compressor.Compress(newTestDictionaryMessage(0))
+	require.NoError(err)
+
+	_, err = compressor.Decompress(compressed[:len(compressed)-1])
+	require.ErrorIs(err, ErrInvalidFormat)
+}
+
+func TestDeflateCompressorTrailingData(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewDeflateCompressor(maxMessageSize)
+	require.NoError(err)
+
+	msg := newTestDictionaryMessage(0)
+	compressed, err := compressor.Compress(msg)
+	require.NoError(err)
+
+	// A clean stream is fully consumed.
+	var decompressed bytes.Buffer
+	require.NoError(compressor.(StreamCompressor).DecompressStream(&decompressed, bytes.NewReader(compressed)))
+	require.Equal(msg, decompressed.Bytes())
+
+	withTrailer := append(compressed, 0)
+
+	_, err = compressor.Decompress(withTrailer)
+	require.ErrorIs(err, ErrTrailingData)
+
+	_, err = compressor.(AppendCompressor).AppendDecompress(nil, withTrailer)
+	require.ErrorIs(err, ErrTrailingData)
+
+	err = compressor.(StreamCompressor).DecompressStream(io.Discard, bytes.NewReader(withTrailer))
+	require.ErrorIs(err, ErrTrailingData)
+}
@@ -0,0 +1,79 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"compress/flate"
+	"crypto/sha256"
+	"slices"
+	"sync"
+)
+
+// maxSharedDeflateStates bounds the number of distinct level and dictionary
+// pairs whose state is cached. Compressors created after the cache is full get
+// state of their own, which keeps the cache from growing without bound if
+// dictionaries are rotated.
+const maxSharedDeflateStates = 64
+
+var deflateStates = &deflateStateCache{
+	states: make(map[deflateStateKey]*deflateState),
+}
+
+type deflateStateKey struct {
+	level    int
+	dictHash [sha256.Size]byte
+}
+
+// deflateState is the state of a deflate compressor that only depends on its
+// level and dictionary, so it can be shared by all the compressors created with
+// them.
+type deflateState struct {
+	dict []byte
+	// The writers are reset between uses, which keeps the dictionary, so any
+	// compressor with the same level and dictionary can use them.
+	writers sync.Pool // of *flate.Writer
+}
+
+func newDeflateState(level int, dict []byte) *deflateState {
+	dict = slices.Clone(dict)
+	return &deflateState{
+		dict: dict,
+		writers: sync.Pool{
+			New: func() any {
+				// NewWriterDict only errors for invalid levels. Reset keeps
+				// the dictionary.
+				writer, _ := flate.NewWriterDict(nil, level, dict)
+				return writer
+			},
+		},
+	}
+}
+
+// deflateStateCache shares [deflateState] between compressors keyed by the
+// hash of their dictionary, so that many compressors using the same large
+// dictionary, such as one per connection, hold a single copy of it and only as
+// many writers as are used concurrently.
+type deflateStateCache struct {
+	lock   sync.Mutex
+	states map[deflateStateKey]*deflateState
+}
+
+func (c *deflateStateCache) get(level int, dict []byte) *deflateState {
+	key := deflateStateKey{
+		level:    level,
+		dictHash: sha256.Sum256(dict),
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if state, ok := c.states[key]; ok {
+		return state
+	}
+	state := newDeflateState(level, dict)
+	if len(c.states) < maxSharedDeflateStates {
+		c.states[key] = state
+	}
+	return state
+}
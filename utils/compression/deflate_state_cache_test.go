@@ -0,0 +1,99 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"compress/flate"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeflateCompressorsShareDictionaryState(t *testing.T) {
+	require := require.New(t)
+
+	// The dictionary is copied, so modifying the caller's copy must not affect
+	// the cached state.
+	dict := append([]byte{}, testDictionary...)
+	a, err := newDeflateDictionaryCompressor(maxMessageSize, dict)
+	require.NoError(err)
+	dict[0]++
+	b, err := newDeflateDictionaryCompressor(maxMessageSize, testDictionary)
+	require.NoError(err)
+	other, err := newDeflateDictionaryCompressor(maxMessageSize, otherTestDictionary)
+	require.NoError(err)
+
+	require.Same(a.writers, b.writers)
+	require.Equal(testDictionary, a.dict)
+	require.NotSame(a.writers, other.writers)
+
+	// Sharing writers must not affect the output.
+	msg := newTestDictionaryMessage(0)
+	compressed, err := a.Compress(msg)
+	require.NoError(err)
+	decompressed, err := b.Decompress(compressed)
+	require.NoError(err)
+	require.Equal(msg, decompressed)
+}
+
+func TestDeflateStateCacheLevels(t *testing.T) {
+	cache := &deflateStateCache{
+		states: make(map[deflateStateKey]*deflateState),
+	}
+	require.NotSame(t, cache.get(flate.BestSpeed, testDictionary), cache.get(flate.BestCompression, testDictionary))
+}
+
+func TestDeflateStateCacheBounded(t *testing.T) {
+	require := require.New(t)
+
+	cache := &deflateStateCache{
+		states: make(map[deflateStateKey]*deflateState),
+	}
+	for i := 0; i < maxSharedDeflateStates; i++ {
+		dict := []byte(fmt.Sprintf("dictionary %d", i))
+		require.Same(cache.get(flate.BestCompression, dict), cache.get(flate.BestCompression, dict))
+	}
+
+	// Once the cache is full, new dictionaries get state of their own.
+	dict := []byte("one too many")
+	require.NotSame(cache.get(flate.BestCompression, dict), cache.get(flate.BestCompression, dict))
+	require.Len(cache.states, maxSharedDeflateStates)
+}
+
+// BenchmarkDeflateDictionaryCompressors reports the memory used by many
+// compressors with the same dictionary, each of which compresses a message,
+// with and without sharing state.
+func BenchmarkDeflateDictionaryCompressors(b *testing.B) {
+	const numCompressors = 100
+	newUnshared := func() *deflateCompressor {
+		state := newDeflateState(flate.BestCompression, testDictionary)
+		return &deflateCompressor{
+			maxSize: maxMessageSize,
+			dict:    state.dict,
+			writers: &state.writers,
+		}
+	}
+	newShared := func() *deflateCompressor {
+		compressor, _ := newDeflateDictionaryCompressor(maxMessageSize, testDictionary)
+		return compressor
+	}
+	for name, newCompressor := range map[string]func() *deflateCompressor{
+		"unshared": newUnshared,
+		"shared":   newShared,
+	} {
+		b.Run(name, func(b *testing.B) {
+			require := require.New(b)
+
+			msg := newTestDictionaryMessage(0)
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				for range numCompressors {
+					_, err := newCompressor().Compress(msg)
+					require.NoError(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import "bytes"
+
+// Stream identifier chunks that the snappy and s2 framing formats start with.
+var (
+	snappyStreamMagic = []byte("\xff\x06\x00\x00sNaPpY")
+	s2StreamMagic     = []byte("\xff\x06\x00\x00S2sTwO")
+)
+
+// DetectAlgorithm returns the registered name of the compressor that produced
+// msg, based on the magic number msg starts with. If no magic number is
+// recognized, false is returned.
+//
+// Only self-identifying formats can be detected: zstd frames, and snappy and s2
+// streams. Snappy and s2 blocks, as produced by Compress, have no magic number
+// and are never detected. Streams must be decompressed with the
+// DecompressStream method of the registered compressor, as its Decompress
+// method only accepts blocks.
+func DetectAlgorithm(msg []byte) (string, bool) {
+	switch {
+	case isZstdFrame(msg):
+		return TypeZstd.String(), true
+	case bytes.HasPrefix(msg, snappyStreamMagic):
+		return SnappyName, true
+	case bytes.HasPrefix(msg, s2StreamMagic):
+		return S2Name, true
+	default:
+		return "", false
+	}
+}
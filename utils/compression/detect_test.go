@@ -0,0 +1,119 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestDetectAlgorithm(t *testing.T) {
+	msg := bytes.Repeat([]byte("avalanche"), units.KiB)
+
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(t, err)
+	zstdCompressed, err := zstdCompressor.Compress(msg)
+	require.NoError(t, err)
+
+	zstdDictionaryCompressor, err := NewZstdCompressorWithDictionary(maxMessageSize, testDictionary)
+	require.NoError(t, err)
+	zstdDictionaryCompressed, err := zstdDictionaryCompressor.Compress(msg)
+	require.NoError(t, err)
+
+	snappyCompressor, err := NewSnappyCompressor(maxMessageSize)
+	require.NoError(t, err)
+	var snappyStream bytes.Buffer
+	require.NoError(t, snappyCompressor.(StreamCompressor).CompressStream(&snappyStream, bytes.NewReader(msg)))
+
+	s2Compressor, err := NewS2Compressor(maxMessageSize)
+	require.NoError(t, err)
+	var s2Stream bytes.Buffer
+	require.NoError(t, s2Compressor.(StreamCompressor).CompressStream(&s2Stream, bytes.NewReader(msg)))
+
+	tests := []struct {
+		name         string
+		msg          []byte
+		expectedName string
+		expectedOk   bool
+	}{
+		{
+			name:         "zstd",
+			msg:          zstdCompressed,
+			expectedName: TypeZstd.String(),
+			expectedOk:   true,
+		},
+		{
+			name:         "zstd with dictionary",
+			msg:          zstdDictionaryCompressed,
+			expectedName: TypeZstd.String(),
+			expectedOk:   true,
+		},
+		{
+			name:         "snappy stream",
+			msg:          snappyStream.Bytes(),
+			expectedName: SnappyName,
+			expectedOk:   true,
+		},
+		{
+			name:         "s2 stream",
+			msg:          s2Stream.Bytes(),
+			expectedName: S2Name,
+			expectedOk:   true,
+		},
+		{
+			name: "empty",
+			msg:  nil,
+		},
+		{
+			name: "truncated magic",
+			msg:  zstdCompressed[:3],
+		},
+		{
+			name: "random",
+			msg:  append([]byte{0}, utils.RandomBytes(units.KiB)...),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			name, ok := DetectAlgorithm(test.msg)
+			require.Equal(test.expectedOk, ok)
+			require.Equal(test.expectedName, name)
+		})
+	}
+}
+
+func TestDetectAlgorithmStreams(t *testing.T) {
+	msg := bytes.Repeat([]byte("avalanche"), units.KiB)
+
+	for _, name := range []string{SnappyName, S2Name} {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := NewCompressorByName(name, maxMessageSize)
+			require.NoError(err)
+			streamCompressor := compressor.(StreamCompressor)
+
+			var compressed bytes.Buffer
+			require.NoError(streamCompressor.CompressStream(&compressed, bytes.NewReader(msg)))
+
+			detectedName, ok := DetectAlgorithm(compressed.Bytes())
+			require.True(ok)
+			require.Equal(name, detectedName)
+
+			// The detected compressor decodes the stream with DecompressStream.
+			detected, err := NewCompressorByName(detectedName, maxMessageSize)
+			require.NoError(err)
+			var decompressed bytes.Buffer
+			require.NoError(detected.(StreamCompressor).DecompressStream(&decompressed, bytes.NewReader(compressed.Bytes())))
+			require.Equal(msg, decompressed.Bytes())
+		})
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+)
+
+var (
+	_ Compressor = (*dictionaryCompressor)(nil)
+
+	ErrUnknownDictionary   = errors.New("unknown dictionary")
+	ErrDuplicateDictionary = errors.New("duplicate dictionary")
+)
+
+// DictionaryRegistry maps message type IDs to the preset deflate dictionaries
+// that messages of that type are compressed with. Messages are compressed as
+// by [NewDeflateCompressorWithDictionary].
+type DictionaryRegistry struct {
+	maxSize int64
+
+	lock        sync.RWMutex
+	compressors map[uint16]*deflateCompressor
+}
+
+// NewDictionaryRegistry returns an empty DictionaryRegistry whose compressors
+// bound messages to maxSize bytes.
+func NewDictionaryRegistry(maxSize int64) *DictionaryRegistry {
+	return &DictionaryRegistry{
+		maxSize:     maxSize,
+		compressors: make(map[uint16]*deflateCompressor),
+	}
+}
+
+// Register makes dict available for messages of type typeID. Each type ID can
+// only be registered once.
+func (r *DictionaryRegistry) Register(typeID uint16, dict []byte) error {
+	compressor, err := newDeflateDictionaryCompressor(r.maxSize, dict)
+	if err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.compressors[typeID]; ok {
+		return fmt.Errorf("%w: %d", ErrDuplicateDictionary, typeID)
+	}
+	r.compressors[typeID] = compressor
+	return nil
+}
+
+// NewDictCompressorFor returns a Compressor that compresses messages with the
+// dictionary registered for typeID. Compressed messages are prefixed with
+// typeID, and Decompress uses the dictionary registered for the prefixed type
+// ID, so messages of any registered type can be decompressed.
+func (r *DictionaryRegistry) NewDictCompressorFor(typeID uint16) (Compressor, error) {
+	compressor, err := r.get(typeID)
+	if err != nil {
+		return nil, err
+	}
+	return &dictionaryCompressor{
+		registry:   r,
+		typeID:     typeID,
+		compressor: compressor,
+	}, nil
+}
+
+func (r *DictionaryRegistry) get(typeID uint16) (*deflateCompressor, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	compressor, ok := r.compressors[typeID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownDictionary, typeID)
+	}
+	return compressor, nil
+}
+
+type dictionaryCompressor struct {
+	registry   *DictionaryRegistry
+	typeID     uint16
+	compressor *deflateCompressor
+}
+
+func (d *dictionaryCompressor) Compress(msg []byte) ([]byte, error) {
+	dst := binary.BigEndian.AppendUint16(nil, d.typeID)
+	return d.compressor.AppendCompress(dst, msg)
+}
+
+func (d *dictionaryCompressor) Decompress(msg []byte) ([]byte, error) {
+	if len(msg) < wrappers.ShortLen {
+		return nil, fmt.Errorf("%w: missing dictionary type ID", ErrInvalidFormat)
+	}
+	compressor, err := d.registry.get(binary.BigEndian.Uint16(msg))
+	if err != nil {
+		return nil, err
+	}
+	return compressor.Decompress(msg[wrappers.ShortLen:])
+}
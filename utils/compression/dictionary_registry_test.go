@@ -0,0 +1,87 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testVoteDictionary  = []byte(`{"type":"vote","height":1000000,"blockID":"2wLHnpEmLhaD12vS8ZCTo4Y3t9dZ9dipzvjMtAGd6pV7SJnGB","nodeID":"NodeID-7Xhw2mDxuDS44j42TCB6U5579esbSt3Lg"}`)
+	testBlockDictionary = []byte(`{"type":"block","height":1000000,"parentID":"2wLHnpEmLhaD12vS8ZCTo4Y3t9dZ9dipzvjMtAGd6pV7SJnGB","txs":[]}`)
+)
+
+const (
+	testVoteTypeID uint16 = iota + 1
+	testBlockTypeID
+	testUnknownTypeID
+)
+
+func newTestDictionaryRegistry(t *testing.T) *DictionaryRegistry {
+	registry := NewDictionaryRegistry(maxMessageSize)
+	require.NoError(t, registry.Register(testVoteTypeID, testVoteDictionary))
+	require.NoError(t, registry.Register(testBlockTypeID, testBlockDictionary))
+	return registry
+}
+
+func TestDictionaryRegistry(t *testing.T) {
+	require := require.New(t)
+
+	registry := newTestDictionaryRegistry(t)
+	voteCompressor, err := registry.NewDictCompressorFor(testVoteTypeID)
+	require.NoError(err)
+	blockCompressor, err := registry.NewDictCompressorFor(testBlockTypeID)
+	require.NoError(err)
+
+	vote := []byte(`{"type":"vote","height":1000123,"blockID":"2wLHnpEmLhaD12vS8ZCTo4Y3t9dZ9dipzvjMtAGd6pV7SJnGB","nodeID":"NodeID-7Xhw2mDxuDS44j42TCB6U5579esbSt3Lg"}`)
+	compressedVote, err := voteCompressor.Compress(vote)
+	require.NoError(err)
+
+	// The dictionary shares most of the message, so the message compresses
+	// much better than without it.
+	deflateCompressor, err := NewDeflateCompressor(maxMessageSize)
+	require.NoError(err)
+	compressedWithoutDict, err := deflateCompressor.Compress(vote)
+	require.NoError(err)
+	require.Less(len(compressedVote), len(compressedWithoutDict))
+
+	// The type ID is read from the message, so any compressor from the
+	// registry selects the vote dictionary.
+	for _, compressor := range []Compressor{voteCompressor, blockCompressor} {
+		decompressed, err := compressor.Decompress(compressedVote)
+		require.NoError(err)
+		require.Equal(vote, decompressed)
+	}
+}
+
+func TestDictionaryRegistryDuplicate(t *testing.T) {
+	registry := newTestDictionaryRegistry(t)
+	err := registry.Register(testVoteTypeID, testBlockDictionary)
+	require.ErrorIs(t, err, ErrDuplicateDictionary)
+}
+
+func TestDictionaryRegistryUnknown(t *testing.T) {
+	require := require.New(t)
+
+	registry := newTestDictionaryRegistry(t)
+	_, err := registry.NewDictCompressorFor(testUnknownTypeID)
+	require.ErrorIs(err, ErrUnknownDictionary)
+
+	compressor, err := registry.NewDictCompressorFor(testVoteTypeID)
+	require.NoError(err)
+	compressed, err := compressor.Compress(bytes.Repeat([]byte("avalanche"), 1024))
+	require.NoError(err)
+
+	// A message claiming an unregistered type must not be decompressed with
+	// another type's dictionary.
+	compressed[0], compressed[1] = 0, byte(testUnknownTypeID)
+	_, err = compressor.Decompress(compressed)
+	require.ErrorIs(err, ErrUnknownDictionary)
+
+	_, err = compressor.Decompress([]byte{0})
+	require.ErrorIs(err, ErrInvalidFormat)
+}
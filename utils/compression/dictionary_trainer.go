@@ -0,0 +1,113 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"cmp"
+	"slices"
+)
+
+// trainingGramLen is the length of the substrings that TrainDictionary
+// counts. Longer common substrings are found by joining overlapping grams.
+const trainingGramLen = 8
+
+// TrainDictionary returns a preset dictionary of at most maxSize bytes for
+// [NewDeflateCompressorWithDictionary], built from substrings that are common
+// across samples.
+//
+// This is a best-effort helper rather than an optimal trainer. Runs of bytes
+// that appear in at least a quarter of the samples are collected, and the most
+// frequent runs are placed at the end of the dictionary, where deflate can
+// reference them most cheaply. Deflate only references the last 32 KiB of a
+// dictionary, so larger values of maxSize are not useful.
+func TrainDictionary(samples [][]byte, maxSize int) []byte {
+	minCount := max(2, len(samples)/4)
+
+	gramCounts := make(map[string]int)
+	forEachDistinct(samples, func(sample []byte) [][]byte {
+		var grams [][]byte
+		for i := 0; i+trainingGramLen <= len(sample); i++ {
+			grams = append(grams, sample[i:i+trainingGramLen])
+		}
+		return grams
+	}, gramCounts)
+
+	segmentCounts := make(map[string]int)
+	forEachDistinct(samples, func(sample []byte) [][]byte {
+		// Join consecutive common grams into the longest common runs.
+		var (
+			segments [][]byte
+			start    = -1
+		)
+		for i := 0; i+trainingGramLen <= len(sample); i++ {
+			common := gramCounts[string(sample[i:i+trainingGramLen])] >= minCount
+			switch {
+			case common && start < 0:
+				start = i
+			case !common && start >= 0:
+				segments = append(segments, sample[start:i-1+trainingGramLen])
+				start = -1
+			}
+		}
+		if start >= 0 {
+			segments = append(segments, sample[start:])
+		}
+		return segments
+	}, segmentCounts)
+
+	type scoredSegment struct {
+		segment string
+		score   int
+	}
+	scored := make([]scoredSegment, 0, len(segmentCounts))
+	for segment, count := range segmentCounts {
+		if count >= minCount {
+			scored = append(scored, scoredSegment{
+				segment: segment,
+				score:   count * len(segment),
+			})
+		}
+	}
+	slices.SortFunc(scored, func(a, b scoredSegment) int {
+		if c := cmp.Compare(b.score, a.score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.segment, b.segment)
+	})
+
+	var selected [][]byte
+	size := 0
+	for _, s := range scored {
+		segment := []byte(s.segment)
+		if size+len(segment) > maxSize || slices.ContainsFunc(selected, func(selected []byte) bool {
+			return bytes.Contains(selected, segment)
+		}) {
+			continue
+		}
+		selected = append(selected, segment)
+		size += len(segment)
+	}
+
+	// Deflate encodes closer matches more cheaply, so the most valuable
+	// segments go last.
+	slices.Reverse(selected)
+	return bytes.Join(selected, nil)
+}
+
+// forEachDistinct adds one to counts for every distinct substring that split
+// returns for each sample.
+func forEachDistinct(samples [][]byte, split func([]byte) [][]byte, counts map[string]int) {
+	for _, sample := range samples {
+		seen := make(map[string]struct{})
+		for _, substring := range split(sample) {
+			key := string(substring)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			counts[key]++
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func newTestTrainingSamples(start, end int) [][]byte {
+	samples := make([][]byte, 0, end-start)
+	for i := start; i < end; i++ {
+		samples = append(samples, newTestDictionaryMessage(i))
+	}
+	return samples
+}
+
+func TestTrainDictionary(t *testing.T) {
+	require := require.New(t)
+
+	dict := TrainDictionary(newTestTrainingSamples(0, 100), units.KiB)
+	require.NotEmpty(dict)
+	require.LessOrEqual(len(dict), units.KiB)
+
+	withoutDictionary, err := NewDeflateCompressor(maxMessageSize)
+	require.NoError(err)
+	withDictionary, err := NewDeflateCompressorWithDictionary(maxMessageSize, dict)
+	require.NoError(err)
+
+	// Use messages that weren't part of the training set.
+	for _, msg := range newTestTrainingSamples(5000, 5010) {
+		compressedWithout, err := withoutDictionary.Compress(msg)
+		require.NoError(err)
+		compressedWith, err := withDictionary.Compress(msg)
+		require.NoError(err)
+		require.Less(len(compressedWith), len(compressedWithout)/2)
+
+		decompressed, err := withDictionary.Decompress(compressedWith)
+		require.NoError(err)
+		require.Equal(msg, decompressed)
+	}
+}
+
+func TestTrainDictionaryDeterministic(t *testing.T) {
+	samples := newTestTrainingSamples(0, 100)
+	require.Equal(t, TrainDictionary(samples, units.KiB), TrainDictionary(samples, units.KiB))
+}
+
+func TestTrainDictionaryMaxSize(t *testing.T) {
+	dict := TrainDictionary(newTestTrainingSamples(0, 100), 16)
+	require.LessOrEqual(t, len(dict), 16)
+}
+
+func TestTrainDictionaryNoCommonSubstrings(t *testing.T) {
+	samples := [][]byte{
+		utils.RandomBytes(units.KiB),
+		utils.RandomBytes(units.KiB),
+	}
+	require.Empty(t, TrainDictionary(samples, units.KiB))
+	require.Empty(t, TrainDictionary(nil, units.KiB))
+}
@@ -0,0 +1,47 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import "errors"
+
+var _ Compressor = (*failSafeCompressor)(nil)
+
+// NewFailSafeCompressor returns a Compressor that sends messages uncompressed
+// rather than failing when compressor errors. Payloads are prefixed with the
+// same flag byte as [NewAutoCompressor], so either can decompress the other's
+// output.
+//
+// If onError is non-nil, it is called with every error that caused a message
+// to be sent uncompressed. Messages that are too large for compressor are
+// still rejected with [ErrMsgTooLarge], so that wrapping compressor doesn't
+// lift its limit. Decompression errors are returned as usual.
+func NewFailSafeCompressor(compressor Compressor, onError func(error)) Compressor {
+	return &failSafeCompressor{
+		compressor: compressor,
+		onError:    onError,
+	}
+}
+
+type failSafeCompressor struct {
+	compressor Compressor
+	onError    func(error)
+}
+
+func (f *failSafeCompressor) Compress(msg []byte) ([]byte, error) {
+	compressed, err := f.compressor.Compress(msg)
+	if errors.Is(err, ErrMsgTooLarge) {
+		return nil, err
+	}
+	if err != nil {
+		if f.onError != nil {
+			f.onError(err)
+		}
+		return withFlag(rawFlag, msg), nil
+	}
+	return withFlag(compressedFlag, compressed), nil
+}
+
+func (f *failSafeCompressor) Decompress(msg []byte) ([]byte, error) {
+	return decompressFlagged(f.compressor, msg)
+}
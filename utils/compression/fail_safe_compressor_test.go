@@ -0,0 +1,102 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+// errCompressor fails every call with err.
+type errCompressor struct {
+	err error
+}
+
+func (e errCompressor) Compress([]byte) ([]byte, error) {
+	return nil, e.err
+}
+
+func (e errCompressor) Decompress([]byte) ([]byte, error) {
+	return nil, e.err
+}
+
+func TestFailSafeCompressor(t *testing.T) {
+	require := require.New(t)
+
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+
+	var errs []error
+	compressor := NewFailSafeCompressor(zstdCompressor, func(err error) {
+		errs = append(errs, err)
+	})
+
+	msg := bytes.Repeat([]byte("avalanche"), units.KiB)
+	compressed, err := compressor.Compress(msg)
+	require.NoError(err)
+	require.Equal(compressedFlag, compressed[0])
+	require.Empty(errs)
+
+	decompressed, err := compressor.Decompress(compressed)
+	require.NoError(err)
+	require.Equal(msg, decompressed)
+}
+
+func TestFailSafeCompressorFallback(t *testing.T) {
+	require := require.New(t)
+
+	var errs []error
+	compressor := NewFailSafeCompressor(errCompressor{err: errTest}, func(err error) {
+		errs = append(errs, err)
+	})
+
+	msg := bytes.Repeat([]byte("avalanche"), units.KiB)
+	compressed, err := compressor.Compress(msg)
+	require.NoError(err)
+	require.Equal(rawFlag, compressed[0])
+	require.Equal([]error{errTest}, errs)
+
+	// Raw payloads don't need the failing compressor to be decompressed.
+	decompressed, err := compressor.Decompress(compressed)
+	require.NoError(err)
+	require.Equal(msg, decompressed)
+
+	_, err = compressor.Decompress(append([]byte{compressedFlag}, msg...))
+	require.ErrorIs(err, errTest)
+}
+
+func TestFailSafeCompressorNilCallback(t *testing.T) {
+	compressor := NewFailSafeCompressor(errCompressor{err: errTest}, nil)
+	compressed, err := compressor.Compress([]byte("avalanche"))
+	require.NoError(t, err)
+	require.Equal(t, rawFlag, compressed[0])
+}
+
+func TestFailSafeCompressorMaxSize(t *testing.T) {
+	require := require.New(t)
+
+	zstdCompressor, err := NewZstdCompressor(units.KiB)
+	require.NoError(err)
+
+	var errs []error
+	compressor := NewFailSafeCompressor(zstdCompressor, func(err error) {
+		errs = append(errs, err)
+	})
+
+	_, err = compressor.Compress(make([]byte, units.KiB+1))
+	require.ErrorIs(err, ErrMsgTooLarge)
+	require.Empty(errs)
+
+	// Raw payloads are limited to the max size of the compressor.
+	_, err = compressor.Decompress(append([]byte{rawFlag}, make([]byte, units.KiB+1)...))
+	require.ErrorIs(err, ErrDecompressedMsgTooLarge)
+
+	decompressed, err := compressor.Decompress(append([]byte{rawFlag}, make([]byte, units.KiB)...))
+	require.NoError(err)
+	require.Len(decompressed, units.KiB)
+}
@@ -0,0 +1,53 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+
+	"golang.org/x/exp/mmap"
+
+	"github.com/ava-labs/avalanchego/utils/perms"
+)
+
+// DecompressToFile decompresses msg, in the stream format of c, into the file
+// at dstPath, which is created or truncated. The decompressed bytes are
+// written as they are produced, so they are never held in memory in full.
+//
+// If an error is returned, the file is removed.
+func DecompressToFile(c StreamCompressor, dstPath string, msg []byte) error {
+	file, err := os.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perms.ReadWrite)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	err = c.DecompressStream(writer, bytes.NewReader(msg))
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Join(err, os.Remove(dstPath))
+	}
+	return nil
+}
+
+// DecompressToMappedFile decompresses msg into the file at dstPath, as by
+// [DecompressToFile], and maps the file into memory for read-only access.
+// Pages of the decompressed file are only loaded when they are accessed, and
+// can be reclaimed by the OS under memory pressure.
+//
+// The caller must close the returned reader, which doesn't remove the file.
+func DecompressToMappedFile(c StreamCompressor, dstPath string, msg []byte) (*mmap.ReaderAt, error) {
+	if err := DecompressToFile(c, dstPath, msg); err != nil {
+		return nil, err
+	}
+	return mmap.Open(dstPath)
+}
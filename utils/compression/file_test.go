@@ -0,0 +1,58 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestDecompressToFile(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewZstdCompressor(64 * units.MiB)
+	require.NoError(err)
+	msg := append(utils.RandomBytes(units.MiB), make([]byte, 16*units.MiB)...)
+	compressed, err := CompressReader(compressor.(StreamCompressor), bytes.NewReader(msg))
+	require.NoError(err)
+
+	path := filepath.Join(t.TempDir(), "decompressed")
+	require.NoError(DecompressToFile(compressor.(StreamCompressor), path, compressed))
+
+	decompressed, err := os.ReadFile(path)
+	require.NoError(err)
+	require.Equal(msg, decompressed)
+
+	mapped, err := DecompressToMappedFile(compressor.(StreamCompressor), path, compressed)
+	require.NoError(err)
+	defer mapped.Close()
+
+	require.Equal(len(msg), mapped.Len())
+	decompressed = make([]byte, mapped.Len())
+	_, err = mapped.ReadAt(decompressed, 0)
+	require.NoError(err)
+	require.Equal(msg, decompressed)
+}
+
+func TestDecompressToFileInvalidFormat(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+
+	path := filepath.Join(t.TempDir(), "decompressed")
+	err = DecompressToFile(compressor.(StreamCompressor), path, []byte{0xff, 0xff, 0xff, 0xff, 0xff})
+	require.ErrorIs(err, ErrInvalidFormat)
+
+	// The partially written file is removed.
+	_, err = os.Stat(path)
+	require.ErrorIs(err, os.ErrNotExist)
+}
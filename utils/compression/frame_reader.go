@@ -0,0 +1,82 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+)
+
+// FrameReader reads msgs from a stream of length-prefixed frames, as written
+// by [NewCompressedConn].
+//
+// Frames are a 4 byte length followed by a payload prefixed with the same flag
+// byte as [NewAutoCompressor], so msgs that don't shrink are sent raw.
+//
+// A FrameReader isn't safe for concurrent use.
+type FrameReader struct {
+	reader       io.Reader
+	compressor   Compressor
+	maxFrameSize uint32
+	header       [wrappers.IntLen]byte
+}
+
+// NewFrameReader returns a FrameReader that reads frames from r and
+// decompresses them with compressor. Frames with a length larger than
+// maxFrameSize are rejected with [ErrInvalidFormat] before they are read,
+// which bounds the memory a peer can make us allocate.
+func NewFrameReader(r io.Reader, compressor Compressor, maxFrameSize uint32) *FrameReader {
+	return &FrameReader{
+		reader:       r,
+		compressor:   compressor,
+		maxFrameSize: maxFrameSize,
+	}
+}
+
+// Next reads and decompresses the next frame. If r ends between frames, Next
+// returns [io.EOF]. If r ends within a frame, [io.ErrUnexpectedEOF] is
+// returned.
+func (f *FrameReader) Next() ([]byte, error) {
+	if _, err := io.ReadFull(f.reader, f.header[:]); err != nil {
+		// ReadFull returns io.EOF only if no bytes of the header were read.
+		return nil, err
+	}
+	frameLen := binary.BigEndian.Uint32(f.header[:])
+	if frameLen > f.maxFrameSize {
+		return nil, fmt.Errorf("%w: frame length (%d) > (%d)", ErrInvalidFormat, frameLen, f.maxFrameSize)
+	}
+
+	frame := make([]byte, frameLen)
+	if _, err := io.ReadFull(f.reader, frame); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return decompressFlagged(f.compressor, frame)
+}
+
+// appendFrame appends msg to dst as a frame that can be read by a
+// [FrameReader].
+func appendFrame(dst []byte, compressor Compressor, msg []byte, maxFrameSize uint32) ([]byte, error) {
+	payload, compressed, err := CompressChecked(compressor, msg)
+	if err != nil {
+		return nil, err
+	}
+	frameLen := 1 + uint64(len(payload))
+	if frameLen > uint64(maxFrameSize) {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrMsgTooLarge, frameLen, maxFrameSize)
+	}
+
+	dst = binary.BigEndian.AppendUint32(dst, uint32(frameLen))
+	if compressed {
+		dst = append(dst, compressedFlag)
+	} else {
+		dst = append(dst, rawFlag)
+	}
+	return append(dst, payload...), nil
+}
@@ -0,0 +1,67 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestFrameReader(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+
+	msgs := [][]byte{
+		newTestDictionaryMessage(0),
+		// Random bytes don't shrink, so they are sent raw.
+		utils.RandomBytes(units.KiB),
+		{},
+		bytes.Repeat([]byte("avalanche"), 100*units.KiB),
+	}
+	var stream []byte
+	for _, msg := range msgs {
+		stream, err = appendFrame(stream, compressor, msg, maxMessageSize)
+		require.NoError(err)
+	}
+	// The random bytes are sent uncompressed.
+	require.True(bytes.Contains(stream, append([]byte{rawFlag}, msgs[1]...)))
+
+	reader := NewFrameReader(bytes.NewReader(stream), compressor, maxMessageSize)
+	for _, expected := range msgs {
+		msg, err := reader.Next()
+		require.NoError(err)
+		require.Equal(expected, append([]byte{}, msg...))
+	}
+	_, err = reader.Next()
+	require.Equal(io.EOF, err)
+}
+
+func TestFrameReaderTruncated(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+
+	var stream []byte
+	for i := range 2 {
+		stream, err = appendFrame(stream, compressor, newTestDictionaryMessage(i), maxMessageSize)
+		require.NoError(err)
+	}
+
+	reader := NewFrameReader(bytes.NewReader(stream[:len(stream)-1]), compressor, maxMessageSize)
+	msg, err := reader.Next()
+	require.NoError(err)
+	require.Equal(newTestDictionaryMessage(0), msg)
+
+	_, err = reader.Next()
+	require.ErrorIs(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,44 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidFrameSize = errors.New("invalid frame size")
+
+// CompressFramed compresses msg with compressor and splits the result into
+// frames of frameSize bytes, except for the last frame which may be shorter.
+// The frames alias a single compressed buffer.
+func CompressFramed(compressor Compressor, msg []byte, frameSize int) ([][]byte, error) {
+	if frameSize <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidFrameSize, frameSize)
+	}
+
+	compressed, err := compressor.Compress(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	frames := make([][]byte, 0, (len(compressed)+frameSize-1)/frameSize)
+	for start := 0; start < len(compressed); start += frameSize {
+		end := min(start+frameSize, len(compressed))
+		// Limiting the capacity prevents appends to a frame from overwriting
+		// the next frame.
+		frames = append(frames, compressed[start:end:end])
+	}
+	return frames, nil
+}
+
+// DecompressFramed reassembles frames, which must have been produced by
+// [CompressFramed], and decompresses them with decompressor.
+func DecompressFramed(decompressor Decompressor, frames [][]byte) ([]byte, error) {
+	if len(frames) == 1 {
+		return decompressor.Decompress(frames[0])
+	}
+	return decompressor.Decompress(bytes.Join(frames, nil))
+}
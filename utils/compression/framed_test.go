@@ -0,0 +1,85 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestCompressDecompressFramed(t *testing.T) {
+	compressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(t, err)
+
+	msg := utils.RandomBytes(10 * units.KiB)
+	compressed, err := compressor.Compress(msg)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name              string
+		frameSize         int
+		expectedNumFrames int
+	}{
+		{
+			name:              "single frame",
+			frameSize:         len(compressed),
+			expectedNumFrames: 1,
+		},
+		{
+			name:              "larger than compressed",
+			frameSize:         2 * len(compressed),
+			expectedNumFrames: 1,
+		},
+		{
+			name:              "not a multiple of the compressed length",
+			frameSize:         units.KiB,
+			expectedNumFrames: (len(compressed) + units.KiB - 1) / units.KiB,
+		},
+		{
+			name:              "single byte frames",
+			frameSize:         1,
+			expectedNumFrames: len(compressed),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			frames, err := CompressFramed(compressor, msg, test.frameSize)
+			require.NoError(err)
+			require.Len(frames, test.expectedNumFrames)
+			for _, frame := range frames {
+				require.LessOrEqual(len(frame), test.frameSize)
+			}
+
+			decompressed, err := DecompressFramed(compressor, frames)
+			require.NoError(err)
+			require.Equal(msg, decompressed)
+		})
+	}
+}
+
+func TestCompressFramedInvalidFrameSize(t *testing.T) {
+	for _, frameSize := range []int{-1, 0} {
+		_, err := CompressFramed(NewNoCompressor(), []byte{1}, frameSize)
+		require.ErrorIs(t, err, ErrInvalidFrameSize)
+	}
+}
+
+func TestDecompressFramedMissingFrame(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewDeflateCompressor(maxMessageSize)
+	require.NoError(err)
+
+	frames, err := CompressFramed(compressor, utils.RandomBytes(10*units.KiB), units.KiB)
+	require.NoError(err)
+
+	_, err = DecompressFramed(compressor, frames[:len(frames)-1])
+	require.ErrorIs(err, ErrInvalidFormat)
+}
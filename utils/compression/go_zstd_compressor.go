@@ -0,0 +1,268 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	_ Compressor       = (*goZstdCompressor)(nil)
+	_ StreamCompressor = (*goZstdCompressor)(nil)
+	_ AppendCompressor = (*goZstdCompressor)(nil)
+	_ SizeEstimator    = (*goZstdCompressor)(nil)
+	_ WriterCompressor = (*goZstdCompressor)(nil)
+	_ ConfigReporter   = (*goZstdCompressor)(nil)
+)
+
+// goZstdCompressor is a pure Go implementation of the zstd Compressor. It
+// produces and accepts the same format as [zstdCompressor], but it maps
+// compression levels onto the coarser levels of the Go encoder, so its output
+// isn't byte for byte identical.
+type goZstdCompressor struct {
+	maxSize int64
+	level   int
+	// encoderLevel is the coarser level of the Go encoder that level is
+	// mapped to.
+	encoderLevel zstd.EncoderLevel
+	// dict, if not nil, is the formatted zstd dictionary with ID
+	// dictionaryID that msgs are compressed with.
+	dict         []byte
+	dictionaryID uint32
+
+	// The encoder is safe for concurrent use by EncodeAll.
+	encoder *zstd.Encoder
+	// Decoders hold their window across calls, so they are reused.
+	decoders sync.Pool // of *zstd.Decoder
+}
+
+func newGoZstdCompressor(maxSize int64, level int) (*goZstdCompressor, error) {
+	z, err := newGoZstdCompressorConfig(maxSize, level)
+	if err != nil {
+		return nil, err
+	}
+	return z, z.init()
+}
+
+// newGoZstdDictionaryCompressor returns a goZstdCompressor that uses dict as a
+// preset dictionary. See [NewZstdCompressorWithDictionary] for the
+// requirements of dict.
+func newGoZstdDictionaryCompressor(maxSize int64, level int, dict []byte) (*goZstdCompressor, error) {
+	z, err := newGoZstdCompressorConfig(maxSize, level)
+	if err != nil {
+		return nil, err
+	}
+	z.dictionaryID, err = zstdDictionaryID(dict)
+	if err != nil {
+		return nil, err
+	}
+	// The dictionary is copied so that the caller's slice can't be modified
+	// out from under any in-flight streams.
+	z.dict = slices.Clone(dict)
+	// The default level of the Go encoder barely uses the dictionary for
+	// short msgs, which dictionaries are intended for.
+	z.encoderLevel = max(z.encoderLevel, zstd.SpeedBetterCompression)
+	if err := z.init(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDictionary, err)
+	}
+	return z, nil
+}
+
+func newGoZstdCompressorConfig(maxSize int64, level int) (*goZstdCompressor, error) {
+	if level < zstdBestSpeed || level > zstdBestCompression {
+		return nil, fmt.Errorf("%w: %d not in [%d, %d]", ErrInvalidCompressionLevel, level, zstdBestSpeed, zstdBestCompression)
+	}
+	if maxSize == math.MaxInt64 {
+		// See [newZstdCompressor] for why the max size must be less than
+		// [math.MaxInt64].
+		return nil, ErrInvalidMaxSizeCompressor
+	}
+	return &goZstdCompressor{
+		maxSize:      maxSize,
+		level:        level,
+		encoderLevel: zstd.EncoderLevelFromZstd(level),
+	}, nil
+}
+
+// init creates the encoder and the decoder pool of z once it has been
+// configured.
+func (z *goZstdCompressor) init() error {
+	encoder, err := zstd.NewWriter(nil, z.encoderOptions()...)
+	if err != nil {
+		return err
+	}
+	// Decoders are created lazily, so their options are checked now.
+	decoder, err := z.newDecoder(nil)
+	if err != nil {
+		return err
+	}
+	z.encoder = encoder
+	z.decoders.Put(decoder)
+	z.decoders.New = func() any {
+		// The options were checked by init.
+		decoder, _ := z.newDecoder(nil)
+		return decoder
+	}
+	return nil
+}
+
+func (z *goZstdCompressor) encoderOptions() []zstd.EOption {
+	options := []zstd.EOption{
+		zstd.WithEncoderLevel(z.encoderLevel),
+		// Like the reference library, empty msgs are encoded as a frame
+		// rather than as no bytes, and no checksum is written.
+		zstd.WithZeroFrames(true),
+		zstd.WithEncoderCRC(false),
+	}
+	if z.dict != nil {
+		options = append(options, zstd.WithEncoderDict(z.dict))
+	}
+	return options
+}
+
+func (z *goZstdCompressor) newDecoder(src io.Reader) (*zstd.Decoder, error) {
+	// With a concurrency of 1, the decoder doesn't start any goroutines, so
+	// it doesn't need to be closed to be garbage collected.
+	options := []zstd.DOption{
+		zstd.WithDecoderConcurrency(1),
+	}
+	if z.dict != nil {
+		options = append(options, zstd.WithDecoderDicts(z.dict))
+	}
+	return zstd.NewReader(src, options...)
+}
+
+// newFrameParser returns a parser that rejects frames compressed with a
+// different dictionary.
+func (z *goZstdCompressor) newFrameParser() *zstdFrameParser {
+	frames := newZstdFrameParser()
+	if z.dict == nil {
+		return frames
+	}
+	frames.checkDictionaryID = func(dictionaryID uint32) error {
+		if dictionaryID != z.dictionaryID {
+			return fmt.Errorf("%w: frame uses dictionary %d, expected %d", ErrUnknownDictionary, dictionaryID, z.dictionaryID)
+		}
+		return nil
+	}
+	return frames
+}
+
+func (z *goZstdCompressor) Compress(msg []byte) ([]byte, error) {
+	return z.AppendCompress(nil, msg)
+}
+
+func (z *goZstdCompressor) Decompress(msg []byte) ([]byte, error) {
+	decompressed, err := z.AppendDecompress(nil, msg)
+	if err != nil {
+		return nil, err
+	}
+	// Like the reference library, an empty msg is returned as an empty slice
+	// rather than nil.
+	if decompressed == nil {
+		decompressed = []byte{}
+	}
+	return decompressed, nil
+}
+
+func (z *goZstdCompressor) CompressStream(dst io.Writer, src io.Reader) error {
+	writer := z.newWriter(dst)
+	exceeded, err := copyLimited(writer, src, z.maxSize)
+	if err != nil {
+		_ = writer.Close()
+		return err
+	}
+	if exceeded {
+		_ = writer.Close()
+		return fmt.Errorf("%w: (> %d)", ErrMsgTooLarge, z.maxSize)
+	}
+	return writer.Close()
+}
+
+func (z *goZstdCompressor) DecompressStream(dst io.Writer, src io.Reader) error {
+	return decompressZstdStream(dst, src, z.maxSize, z.newFrameParser(), func(src io.Reader) io.ReadCloser {
+		// The options were checked by init.
+		decoder, _ := z.newDecoder(src)
+		return decoder.IOReadCloser()
+	})
+}
+
+func (z *goZstdCompressor) Config() CompressorConfig {
+	return CompressorConfig{
+		MaxSize: z.maxSize,
+		Level:   z.level,
+	}
+}
+
+func (z *goZstdCompressor) AppendCompress(dst, msg []byte) ([]byte, error) {
+	if int64(len(msg)) > z.maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrMsgTooLarge, len(msg), z.maxSize)
+	}
+	return z.encoder.EncodeAll(msg, dst), nil
+}
+
+func (z *goZstdCompressor) AppendDecompress(dst, msg []byte) ([]byte, error) {
+	if err := checkZstdMagic(msg); err != nil {
+		return nil, err
+	}
+	if err := checkZstdFrame(z.newFrameParser(), msg); err != nil {
+		return nil, err
+	}
+
+	decoder := z.decoders.Get().(*zstd.Decoder)
+	defer func() {
+		// Resetting to a nil reader releases the reference to msg.
+		_ = decoder.Reset(nil)
+		z.decoders.Put(decoder)
+	}()
+	if err := decoder.Reset(bytes.NewReader(msg)); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+
+	// The decoder's own size limit rejects frames with windows larger than
+	// the limit, even if their content is small, so the output is limited
+	// while reading instead.
+	decompressed, exceeded, err := appendLimited(dst, decoder, z.maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+	if exceeded {
+		return nil, fmt.Errorf("%w: (> %d)", ErrDecompressedMsgTooLarge, z.maxSize)
+	}
+	return decompressed, nil
+}
+
+func (z *goZstdCompressor) Writer() *MessageWriter {
+	buf := &bytes.Buffer{}
+	writer := z.newWriter(buf)
+	return newMessageWriter(buf, writer, writer.Close, z.maxSize)
+}
+
+func (z *goZstdCompressor) newWriter(dst io.Writer) *zstd.Encoder {
+	// The options were checked by init.
+	writer, _ := zstd.NewWriter(dst, append(z.encoderOptions(), zstd.WithEncoderConcurrency(1))...)
+	return writer
+}
+
+func (*goZstdCompressor) EstimateCompressedSize(msg []byte) int {
+	return zstdCompressBound(len(msg))
+}
+
+// zstdCompressBound is the ZSTD_COMPRESSBOUND macro of the reference zstd
+// library.
+func zstdCompressBound(n int) int {
+	const lowLimit = 128 << 10
+	margin := 0
+	if n < lowLimit {
+		margin = (lowLimit - n) >> 11
+	}
+	return n + n>>8 + margin
+}
@@ -0,0 +1,85 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestGoZstdCompressorLevels(t *testing.T) {
+	tests := []struct {
+		name        string
+		level       int
+		expectedErr error
+	}{
+		{
+			name:        "too low",
+			level:       zstdBestSpeed - 1,
+			expectedErr: ErrInvalidCompressionLevel,
+		},
+		{
+			name:  "best speed",
+			level: zstdBestSpeed,
+		},
+		{
+			name:  "best compression",
+			level: zstdBestCompression,
+		},
+		{
+			name:        "too high",
+			level:       zstdBestCompression + 1,
+			expectedErr: ErrInvalidCompressionLevel,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := newGoZstdCompressor(maxMessageSize, test.level)
+			require.ErrorIs(t, err, test.expectedErr)
+		})
+	}
+}
+
+func TestZstdTruncatedTrailer(t *testing.T) {
+	// The zstd Compressors don't write frame checksums, but other senders
+	// may.
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderCRC(true))
+	require.NoError(t, err)
+	msg := bytes.Repeat([]byte("avalanche"), units.KiB)
+	compressed := encoder.EncodeAll(msg, nil)
+	require.NoError(t, encoder.Close())
+
+	for _, name := range []string{"zstd", "zstd_pooled", "zstd_go"} {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := newCompressorFuncs[name](maxMessageSize)
+			require.NoError(err)
+
+			decompressed, err := compressor.Decompress(compressed)
+			require.NoError(err)
+			require.Equal(msg, decompressed)
+
+			withoutTrailer := compressed[:len(compressed)-zstdChecksumLen]
+			_, err = compressor.Decompress(withoutTrailer)
+			require.ErrorIs(err, ErrTruncatedTrailer)
+			_, err = compressor.(AppendCompressor).AppendDecompress(nil, withoutTrailer)
+			require.ErrorIs(err, ErrTruncatedTrailer)
+			err = compressor.(StreamCompressor).DecompressStream(io.Discard, bytes.NewReader(withoutTrailer))
+			require.ErrorIs(err, ErrTruncatedTrailer)
+
+			// Cutting into the last block is reported as a truncated stream
+			// rather than a truncated trailer.
+			_, err = compressor.Decompress(compressed[:len(compressed)-zstdChecksumLen-1])
+			require.ErrorIs(err, ErrTruncatedStream)
+			require.NotErrorIs(err, ErrTruncatedTrailer)
+		})
+	}
+}
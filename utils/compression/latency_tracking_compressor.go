@@ -0,0 +1,72 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+var _ Compressor = (*LatencyTrackingCompressor)(nil)
+
+// LatencyTrackingCompressor wraps a Compressor and records the longest time
+// taken by a call to Compress and to Decompress, which is cheaper than a
+// histogram and simple to alert on. Failed calls are tracked as well, since
+// they take time too.
+type LatencyTrackingCompressor struct {
+	compressor Compressor
+
+	// The latencies are stored in nanoseconds.
+	maxCompressLatency   atomic.Int64
+	maxDecompressLatency atomic.Int64
+}
+
+func NewLatencyTrackingCompressor(compressor Compressor) *LatencyTrackingCompressor {
+	return &LatencyTrackingCompressor{
+		compressor: compressor,
+	}
+}
+
+func (l *LatencyTrackingCompressor) Compress(msg []byte) ([]byte, error) {
+	start := time.Now()
+	compressed, err := l.compressor.Compress(msg)
+	storeMax(&l.maxCompressLatency, time.Since(start))
+	return compressed, err
+}
+
+func (l *LatencyTrackingCompressor) Decompress(msg []byte) ([]byte, error) {
+	start := time.Now()
+	decompressed, err := l.compressor.Decompress(msg)
+	storeMax(&l.maxDecompressLatency, time.Since(start))
+	return decompressed, err
+}
+
+// MaxCompressLatency returns the longest time taken by Compress since the
+// LatencyTrackingCompressor was created or last reset.
+func (l *LatencyTrackingCompressor) MaxCompressLatency() time.Duration {
+	return time.Duration(l.maxCompressLatency.Load())
+}
+
+// MaxDecompressLatency returns the longest time taken by Decompress since the
+// LatencyTrackingCompressor was created or last reset.
+func (l *LatencyTrackingCompressor) MaxDecompressLatency() time.Duration {
+	return time.Duration(l.maxDecompressLatency.Load())
+}
+
+// Reset clears the recorded latencies, so that the maximums can be reported
+// per interval.
+func (l *LatencyTrackingCompressor) Reset() {
+	l.maxCompressLatency.Store(0)
+	l.maxDecompressLatency.Store(0)
+}
+
+// storeMax sets v to d if d is larger than the current value of v.
+func storeMax(v *atomic.Int64, d time.Duration) {
+	for {
+		current := v.Load()
+		if int64(d) <= current || v.CompareAndSwap(current, int64(d)) {
+			return
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// delayedCompressor sleeps for delay before every call.
+type delayedCompressor struct {
+	Compressor
+	delay time.Duration
+}
+
+func (d *delayedCompressor) Compress(msg []byte) ([]byte, error) {
+	time.Sleep(d.delay)
+	return d.Compressor.Compress(msg)
+}
+
+func (d *delayedCompressor) Decompress(msg []byte) ([]byte, error) {
+	time.Sleep(d.delay)
+	return d.Compressor.Decompress(msg)
+}
+
+func TestLatencyTrackingCompressor(t *testing.T) {
+	require := require.New(t)
+
+	const delay = 10 * time.Millisecond
+
+	inner := &delayedCompressor{
+		Compressor: NewNoCompressor(),
+	}
+	compressor := NewLatencyTrackingCompressor(inner)
+
+	msg := []byte("avalanche")
+	_, err := compressor.Compress(msg)
+	require.NoError(err)
+	_, err = compressor.Decompress(msg)
+	require.NoError(err)
+	require.Less(compressor.MaxCompressLatency(), delay)
+	require.Less(compressor.MaxDecompressLatency(), delay)
+
+	inner.delay = delay
+	_, err = compressor.Compress(msg)
+	require.NoError(err)
+	require.GreaterOrEqual(compressor.MaxCompressLatency(), delay)
+	require.Less(compressor.MaxDecompressLatency(), delay)
+
+	_, err = compressor.Decompress(msg)
+	require.NoError(err)
+	require.GreaterOrEqual(compressor.MaxDecompressLatency(), delay)
+
+	// Faster calls don't lower the maximum.
+	inner.delay = 0
+	_, err = compressor.Compress(msg)
+	require.NoError(err)
+	require.GreaterOrEqual(compressor.MaxCompressLatency(), delay)
+
+	compressor.Reset()
+	require.Zero(compressor.MaxCompressLatency())
+	require.Zero(compressor.MaxDecompressLatency())
+}
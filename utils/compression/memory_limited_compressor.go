@@ -0,0 +1,77 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/sync/semaphore"
+)
+
+var _ Compressor = (*memoryLimitedCompressor)(nil)
+
+// MemoryLimiter bounds the memory used by decompressions across all the
+// Compressors that share it, which per Compressor limits can't do when many
+// peers send large payloads at once.
+//
+// MemoryLimiter is safe for concurrent use.
+type MemoryLimiter struct {
+	budget int64
+	sem    *semaphore.Weighted
+}
+
+// NewMemoryLimiter returns a MemoryLimiter that allows up to budget bytes to
+// be held at once.
+func NewMemoryLimiter(budget int64) *MemoryLimiter {
+	return &MemoryLimiter{
+		budget: budget,
+		sem:    semaphore.NewWeighted(budget),
+	}
+}
+
+// Acquire blocks until n bytes of the budget are available or ctx is done.
+func (m *MemoryLimiter) Acquire(ctx context.Context, n int64) error {
+	return m.sem.Acquire(ctx, n)
+}
+
+// Release returns n bytes to the budget, which must have been acquired.
+func (m *MemoryLimiter) Release(n int64) {
+	m.sem.Release(n)
+}
+
+// NewMemoryLimitedCompressor returns a Compressor that acquires maxSize bytes
+// from limiter for the duration of each Decompress, blocking until they are
+// available. maxSize must be the max size of compressor, since the output size
+// isn't known until msg has been decompressed, and must not exceed the budget
+// of limiter. Compress isn't limited.
+func NewMemoryLimitedCompressor(compressor Compressor, limiter *MemoryLimiter, maxSize int64) (Compressor, error) {
+	if maxSize <= 0 || maxSize > limiter.budget {
+		return nil, fmt.Errorf("%w: %d not in [1, %d]", ErrInvalidMaxSizeCompressor, maxSize, limiter.budget)
+	}
+	return &memoryLimitedCompressor{
+		compressor: compressor,
+		limiter:    limiter,
+		maxSize:    maxSize,
+	}, nil
+}
+
+type memoryLimitedCompressor struct {
+	compressor Compressor
+	limiter    *MemoryLimiter
+	maxSize    int64
+}
+
+func (m *memoryLimitedCompressor) Compress(msg []byte) ([]byte, error) {
+	return m.compressor.Compress(msg)
+}
+
+func (m *memoryLimitedCompressor) Decompress(msg []byte) ([]byte, error) {
+	if err := m.limiter.Acquire(context.Background(), m.maxSize); err != nil {
+		return nil, err
+	}
+	defer m.limiter.Release(m.maxSize)
+
+	return m.compressor.Decompress(msg)
+}
@@ -0,0 +1,94 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+// concurrencyCompressor records the largest number of concurrent calls to
+// Decompress.
+type concurrencyCompressor struct {
+	Compressor
+	running    atomic.Int64
+	maxRunning atomic.Int64
+}
+
+func (c *concurrencyCompressor) Decompress(msg []byte) ([]byte, error) {
+	running := c.running.Add(1)
+	defer c.running.Add(-1)
+	for {
+		current := c.maxRunning.Load()
+		if running <= current || c.maxRunning.CompareAndSwap(current, running) {
+			break
+		}
+	}
+
+	// Make overlapping calls likely if they aren't prevented.
+	time.Sleep(time.Millisecond)
+	return c.Compressor.Decompress(msg)
+}
+
+func TestMemoryLimitedCompressor(t *testing.T) {
+	tests := []struct {
+		name               string
+		budget             int64
+		expectedMaxRunning int64
+	}{
+		{
+			name:               "serialized",
+			budget:             units.KiB,
+			expectedMaxRunning: 1,
+		},
+		{
+			name:               "two at a time",
+			budget:             2*units.KiB + 1,
+			expectedMaxRunning: 2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			inner := &concurrencyCompressor{
+				Compressor: NewNoCompressor(),
+			}
+			limiter := NewMemoryLimiter(test.budget)
+			compressor, err := NewMemoryLimitedCompressor(inner, limiter, units.KiB)
+			require.NoError(err)
+
+			var eg errgroup.Group
+			for range 8 {
+				eg.Go(func() error {
+					for range 10 {
+						if _, err := compressor.Decompress([]byte("avalanche")); err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+			}
+			require.NoError(eg.Wait())
+			require.LessOrEqual(inner.maxRunning.Load(), test.expectedMaxRunning)
+
+			// The budget is fully released.
+			require.NoError(limiter.Acquire(context.Background(), test.budget))
+		})
+	}
+}
+
+func TestNewMemoryLimitedCompressorMaxSize(t *testing.T) {
+	limiter := NewMemoryLimiter(units.KiB)
+
+	_, err := NewMemoryLimitedCompressor(NewNoCompressor(), limiter, units.KiB+1)
+	require.ErrorIs(t, err, ErrInvalidMaxSizeCompressor)
+}
@@ -0,0 +1,123 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+)
+
+// hashChunkSize is the size of the pieces that CompressHashed passes to the
+// hash and the compressor in turn, which is small enough for each piece to
+// still be cached when the second one reads it.
+const hashChunkSize = 32 * 1024
+
+var _ io.Writer = (*MessageWriter)(nil)
+
+// WriterCompressor is implemented by compressors that can compress a msg that
+// is written in pieces, which avoids joining the pieces before compressing.
+type WriterCompressor interface {
+	// Writer returns a MessageWriter whose result can be decompressed by the
+	// Compressor.
+	Writer() *MessageWriter
+}
+
+// MessageWriter incrementally builds a single compressed msg. Bytes must be
+// called once all the pieces have been written to release the resources
+// held by the MessageWriter.
+//
+// A MessageWriter isn't safe for concurrent use.
+type MessageWriter struct {
+	buf     *bytes.Buffer
+	writer  io.Writer
+	finish  func() error
+	maxSize int64
+	written int64
+	closed  bool
+}
+
+// newMessageWriter returns a MessageWriter that writes to writer, which
+// compresses into buf, and finalizes the msg with finish.
+func newMessageWriter(buf *bytes.Buffer, writer io.Writer, finish func() error, maxSize int64) *MessageWriter {
+	return &MessageWriter{
+		buf:     buf,
+		writer:  writer,
+		finish:  finish,
+		maxSize: maxSize,
+	}
+}
+
+// Write appends p to the msg. If the msg would exceed the max size of the
+// Compressor, [ErrMsgTooLarge] is returned and p isn't written.
+func (m *MessageWriter) Write(p []byte) (int, error) {
+	if m.closed {
+		return 0, ErrClosed
+	}
+	if int64(len(p)) > m.maxSize-m.written {
+		return 0, fmt.Errorf("%w: (> %d)", ErrMsgTooLarge, m.maxSize)
+	}
+	n, err := m.writer.Write(p)
+	m.written += int64(n)
+	return n, err
+}
+
+// Bytes finalizes the msg and returns it compressed. The MessageWriter can't
+// be used afterwards.
+func (m *MessageWriter) Bytes() ([]byte, error) {
+	if m.closed {
+		return nil, ErrClosed
+	}
+	m.closed = true
+	if err := m.finish(); err != nil {
+		return nil, err
+	}
+	return m.buf.Bytes(), nil
+}
+
+// CompressEncoded encodes v with encode directly into a [MessageWriter] of
+// compressor and returns the compressed result, so the encoded msg is never
+// held uncompressed in memory. encode may write to w in as many pieces as it
+// needs, for example with a [encoding/json.Encoder].
+func CompressEncoded(compressor WriterCompressor, v any, encode func(w io.Writer, v any) error) ([]byte, error) {
+	writer := compressor.Writer()
+	if err := encode(writer, v); err != nil {
+		// The writer must still be finalized to release its resources.
+		_, _ = writer.Bytes()
+		return nil, err
+	}
+	return writer.Bytes()
+}
+
+// CompressHashed compresses msg with compressor and returns the SHA-256 hash
+// of msg along with the compressed result, for stores that address msgs by
+// their uncompressed content. msg is hashed and compressed piece by piece, so
+// each piece is only loaded from memory once.
+func CompressHashed(compressor WriterCompressor, msg []byte) ([]byte, [sha256.Size]byte, error) {
+	var (
+		writer = compressor.Writer()
+		hash   = sha256.New()
+	)
+	for len(msg) > 0 {
+		chunk := msg[:min(len(msg), hashChunkSize)]
+		msg = msg[len(chunk):]
+
+		// Writes to a hash never error.
+		_, _ = hash.Write(chunk)
+		if _, err := writer.Write(chunk); err != nil {
+			// The writer must still be finalized to release its resources.
+			_, _ = writer.Bytes()
+			return nil, [sha256.Size]byte{}, err
+		}
+	}
+
+	compressed, err := writer.Bytes()
+	if err != nil {
+		return nil, [sha256.Size]byte{}, err
+	}
+	var contentHash [sha256.Size]byte
+	hash.Sum(contentHash[:0])
+	return compressed, contentHash, nil
+}
@@ -0,0 +1,141 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestMessageWriter(t *testing.T) {
+	pieces := [][]byte{
+		newTestDictionaryMessage(0),
+		bytes.Repeat([]byte("avalanche"), 10*units.KiB),
+		utils.RandomBytes(units.KiB),
+	}
+	for name, newCompressorFunc := range newCompressorFuncs {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := newCompressorFunc(maxMessageSize)
+			require.NoError(err)
+			writerCompressor, ok := compressor.(WriterCompressor)
+			if !ok {
+				t.Skip("compressor doesn't support writers")
+			}
+
+			writer := writerCompressor.Writer()
+			for _, piece := range pieces {
+				n, err := writer.Write(piece)
+				require.NoError(err)
+				require.Len(piece, n)
+			}
+			compressed, err := writer.Bytes()
+			require.NoError(err)
+
+			decompressed, err := compressor.Decompress(compressed)
+			require.NoError(err)
+			require.Equal(bytes.Join(pieces, nil), decompressed)
+
+			_, err = writer.Write([]byte{1})
+			require.ErrorIs(err, ErrClosed)
+			_, err = writer.Bytes()
+			require.ErrorIs(err, ErrClosed)
+		})
+	}
+}
+
+func TestMessageWriterTooLarge(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewZstdCompressor(units.KiB)
+	require.NoError(err)
+
+	writer := compressor.(WriterCompressor).Writer()
+	_, err = writer.Write(make([]byte, units.KiB))
+	require.NoError(err)
+	_, err = writer.Write([]byte{1})
+	require.ErrorIs(err, ErrMsgTooLarge)
+
+	compressed, err := writer.Bytes()
+	require.NoError(err)
+	decompressed, err := compressor.Decompress(compressed)
+	require.NoError(err)
+	require.Equal(make([]byte, units.KiB), decompressed)
+}
+
+func TestCompressEncoded(t *testing.T) {
+	require := require.New(t)
+
+	type message struct {
+		Name   string
+		Values []int
+	}
+
+	compressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+
+	expected := message{
+		Name:   "avalanche",
+		Values: []int{1, 2, 3},
+	}
+	compressed, err := CompressEncoded(compressor.(WriterCompressor), expected, func(w io.Writer, v any) error {
+		return json.NewEncoder(w).Encode(v)
+	})
+	require.NoError(err)
+
+	decompressed, err := compressor.Decompress(compressed)
+	require.NoError(err)
+	var decoded message
+	require.NoError(json.Unmarshal(decompressed, &decoded))
+	require.Equal(expected, decoded)
+}
+
+func TestCompressEncodedError(t *testing.T) {
+	compressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(t, err)
+
+	_, err = CompressEncoded(compressor.(WriterCompressor), nil, func(io.Writer, any) error {
+		return errTest
+	})
+	require.ErrorIs(t, err, errTest)
+}
+
+func TestCompressHashed(t *testing.T) {
+	for _, name := range []string{"zstd", "zstd_go", "deflate"} {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := newCompressorFuncs[name](maxMessageSize)
+			require.NoError(err)
+
+			for _, size := range []int{0, 1, hashChunkSize, hashChunkSize + 1, units.MiB} {
+				msg := utils.RandomBytes(size)
+				compressed, contentHash, err := CompressHashed(compressor.(WriterCompressor), msg)
+				require.NoError(err)
+				require.Equal(sha256.Sum256(msg), contentHash)
+
+				decompressed, err := compressor.Decompress(compressed)
+				require.NoError(err)
+				require.Equal(msg, append([]byte{}, decompressed...))
+			}
+		})
+	}
+}
+
+func TestCompressHashedTooLarge(t *testing.T) {
+	compressor, err := NewZstdCompressor(units.KiB)
+	require.NoError(t, err)
+
+	_, _, err = CompressHashed(compressor.(WriterCompressor), make([]byte, units.KiB+1))
+	require.ErrorIs(t, err, ErrMsgTooLarge)
+}
@@ -0,0 +1,94 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const opLabel = "op"
+
+var (
+	_ Compressor = (*metricsCompressor)(nil)
+
+	metricLabels = []string{opLabel}
+)
+
+// NewMetricsCompressor returns a Compressor that reports the calls, errors,
+// and bytes that pass through compressor to registerer.
+func NewMetricsCompressor(compressor Compressor, registerer prometheus.Registerer) (Compressor, error) {
+	m := &metricsCompressor{
+		compressor: compressor,
+		calls: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "compressor_calls",
+				Help: "number of calls to the compressor",
+			},
+			metricLabels,
+		),
+		errors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "compressor_errors",
+				Help: "number of calls to the compressor that failed",
+			},
+			metricLabels,
+		),
+		bytesIn: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "compressor_bytes_in",
+				Help: "number of bytes provided to successful calls to the compressor",
+			},
+			metricLabels,
+		),
+		bytesOut: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "compressor_bytes_out",
+				Help: "number of bytes returned by successful calls to the compressor",
+			},
+			metricLabels,
+		),
+	}
+	return m, errors.Join(
+		registerer.Register(m.calls),
+		registerer.Register(m.errors),
+		registerer.Register(m.bytesIn),
+		registerer.Register(m.bytesOut),
+	)
+}
+
+type metricsCompressor struct {
+	compressor Compressor
+
+	calls    *prometheus.CounterVec // op
+	errors   *prometheus.CounterVec // op
+	bytesIn  *prometheus.CounterVec // op
+	bytesOut *prometheus.CounterVec // op
+}
+
+func (m *metricsCompressor) Compress(msg []byte) ([]byte, error) {
+	compressed, err := m.compressor.Compress(msg)
+	m.observe(CompressOp, msg, compressed, err)
+	return compressed, err
+}
+
+func (m *metricsCompressor) Decompress(msg []byte) ([]byte, error) {
+	decompressed, err := m.compressor.Decompress(msg)
+	m.observe(DecompressOp, msg, decompressed, err)
+	return decompressed, err
+}
+
+func (m *metricsCompressor) observe(op string, in, out []byte, err error) {
+	labels := prometheus.Labels{
+		opLabel: op,
+	}
+	m.calls.With(labels).Inc()
+	if err != nil {
+		m.errors.With(labels).Inc()
+		return
+	}
+	m.bytesIn.With(labels).Add(float64(len(in)))
+	m.bytesOut.With(labels).Add(float64(len(out)))
+}
@@ -0,0 +1,56 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsCompressor(t *testing.T) {
+	require := require.New(t)
+
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	compressorIntf, err := NewMetricsCompressor(zstdCompressor, prometheus.NewRegistry())
+	require.NoError(err)
+	compressor := compressorIntf.(*metricsCompressor)
+
+	msg := bytes.Repeat([]byte("avalanche"), 1024)
+	compressed, err := compressor.Compress(msg)
+	require.NoError(err)
+	_, err = compressor.Decompress(compressed)
+	require.NoError(err)
+	_, err = compressor.Decompress(compressed)
+	require.NoError(err)
+	_, err = compressor.Decompress([]byte{0xff, 0xff, 0xff, 0xff, 0xff})
+	require.ErrorIs(err, ErrInvalidFormat)
+
+	compressLabels := prometheus.Labels{opLabel: CompressOp}
+	require.InDelta(1, testutil.ToFloat64(compressor.calls.With(compressLabels)), 0)
+	require.InDelta(0, testutil.ToFloat64(compressor.errors.With(compressLabels)), 0)
+	require.InDelta(len(msg), testutil.ToFloat64(compressor.bytesIn.With(compressLabels)), 0)
+	require.InDelta(len(compressed), testutil.ToFloat64(compressor.bytesOut.With(compressLabels)), 0)
+
+	decompressLabels := prometheus.Labels{opLabel: DecompressOp}
+	require.InDelta(3, testutil.ToFloat64(compressor.calls.With(decompressLabels)), 0)
+	require.InDelta(1, testutil.ToFloat64(compressor.errors.With(decompressLabels)), 0)
+	require.InDelta(2*len(compressed), testutil.ToFloat64(compressor.bytesIn.With(decompressLabels)), 0)
+	require.InDelta(2*len(msg), testutil.ToFloat64(compressor.bytesOut.With(decompressLabels)), 0)
+}
+
+func TestMetricsCompressorDuplicateRegistration(t *testing.T) {
+	require := require.New(t)
+
+	registry := prometheus.NewRegistry()
+	_, err := NewMetricsCompressor(NewNoCompressor(), registry)
+	require.NoError(err)
+	_, err = NewMetricsCompressor(NewNoCompressor(), registry)
+	var alreadyRegisteredErr prometheus.AlreadyRegisteredError
+	require.ErrorAs(err, &alreadyRegisteredErr)
+}
@@ -0,0 +1,50 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import "errors"
+
+var _ Compressor = (*MigratingCompressor)(nil)
+
+// MigratingCompressor migrates stored messages from one format to another as
+// they are accessed. Messages are compressed in the new format, and messages
+// in either format can be decompressed.
+type MigratingCompressor struct {
+	from Compressor
+	to   Compressor
+}
+
+// NewMigratingCompressor returns a MigratingCompressor that migrates messages
+// from the format of from to the format of to.
+//
+// Messages are first decompressed with to, and only decompressed with from if
+// to reports [ErrInvalidFormat]. to must therefore reject messages in the
+// format of from, as formats with a magic number, such as zstd, do.
+func NewMigratingCompressor(from, to Compressor) *MigratingCompressor {
+	return &MigratingCompressor{
+		from: from,
+		to:   to,
+	}
+}
+
+func (m *MigratingCompressor) Compress(msg []byte) ([]byte, error) {
+	return m.to.Compress(msg)
+}
+
+func (m *MigratingCompressor) Decompress(msg []byte) ([]byte, error) {
+	decompressed, err := m.to.Decompress(msg)
+	if !errors.Is(err, ErrInvalidFormat) {
+		return decompressed, err
+	}
+	return m.from.Decompress(msg)
+}
+
+// Recompress converts msg from the old format to the new format.
+func (m *MigratingCompressor) Recompress(msg []byte) ([]byte, error) {
+	decompressed, err := m.from.Decompress(msg)
+	if err != nil {
+		return nil, err
+	}
+	return m.to.Compress(decompressed)
+}
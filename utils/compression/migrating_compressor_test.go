@@ -0,0 +1,108 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/ava-labs/avalanchego/utils/compression/compressionmock"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func newTestMigratingCompressor(t *testing.T) (*MigratingCompressor, Compressor, Compressor) {
+	from, err := NewDeflateCompressor(maxMessageSize)
+	require.NoError(t, err)
+	to, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(t, err)
+	return NewMigratingCompressor(from, to), from, to
+}
+
+func TestMigratingCompressorRecompress(t *testing.T) {
+	require := require.New(t)
+
+	m, from, to := newTestMigratingCompressor(t)
+
+	msg := bytes.Repeat([]byte("avalanche"), units.KiB)
+	old, err := from.Compress(msg)
+	require.NoError(err)
+
+	recompressed, err := m.Recompress(old)
+	require.NoError(err)
+	require.True(isZstdFrame(recompressed))
+
+	decompressed, err := to.Decompress(recompressed)
+	require.NoError(err)
+	require.Equal(msg, decompressed)
+}
+
+func TestMigratingCompressorDecompress(t *testing.T) {
+	require := require.New(t)
+
+	m, from, _ := newTestMigratingCompressor(t)
+
+	msg := bytes.Repeat([]byte("avalanche"), units.KiB)
+	old, err := from.Compress(msg)
+	require.NoError(err)
+	compressed, err := m.Compress(msg)
+	require.NoError(err)
+	require.True(isZstdFrame(compressed))
+
+	for _, stored := range [][]byte{old, compressed} {
+		decompressed, err := m.Decompress(stored)
+		require.NoError(err)
+		require.Equal(msg, decompressed)
+	}
+
+	_, err = m.Decompress([]byte{0xff, 0xff, 0xff, 0xff, 0xff})
+	require.ErrorIs(err, ErrInvalidFormat)
+
+	// Only messages in the old format can be recompressed.
+	_, err = m.Recompress(compressed)
+	require.ErrorIs(err, ErrInvalidFormat)
+}
+
+func TestMigratingCompressorOnlyFallsBackOnInvalidFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		toErr       error
+		fromCalls   int
+		expectedErr error
+	}{
+		{
+			name:      "invalid format",
+			toErr:     ErrInvalidFormat,
+			fromCalls: 1,
+		},
+		{
+			name:        "too large",
+			toErr:       ErrDecompressedMsgTooLarge,
+			fromCalls:   0,
+			expectedErr: ErrDecompressedMsgTooLarge,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+			ctrl := gomock.NewController(t)
+
+			var (
+				msg  = []byte("compressed")
+				from = compressionmock.NewCompressor(ctrl)
+				to   = compressionmock.NewCompressor(ctrl)
+			)
+			to.EXPECT().Decompress(msg).Return(nil, test.toErr)
+			from.EXPECT().Decompress(msg).Return([]byte("avalanche"), nil).Times(test.fromCalls)
+
+			decompressed, err := NewMigratingCompressor(from, to).Decompress(msg)
+			require.ErrorIs(err, test.expectedErr)
+			if test.expectedErr == nil {
+				require.Equal([]byte("avalanche"), decompressed)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	_ Compressor = (*nestedCompressor)(nil)
+
+	ErrInvalidMaxDepth  = errors.New("invalid max depth")
+	ErrDoubleCompressed = errors.New("double compressed")
+)
+
+// NewNestedCompressor returns a Compressor that detects zstd frames nested
+// inside the messages decompressed by compressor, which must be a zstd
+// Compressor.
+//
+// Up to maxDepth nested frames are transparently decompressed with
+// compressor. If the output still starts with a zstd frame after that,
+// Decompress fails with [ErrDoubleCompressed], so a maxDepth of 0 rejects all
+// nesting. Messages that legitimately start with the zstd magic number are
+// indistinguishable from nested frames.
+func NewNestedCompressor(compressor Compressor, maxDepth int) (Compressor, error) {
+	if maxDepth < 0 {
+		return nil, fmt.Errorf("%w: %d < 0", ErrInvalidMaxDepth, maxDepth)
+	}
+	return &nestedCompressor{
+		compressor: compressor,
+		maxDepth:   maxDepth,
+	}, nil
+}
+
+type nestedCompressor struct {
+	compressor Compressor
+	maxDepth   int
+}
+
+func (n *nestedCompressor) Compress(msg []byte) ([]byte, error) {
+	return n.compressor.Compress(msg)
+}
+
+func (n *nestedCompressor) Decompress(msg []byte) ([]byte, error) {
+	decompressed, err := n.compressor.Decompress(msg)
+	if err != nil {
+		return nil, err
+	}
+	for depth := 0; isZstdFrame(decompressed); depth++ {
+		if depth == n.maxDepth {
+			return nil, fmt.Errorf("%w: more than %d nested frames", ErrDoubleCompressed, n.maxDepth)
+		}
+		decompressed, err = n.compressor.Decompress(decompressed)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return decompressed, nil
+}
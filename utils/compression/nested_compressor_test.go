@@ -0,0 +1,80 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNestedCompressor(t *testing.T) {
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(t, err)
+
+	msg := bytes.Repeat([]byte("avalanche"), 1024)
+	compress := func(t *testing.T, layers int) []byte {
+		compressed := msg
+		for range layers {
+			compressed, err = zstdCompressor.Compress(compressed)
+			require.NoError(t, err)
+		}
+		return compressed
+	}
+
+	tests := []struct {
+		name        string
+		layers      int
+		maxDepth    int
+		expectedErr error
+	}{
+		{
+			name:     "single",
+			layers:   1,
+			maxDepth: 0,
+		},
+		{
+			name:        "double rejected",
+			layers:      2,
+			maxDepth:    0,
+			expectedErr: ErrDoubleCompressed,
+		},
+		{
+			name:     "double unwrapped",
+			layers:   2,
+			maxDepth: 1,
+		},
+		{
+			name:        "exceeds max depth",
+			layers:      4,
+			maxDepth:    2,
+			expectedErr: ErrDoubleCompressed,
+		},
+		{
+			name:     "at max depth",
+			layers:   3,
+			maxDepth: 2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := NewNestedCompressor(zstdCompressor, test.maxDepth)
+			require.NoError(err)
+
+			decompressed, err := compressor.Decompress(compress(t, test.layers))
+			require.ErrorIs(err, test.expectedErr)
+			if test.expectedErr == nil {
+				require.Equal(msg, decompressed)
+			}
+		})
+	}
+}
+
+func TestNewNestedCompressorInvalidMaxDepth(t *testing.T) {
+	_, err := NewNestedCompressor(NewNoCompressor(), -1)
+	require.ErrorIs(t, err, ErrInvalidMaxDepth)
+}
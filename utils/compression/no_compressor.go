@@ -3,7 +3,14 @@
 
 package compression
 
-var _ Compressor = (*noCompressor)(nil)
+import "io"
+
+var (
+	_ Compressor       = (*noCompressor)(nil)
+	_ StreamCompressor = (*noCompressor)(nil)
+	_ AppendCompressor = (*noCompressor)(nil)
+	_ SizeEstimator    = (*noCompressor)(nil)
+)
 
 type noCompressor struct{}
 
@@ -15,6 +22,28 @@ func (*noCompressor) Decompress(msg []byte) ([]byte, error) {
 	return msg, nil
 }
 
+func (*noCompressor) CompressStream(dst io.Writer, src io.Reader) error {
+	_, err := io.Copy(dst, src)
+	return err
+}
+
+func (*noCompressor) DecompressStream(dst io.Writer, src io.Reader) error {
+	_, err := io.Copy(dst, src)
+	return err
+}
+
+func (*noCompressor) AppendCompress(dst, msg []byte) ([]byte, error) {
+	return append(dst, msg...), nil
+}
+
+func (*noCompressor) AppendDecompress(dst, msg []byte) ([]byte, error) {
+	return append(dst, msg...), nil
+}
+
 func NewNoCompressor() Compressor {
 	return &noCompressor{}
 }
+
+func (*noCompressor) EstimateCompressedSize(msg []byte) int {
+	return len(msg)
+}
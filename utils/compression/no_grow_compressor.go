@@ -0,0 +1,47 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	_ Compressor = (*noGrowCompressor)(nil)
+
+	ErrCompressionInflated = errors.New("compression inflated msg")
+)
+
+// NewNoGrowCompressor returns a Compressor that fails with
+// [ErrCompressionInflated] rather than returning compressed output that is
+// larger than the input, so that callers can decide to send the message raw.
+//
+// Unlike [NewAutoCompressor], the output isn't flagged, so it remains
+// compatible with compressor. Every compressed format has some overhead, so
+// compressing an empty msg always fails.
+func NewNoGrowCompressor(compressor Compressor) Compressor {
+	return &noGrowCompressor{
+		compressor: compressor,
+	}
+}
+
+type noGrowCompressor struct {
+	compressor Compressor
+}
+
+func (n *noGrowCompressor) Compress(msg []byte) ([]byte, error) {
+	compressed, err := n.compressor.Compress(msg)
+	if err != nil {
+		return nil, err
+	}
+	if len(compressed) > len(msg) {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrCompressionInflated, len(compressed), len(msg))
+	}
+	return compressed, nil
+}
+
+func (n *noGrowCompressor) Decompress(msg []byte) ([]byte, error) {
+	return n.compressor.Decompress(msg)
+}
@@ -0,0 +1,59 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestNoGrowCompressor(t *testing.T) {
+	tests := []struct {
+		name        string
+		msg         []byte
+		expectedErr error
+	}{
+		{
+			name: "compressible",
+			msg:  bytes.Repeat([]byte("avalanche"), units.KiB),
+		},
+		{
+			name:        "incompressible",
+			msg:         utils.RandomBytes(units.KiB),
+			expectedErr: ErrCompressionInflated,
+		},
+		{
+			name:        "empty",
+			msg:         []byte{},
+			expectedErr: ErrCompressionInflated,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+			require.NoError(err)
+			compressor := NewNoGrowCompressor(zstdCompressor)
+
+			compressed, err := compressor.Compress(test.msg)
+			require.ErrorIs(err, test.expectedErr)
+			if test.expectedErr != nil {
+				return
+			}
+			require.LessOrEqual(len(compressed), len(test.msg))
+
+			// The output isn't framed, so the wrapped compressor can
+			// decompress it.
+			decompressed, err := zstdCompressor.Decompress(compressed)
+			require.NoError(err)
+			require.Equal(test.msg, decompressed)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import "time"
+
+// Operations reported to an [Observer].
+const (
+	CompressOp   = "compress"
+	DecompressOp = "decompress"
+)
+
+var _ Compressor = (*observedCompressor)(nil)
+
+// Observer is notified after each successful operation with the size of the
+// input, the size of the output, and how long the operation took.
+type Observer func(op string, in, out int, duration time.Duration)
+
+// NewObservedCompressor returns a Compressor that reports every successful
+// call to observer. If observer is nil, compressor is returned unchanged so
+// that unobserved compressors have no overhead.
+func NewObservedCompressor(compressor Compressor, observer Observer) Compressor {
+	if observer == nil {
+		return compressor
+	}
+	return &observedCompressor{
+		compressor: compressor,
+		observer:   observer,
+	}
+}
+
+type observedCompressor struct {
+	compressor Compressor
+	observer   Observer
+}
+
+func (o *observedCompressor) Compress(msg []byte) ([]byte, error) {
+	start := time.Now()
+	compressed, err := o.compressor.Compress(msg)
+	if err != nil {
+		return nil, err
+	}
+	o.observer(CompressOp, len(msg), len(compressed), time.Since(start))
+	return compressed, nil
+}
+
+func (o *observedCompressor) Decompress(msg []byte) ([]byte, error) {
+	start := time.Now()
+	decompressed, err := o.compressor.Decompress(msg)
+	if err != nil {
+		return nil, err
+	}
+	o.observer(DecompressOp, len(msg), len(decompressed), time.Since(start))
+	return decompressed, nil
+}
@@ -0,0 +1,61 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type observation struct {
+	op      string
+	in, out int
+}
+
+func TestObservedCompressor(t *testing.T) {
+	require := require.New(t)
+
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+
+	var observations []observation
+	compressor := NewObservedCompressor(zstdCompressor, func(op string, in, out int, duration time.Duration) {
+		require.GreaterOrEqual(duration, time.Duration(0))
+		observations = append(observations, observation{
+			op:  op,
+			in:  in,
+			out: out,
+		})
+	})
+
+	msg := bytes.Repeat([]byte("avalanche"), 1024)
+	compressed, err := compressor.Compress(msg)
+	require.NoError(err)
+	decompressed, err := compressor.Decompress(compressed)
+	require.NoError(err)
+	require.Equal(msg, decompressed)
+
+	// Failed calls aren't observed.
+	_, err = compressor.Compress(make([]byte, maxMessageSize+1))
+	require.ErrorIs(err, ErrMsgTooLarge)
+
+	require.Equal(
+		[]observation{
+			{op: CompressOp, in: len(msg), out: len(compressed)},
+			{op: DecompressOp, in: len(compressed), out: len(msg)},
+		},
+		observations,
+	)
+}
+
+func TestObservedCompressorNilObserver(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	require.Same(compressor, NewObservedCompressor(compressor, nil))
+}
@@ -0,0 +1,104 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+// defaultMaxSize is the max size used by [NewCompressorWithOptions] if
+// [WithMaxDecompressedSize] isn't passed.
+const defaultMaxSize = 2 * units.MiB
+
+var ErrInvalidThreshold = errors.New("invalid threshold")
+
+// Option configures the Compressor returned by [NewCompressorWithOptions].
+type Option func(*options)
+
+type options struct {
+	level     int
+	threshold int
+	maxSize   int64
+
+	ratioLimited bool
+	maxRatio     float64
+}
+
+// WithLevel sets the zstd compression level. The default is the zstd default
+// level.
+func WithLevel(level int) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+// WithThreshold sends messages shorter than threshold uncompressed, as with
+// [NewThresholdCompressor]. By default, every message is compressed.
+func WithThreshold(threshold int) Option {
+	return func(o *options) {
+		o.threshold = threshold
+	}
+}
+
+// WithMaxDecompressedSize sets the size of the largest message that can be
+// compressed or decompressed, including messages sent uncompressed because of
+// [WithThreshold]. The default is 2 MiB.
+func WithMaxDecompressedSize(maxSize int64) Option {
+	return func(o *options) {
+		o.maxSize = maxSize
+	}
+}
+
+// WithMaxExpansionRatio bounds decompressed messages to maxRatio times the size
+// of the compressed message, as with [NewRatioLimitedCompressor]. By default,
+// only the max size is enforced.
+func WithMaxExpansionRatio(maxRatio float64) Option {
+	return func(o *options) {
+		o.ratioLimited = true
+		o.maxRatio = maxRatio
+	}
+}
+
+// NewCompressorWithOptions returns a zstd Compressor configured by opts. Later
+// options override earlier ones.
+//
+// An error is returned if an option is invalid or if the threshold is larger
+// than the max size, in which case no message would ever be compressed.
+func NewCompressorWithOptions(opts ...Option) (Compressor, error) {
+	o := options{
+		level:   zstdDefaultCompression,
+		maxSize: defaultMaxSize,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.maxSize <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidMaxSizeCompressor, o.maxSize)
+	}
+	if o.threshold < 0 || int64(o.threshold) > o.maxSize {
+		return nil, fmt.Errorf("%w: %d not in [0, %d]", ErrInvalidThreshold, o.threshold, o.maxSize)
+	}
+
+	factory := func(maxSize int64) (Compressor, error) {
+		return NewZstdCompressorWithLevel(maxSize, o.level)
+	}
+	compressor, err := factory(o.maxSize)
+	if err != nil {
+		return nil, err
+	}
+	if o.ratioLimited {
+		compressor, err = NewRatioLimitedCompressor(factory, o.maxSize, o.maxRatio)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if o.threshold == 0 {
+		return compressor, nil
+	}
+	return NewThresholdCompressor(o.threshold, compressor), nil
+}
@@ -0,0 +1,161 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestNewCompressorWithOptions(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           []Option
+		expectedConfig CompressorConfig
+		expectedErr    error
+	}{
+		{
+			name: "defaults",
+			expectedConfig: CompressorConfig{
+				MaxSize: defaultMaxSize,
+				Level:   zstdDefaultCompression,
+			},
+		},
+		{
+			name: "all options",
+			opts: []Option{
+				WithLevel(zstdBestSpeed),
+				WithThreshold(64),
+				WithMaxDecompressedSize(units.MiB),
+			},
+			expectedConfig: CompressorConfig{
+				MaxSize:   units.MiB,
+				Level:     zstdBestSpeed,
+				Threshold: 64,
+			},
+		},
+		{
+			name: "later options override earlier ones",
+			opts: []Option{
+				WithLevel(zstdBestSpeed),
+				WithLevel(zstdBestCompression),
+			},
+			expectedConfig: CompressorConfig{
+				MaxSize: defaultMaxSize,
+				Level:   zstdBestCompression,
+			},
+		},
+		{
+			name: "max expansion ratio",
+			opts: []Option{
+				WithThreshold(64),
+				WithMaxExpansionRatio(10),
+			},
+			expectedConfig: CompressorConfig{
+				MaxSize:   defaultMaxSize,
+				Level:     zstdDefaultCompression,
+				Threshold: 64,
+			},
+		},
+		{
+			name:        "invalid max expansion ratio",
+			opts:        []Option{WithMaxExpansionRatio(0)},
+			expectedErr: ErrInvalidMaxRatio,
+		},
+		{
+			name:        "invalid level",
+			opts:        []Option{WithLevel(zstdBestCompression + 1)},
+			expectedErr: ErrInvalidCompressionLevel,
+		},
+		{
+			name:        "zero max size",
+			opts:        []Option{WithMaxDecompressedSize(0)},
+			expectedErr: ErrInvalidMaxSizeCompressor,
+		},
+		{
+			name:        "negative threshold",
+			opts:        []Option{WithThreshold(-1)},
+			expectedErr: ErrInvalidThreshold,
+		},
+		{
+			name: "threshold larger than max size",
+			opts: []Option{
+				WithThreshold(units.KiB + 1),
+				WithMaxDecompressedSize(units.KiB),
+			},
+			expectedErr: ErrInvalidThreshold,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := NewCompressorWithOptions(test.opts...)
+			require.ErrorIs(err, test.expectedErr)
+			if test.expectedErr != nil {
+				return
+			}
+			require.Equal(test.expectedConfig, compressor.(ConfigReporter).Config())
+
+			msg := newTestDictionaryMessage(0)
+			compressed, err := compressor.Compress(msg)
+			require.NoError(err)
+			decompressed, err := compressor.Decompress(compressed)
+			require.NoError(err)
+			require.Equal(msg, decompressed)
+		})
+	}
+}
+
+func TestNewCompressorWithOptionsThreshold(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewCompressorWithOptions(WithThreshold(64))
+	require.NoError(err)
+
+	// Messages below the threshold are only tagged.
+	msg := []byte("avalanche")
+	compressed, err := compressor.Compress(msg)
+	require.NoError(err)
+	require.Len(compressed, len(msg)+1)
+}
+
+func TestNewCompressorWithOptionsMaxSize(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewCompressorWithOptions(WithMaxDecompressedSize(units.KiB))
+	require.NoError(err)
+
+	_, err = compressor.Compress(make([]byte, units.KiB+1))
+	require.ErrorIs(err, ErrMsgTooLarge)
+}
+
+func TestNewCompressorWithOptionsThresholdMaxSize(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewCompressorWithOptions(
+		WithThreshold(64),
+		WithMaxDecompressedSize(units.KiB),
+	)
+	require.NoError(err)
+
+	// Uncompressed payloads are bounded by the max size as well.
+	_, err = compressor.Decompress(withFlag(smallTag, make([]byte, units.MiB)))
+	require.ErrorIs(err, ErrDecompressedMsgTooLarge)
+}
+
+func TestNewCompressorWithOptionsMaxExpansionRatio(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewCompressorWithOptions(WithMaxExpansionRatio(10))
+	require.NoError(err)
+
+	compressed, err := compressor.Compress(make([]byte, units.MiB))
+	require.NoError(err)
+	_, err = compressor.Decompress(compressed)
+	require.ErrorIs(err, ErrRatioExceeded)
+}
@@ -0,0 +1,83 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"math"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+const (
+	// Messages shorter than minEntropySampleSize are always compressed, as
+	// short samples can't distinguish ciphertext from plaintext.
+	minEntropySampleSize = units.KiB
+	entropySampleSize    = 4 * units.KiB
+
+	// ciphertextEntropy is the number of bits of entropy per byte above which
+	// a message is assumed to be ciphertext. Uniform bytes approach 8 bits.
+	ciphertextEntropy = 7.5
+)
+
+var _ Compressor = (*orderAwareCompressor)(nil)
+
+// NewOrderAwareCompressor returns a Compressor that doesn't compress messages
+// that look like ciphertext, which usually indicates that a message was
+// encrypted before being compressed rather than after.
+//
+// Whether a message looks like ciphertext is estimated from the entropy of its
+// first bytes. Skipped messages are sent uncompressed, prefixed with the same
+// flag byte as [NewAutoCompressor], and reported to onSkip, if it is non-nil,
+// so that misordering can be detected upstream.
+func NewOrderAwareCompressor(compressor Compressor, onSkip func(entropy float64)) Compressor {
+	return &orderAwareCompressor{
+		compressor: compressor,
+		onSkip:     onSkip,
+	}
+}
+
+type orderAwareCompressor struct {
+	compressor Compressor
+	onSkip     func(entropy float64)
+}
+
+func (o *orderAwareCompressor) Compress(msg []byte) ([]byte, error) {
+	if len(msg) >= minEntropySampleSize {
+		if e := entropy(msg[:min(len(msg), entropySampleSize)]); e > ciphertextEntropy {
+			if o.onSkip != nil {
+				o.onSkip(e)
+			}
+			return withFlag(rawFlag, msg), nil
+		}
+	}
+
+	compressed, err := o.compressor.Compress(msg)
+	if err != nil {
+		return nil, err
+	}
+	return withFlag(compressedFlag, compressed), nil
+}
+
+func (o *orderAwareCompressor) Decompress(msg []byte) ([]byte, error) {
+	return decompressFlagged(o.compressor, msg)
+}
+
+// entropy returns the Shannon entropy of the bytes in sample, in bits per
+// byte.
+func entropy(sample []byte) float64 {
+	var counts [256]int
+	for _, b := range sample {
+		counts[b]++
+	}
+
+	var e float64
+	for _, count := range counts {
+		if count == 0 {
+			continue
+		}
+		p := float64(count) / float64(len(sample))
+		e -= p * math.Log2(p)
+	}
+	return e
+}
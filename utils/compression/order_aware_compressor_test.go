@@ -0,0 +1,76 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestOrderAwareCompressor(t *testing.T) {
+	rng := rand.New(rand.NewSource(0)) //#nosec G404
+	tests := []struct {
+		name            string
+		msg             []byte
+		expectedFlag    byte
+		expectedSkipped bool
+	}{
+		{
+			name:            "ciphertext",
+			msg:             utils.RandomBytes(4 * units.KiB),
+			expectedFlag:    rawFlag,
+			expectedSkipped: true,
+		},
+		{
+			name:         "plaintext",
+			msg:          newTestText(rng, 4*units.KiB),
+			expectedFlag: compressedFlag,
+		},
+		{
+			name:         "short ciphertext",
+			msg:          utils.RandomBytes(minEntropySampleSize - 1),
+			expectedFlag: compressedFlag,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+			require.NoError(err)
+
+			var skipped bool
+			compressor := NewOrderAwareCompressor(zstdCompressor, func(entropy float64) {
+				require.Greater(entropy, ciphertextEntropy)
+				skipped = true
+			})
+
+			compressed, err := compressor.Compress(test.msg)
+			require.NoError(err)
+			require.Equal(test.expectedFlag, compressed[0])
+			require.Equal(test.expectedSkipped, skipped)
+
+			decompressed, err := compressor.Decompress(compressed)
+			require.NoError(err)
+			require.Equal(test.msg, decompressed)
+		})
+	}
+}
+
+func TestEntropy(t *testing.T) {
+	require := require.New(t)
+
+	require.Zero(entropy(make([]byte, units.KiB)))
+
+	uniform := make([]byte, 256)
+	for i := range uniform {
+		uniform[i] = byte(i)
+	}
+	require.InDelta(8, entropy(uniform), 1e-9)
+}
@@ -0,0 +1,89 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import "errors"
+
+// Outcomes of decompressing a msg, as reported by [NewOutcomeCompressor].
+const (
+	OutcomeSuccess Outcome = iota
+	OutcomeFormatError
+	OutcomeChecksumError
+	OutcomeSizeLimit
+	OutcomeTruncated
+	OutcomeOtherError
+)
+
+var _ Compressor = (*outcomeCompressor)(nil)
+
+// Outcome categorizes the result of decompressing a msg, which allows failures
+// to be counted without inspecting errors.
+type Outcome byte
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeSuccess:
+		return "success"
+	case OutcomeFormatError:
+		return "format_error"
+	case OutcomeChecksumError:
+		return "checksum_error"
+	case OutcomeSizeLimit:
+		return "size_limit"
+	case OutcomeTruncated:
+		return "truncated"
+	default:
+		return "other_error"
+	}
+}
+
+// ClassifyDecompressError returns the Outcome of a decompression that
+// returned err.
+//
+// Truncated msgs are also reported as [ErrInvalidFormat], so truncation takes
+// precedence over format errors.
+func ClassifyDecompressError(err error) Outcome {
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.Is(err, ErrChecksumMismatch):
+		return OutcomeChecksumError
+	case errors.Is(err, ErrDecompressedMsgTooLarge):
+		return OutcomeSizeLimit
+	case errors.Is(err, ErrTruncatedStream):
+		return OutcomeTruncated
+	case errors.Is(err, ErrInvalidFormat):
+		return OutcomeFormatError
+	default:
+		return OutcomeOtherError
+	}
+}
+
+// NewOutcomeCompressor returns a Compressor that reports the Outcome of every
+// call to Decompress to report, so that callers can attribute bad msgs to the
+// peer that sent them. If report is nil, compressor is returned unchanged.
+func NewOutcomeCompressor(compressor Compressor, report func(Outcome)) Compressor {
+	if report == nil {
+		return compressor
+	}
+	return &outcomeCompressor{
+		compressor: compressor,
+		report:     report,
+	}
+}
+
+type outcomeCompressor struct {
+	compressor Compressor
+	report     func(Outcome)
+}
+
+func (o *outcomeCompressor) Compress(msg []byte) ([]byte, error) {
+	return o.compressor.Compress(msg)
+}
+
+func (o *outcomeCompressor) Decompress(msg []byte) ([]byte, error) {
+	decompressed, err := o.compressor.Decompress(msg)
+	o.report(ClassifyDecompressError(err))
+	return decompressed, err
+}
@@ -0,0 +1,89 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestOutcomeCompressor(t *testing.T) {
+	zstdCompressor, err := NewZstdCompressor(units.KiB)
+	require.NoError(t, err)
+
+	msg := newTestDictionaryMessage(0)
+	compressed, err := zstdCompressor.Compress(msg)
+	require.NoError(t, err)
+
+	largeCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(t, err)
+	tooLarge, err := largeCompressor.Compress(make([]byte, units.KiB+1))
+	require.NoError(t, err)
+
+	checksummed, err := NewChecksumCompressor(zstdCompressor).Compress(msg)
+	require.NoError(t, err)
+	checksummed[0]++
+
+	random, err := largeCompressor.Compress(utils.RandomBytes(units.KiB))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		compressor      Compressor
+		msg             []byte
+		expectedOutcome Outcome
+	}{
+		{
+			name:            "success",
+			compressor:      zstdCompressor,
+			msg:             compressed,
+			expectedOutcome: OutcomeSuccess,
+		},
+		{
+			name:            "format error",
+			compressor:      zstdCompressor,
+			msg:             []byte{0xff, 0xff, 0xff, 0xff, 0xff},
+			expectedOutcome: OutcomeFormatError,
+		},
+		{
+			name:            "checksum error",
+			compressor:      NewChecksumCompressor(zstdCompressor),
+			msg:             checksummed,
+			expectedOutcome: OutcomeChecksumError,
+		},
+		{
+			name:            "size limit",
+			compressor:      zstdCompressor,
+			msg:             tooLarge,
+			expectedOutcome: OutcomeSizeLimit,
+		},
+		{
+			name:            "truncated",
+			compressor:      largeCompressor,
+			msg:             random[:len(random)-1],
+			expectedOutcome: OutcomeTruncated,
+		},
+		{
+			name:            "other error",
+			compressor:      errCompressor{err: errTest},
+			msg:             compressed,
+			expectedOutcome: OutcomeOtherError,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var outcomes []Outcome
+			compressor := NewOutcomeCompressor(test.compressor, func(outcome Outcome) {
+				outcomes = append(outcomes, outcome)
+			})
+
+			_, _ = compressor.Decompress(test.msg)
+			require.Equal(t, []Outcome{test.expectedOutcome}, outcomes)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+)
+
+var (
+	_ Compressor = (*paddedCompressor)(nil)
+
+	ErrInvalidBucketSize = errors.New("invalid bucket size")
+)
+
+// NewPaddedCompressor returns a Compressor that pads the output of compressor
+// to the next multiple of bucketSize, so that the size of a payload only
+// reveals which bucket the compressed msg falls into. This trades bandwidth
+// for resistance to fingerprinting msgs by their size.
+//
+// Payloads are prefixed with the length of the compressed msg, encoded as a
+// big-endian uint32, which is followed by the compressed msg and zero bytes.
+// Payloads that aren't a multiple of bucketSize or whose padding isn't zero
+// are rejected with [ErrInvalidFormat].
+func NewPaddedCompressor(compressor Compressor, bucketSize int) (Compressor, error) {
+	if bucketSize <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidBucketSize, bucketSize)
+	}
+	return &paddedCompressor{
+		compressor: compressor,
+		bucketSize: bucketSize,
+	}, nil
+}
+
+type paddedCompressor struct {
+	compressor Compressor
+	bucketSize int
+}
+
+func (p *paddedCompressor) Compress(msg []byte) ([]byte, error) {
+	compressed, err := p.compressor.Compress(msg)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(compressed)) > math.MaxUint32 {
+		return nil, fmt.Errorf("%w: compressed (%d) > (%d)", ErrMsgTooLarge, len(compressed), uint32(math.MaxUint32))
+	}
+
+	unpaddedLen := wrappers.IntLen + len(compressed)
+	// Round up to the next multiple of the bucket size. The zero value of the
+	// padding is implicit in the newly allocated slice.
+	padded := make([]byte, (unpaddedLen+p.bucketSize-1)/p.bucketSize*p.bucketSize)
+	binary.BigEndian.PutUint32(padded, uint32(len(compressed)))
+	copy(padded[wrappers.IntLen:], compressed)
+	return padded, nil
+}
+
+func (p *paddedCompressor) Decompress(msg []byte) ([]byte, error) {
+	if len(msg) < wrappers.IntLen {
+		return nil, fmt.Errorf("%w: missing length prefix", ErrInvalidFormat)
+	}
+	if len(msg)%p.bucketSize != 0 {
+		return nil, fmt.Errorf("%w: length (%d) isn't a multiple of the bucket size (%d)", ErrInvalidFormat, len(msg), p.bucketSize)
+	}
+	compressedLen := binary.BigEndian.Uint32(msg)
+	payload := msg[wrappers.IntLen:]
+	if uint64(compressedLen) > uint64(len(payload)) {
+		return nil, fmt.Errorf("%w: declared length (%d) > (%d)", ErrInvalidFormat, compressedLen, len(payload))
+	}
+	for _, b := range payload[compressedLen:] {
+		if b != 0 {
+			return nil, fmt.Errorf("%w: non-zero padding", ErrInvalidFormat)
+		}
+	}
+	return p.compressor.Decompress(payload[:compressedLen])
+}
@@ -0,0 +1,90 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestPaddedCompressor(t *testing.T) {
+	require := require.New(t)
+
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+
+	for _, bucketSize := range []int{1, 3, 256, units.KiB} {
+		compressor, err := NewPaddedCompressor(zstdCompressor, bucketSize)
+		require.NoError(err)
+
+		for _, size := range []int{0, 1, 100, 1000, units.KiB, 10 * units.KiB} {
+			msg := utils.RandomBytes(size)
+			padded, err := compressor.Compress(msg)
+			require.NoError(err)
+			require.Zero(len(padded) % bucketSize)
+
+			decompressed, err := compressor.Decompress(padded)
+			require.NoError(err)
+			require.Equal(msg, decompressed)
+		}
+	}
+}
+
+func TestPaddedCompressorHidesSize(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewPaddedCompressor(NewNoCompressor(), 256)
+	require.NoError(err)
+
+	short, err := compressor.Compress([]byte("yes"))
+	require.NoError(err)
+	long, err := compressor.Compress([]byte("no, definitely not"))
+	require.NoError(err)
+	require.Len(short, 256)
+	require.Len(long, 256)
+}
+
+func TestPaddedCompressorInvalidFormat(t *testing.T) {
+	compressor, err := NewPaddedCompressor(NewNoCompressor(), 8)
+	require.NoError(t, err)
+	valid, err := compressor.Compress([]byte("ava"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		msg  []byte
+	}{
+		{
+			name: "empty",
+			msg:  nil,
+		},
+		{
+			name: "not a multiple of the bucket size",
+			msg:  valid[:len(valid)-1],
+		},
+		{
+			name: "declared length too long",
+			msg:  []byte{0, 0, 0, 5, 'a', 'v', 'a', 0},
+		},
+		{
+			name: "non-zero padding",
+			msg:  []byte{0, 0, 0, 3, 'a', 'v', 'a', 1},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := compressor.Decompress(test.msg)
+			require.ErrorIs(t, err, ErrInvalidFormat)
+		})
+	}
+}
+
+func TestNewPaddedCompressorInvalidBucketSize(t *testing.T) {
+	_, err := NewPaddedCompressor(NewNoCompressor(), 0)
+	require.ErrorIs(t, err, ErrInvalidBucketSize)
+}
@@ -0,0 +1,158 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+)
+
+var (
+	_ Compressor = (*parallelCompressor)(nil)
+
+	ErrInvalidBlockSize   = errors.New("invalid block size")
+	ErrInvalidWorkerCount = errors.New("invalid worker count")
+)
+
+// NewParallelCompressor returns a Compressor that splits messages into blocks
+// of blockSize bytes and compresses up to workers blocks concurrently, using a
+// compressor created by factory with a max size of blockSize.
+//
+// Compressed messages are framed as the number of blocks followed by each
+// length-prefixed compressed block.
+func NewParallelCompressor(factory Factory, maxSize int64, blockSize int, workers int) (Compressor, error) {
+	if blockSize <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidBlockSize, blockSize)
+	}
+	if workers <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidWorkerCount, workers)
+	}
+	maxBlocks := (maxSize + int64(blockSize) - 1) / int64(blockSize)
+	if maxSize < 0 || maxBlocks > math.MaxUint32 {
+		return nil, ErrInvalidMaxSizeCompressor
+	}
+
+	compressor, err := factory(int64(blockSize))
+	if err != nil {
+		return nil, err
+	}
+	return &parallelCompressor{
+		compressor: compressor,
+		maxSize:    maxSize,
+		maxBlocks:  uint32(maxBlocks),
+		blockSize:  blockSize,
+		workers:    workers,
+	}, nil
+}
+
+type parallelCompressor struct {
+	compressor Compressor
+	maxSize    int64
+	maxBlocks  uint32
+	blockSize  int
+	workers    int
+}
+
+func (p *parallelCompressor) Compress(msg []byte) ([]byte, error) {
+	if int64(len(msg)) > p.maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrMsgTooLarge, len(msg), p.maxSize)
+	}
+
+	var (
+		numBlocks = (len(msg) + p.blockSize - 1) / p.blockSize
+		blocks    = make([][]byte, numBlocks)
+		eg        errgroup.Group
+	)
+	eg.SetLimit(p.workers)
+	for i := range blocks {
+		eg.Go(func() error {
+			start := i * p.blockSize
+			end := min(start+p.blockSize, len(msg))
+			compressed, err := p.compressor.Compress(msg[start:end])
+			blocks[i] = compressed
+			return err
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	size := wrappers.IntLen
+	for _, block := range blocks {
+		size += wrappers.IntLen + len(block)
+	}
+	packer := wrappers.Packer{
+		MaxSize: size,
+		Bytes:   make([]byte, 0, size),
+	}
+	packer.PackInt(uint32(numBlocks))
+	for _, block := range blocks {
+		packer.PackBytes(block)
+	}
+	return packer.Bytes, packer.Err
+}
+
+func (p *parallelCompressor) Decompress(msg []byte) ([]byte, error) {
+	packer := wrappers.Packer{
+		Bytes: msg,
+	}
+	numBlocks := packer.UnpackInt()
+	if packer.Errored() {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, packer.Err)
+	}
+	if numBlocks > p.maxBlocks {
+		return nil, fmt.Errorf("%w: (%d blocks) > (%d blocks)", ErrDecompressedMsgTooLarge, numBlocks, p.maxBlocks)
+	}
+
+	blocks := make([][]byte, numBlocks)
+	for i := range blocks {
+		blocks[i] = packer.UnpackBytes()
+	}
+	if packer.Errored() {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, packer.Err)
+	}
+	if packer.Offset != len(msg) {
+		return nil, fmt.Errorf("%w: %w: %d bytes", ErrInvalidFormat, ErrTrailingData, len(msg)-packer.Offset)
+	}
+
+	var eg errgroup.Group
+	eg.SetLimit(p.workers)
+	for i := range blocks {
+		eg.Go(func() error {
+			decompressed, err := p.compressor.Decompress(blocks[i])
+			if err != nil {
+				return err
+			}
+			// Every block other than the last must be full, and no block may
+			// be empty.
+			isLast := i == len(blocks)-1
+			if len(decompressed) == 0 || (!isLast && len(decompressed) != p.blockSize) {
+				return fmt.Errorf("%w: block %d has length %d", ErrInvalidFormat, i, len(decompressed))
+			}
+			blocks[i] = decompressed
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	var size int
+	for _, block := range blocks {
+		size += len(block)
+	}
+	if int64(size) > p.maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrDecompressedMsgTooLarge, size, p.maxSize)
+	}
+	decompressed := make([]byte, 0, size)
+	for _, block := range blocks {
+		decompressed = append(decompressed, block...)
+	}
+	return decompressed, nil
+}
@@ -0,0 +1,166 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+)
+
+const testBlockSize = 64 * units.KiB
+
+func TestParallelCompressor(t *testing.T) {
+	sizes := []int{
+		0,
+		1,
+		testBlockSize - 1,
+		testBlockSize,
+		testBlockSize + 1,
+		10*testBlockSize + 7,
+		maxMessageSize,
+	}
+	for _, size := range sizes {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := NewParallelCompressor(NewZstdCompressor, maxMessageSize, testBlockSize, 4)
+			require.NoError(err)
+
+			msg := bytes.Repeat([]byte("avalanche"), size/9+1)[:size]
+			compressed, err := compressor.Compress(msg)
+			require.NoError(err)
+
+			decompressed, err := compressor.Decompress(compressed)
+			require.NoError(err)
+			require.Equal(msg, decompressed)
+		})
+	}
+}
+
+func TestParallelCompressorErrors(t *testing.T) {
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(t, err)
+	compressor, err := NewParallelCompressor(NewZstdCompressor, maxMessageSize, testBlockSize, 4)
+	require.NoError(t, err)
+
+	valid, err := compressor.Compress(make([]byte, 3*testBlockSize))
+	require.NoError(t, err)
+
+	pack := func(numBlocks uint32, blocks ...[]byte) []byte {
+		packer := wrappers.Packer{MaxSize: maxMessageSize}
+		packer.PackInt(numBlocks)
+		for _, block := range blocks {
+			packer.PackBytes(block)
+		}
+		require.NoError(t, packer.Err)
+		return packer.Bytes
+	}
+	compress := func(size int) []byte {
+		compressed, err := zstdCompressor.Compress(make([]byte, size))
+		require.NoError(t, err)
+		return compressed
+	}
+
+	tests := []struct {
+		name        string
+		msg         []byte
+		expectedErr error
+	}{
+		{
+			name:        "empty",
+			msg:         nil,
+			expectedErr: ErrInvalidFormat,
+		},
+		{
+			name:        "missing blocks",
+			msg:         valid[:len(valid)-1],
+			expectedErr: ErrInvalidFormat,
+		},
+		{
+			name:        "trailing data",
+			msg:         append(valid, 0),
+			expectedErr: ErrTrailingData,
+		},
+		{
+			name:        "too many blocks",
+			msg:         pack(maxMessageSize/testBlockSize + 1),
+			expectedErr: ErrDecompressedMsgTooLarge,
+		},
+		{
+			name:        "short block",
+			msg:         pack(2, compress(testBlockSize-1), compress(1)),
+			expectedErr: ErrInvalidFormat,
+		},
+		{
+			name:        "empty block",
+			msg:         pack(1, compress(0)),
+			expectedErr: ErrInvalidFormat,
+		},
+		{
+			name:        "oversized block",
+			msg:         pack(1, compress(testBlockSize+1)),
+			expectedErr: ErrDecompressedMsgTooLarge,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := compressor.Decompress(test.msg)
+			require.ErrorIs(t, err, test.expectedErr)
+		})
+	}
+}
+
+func TestNewParallelCompressorInvalidArguments(t *testing.T) {
+	tests := []struct {
+		name        string
+		blockSize   int
+		workers     int
+		expectedErr error
+	}{
+		{
+			name:        "zero block size",
+			blockSize:   0,
+			workers:     1,
+			expectedErr: ErrInvalidBlockSize,
+		},
+		{
+			name:        "zero workers",
+			blockSize:   testBlockSize,
+			workers:     0,
+			expectedErr: ErrInvalidWorkerCount,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewParallelCompressor(NewZstdCompressor, maxMessageSize, test.blockSize, test.workers)
+			require.ErrorIs(t, err, test.expectedErr)
+		})
+	}
+}
+
+func BenchmarkParallelCompressor(b *testing.B) {
+	msg := benchmarkPayloads["json"]
+	msg = bytes.Repeat(msg, maxMessageSize/len(msg))
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers_%d", workers), func(b *testing.B) {
+			require := require.New(b)
+
+			compressor, err := NewParallelCompressor(NewZstdCompressor, maxMessageSize, testBlockSize, workers)
+			require.NoError(err)
+
+			b.SetBytes(int64(len(msg)))
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				_, err := compressor.Compress(msg)
+				require.NoError(err)
+			}
+		})
+	}
+}
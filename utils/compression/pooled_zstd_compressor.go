@@ -0,0 +1,102 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build cgo
+// +build cgo
+
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/DataDog/zstd"
+)
+
+var (
+	_ Compressor     = (*pooledZstdCompressor)(nil)
+	_ ConfigReporter = (*pooledZstdCompressor)(nil)
+)
+
+// NewPooledZstdCompressor returns a zstd Compressor that reuses compression
+// contexts and decompression buffers across calls.
+//
+// Decompression contexts aren't reused: each call to Decompress creates a new
+// decoder. A pooled context can only decompress into a preallocated buffer,
+// and when the message doesn't fit it falls back to decompressing the whole
+// message without a size limit.
+//
+// The output is identical to the output of the Compressor returned by
+// [NewZstdCompressor].
+func NewPooledZstdCompressor(maxSize int64) (Compressor, error) {
+	z, err := newZstdCompressor(maxSize, zstd.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	return &pooledZstdCompressor{
+		zstdCompressor: z,
+		ctxs: sync.Pool{
+			New: func() any {
+				return zstd.NewCtx()
+			},
+		},
+		buffers: sync.Pool{
+			New: func() any {
+				return new(bytes.Buffer)
+			},
+		},
+	}, nil
+}
+
+type pooledZstdCompressor struct {
+	*zstdCompressor
+
+	ctxs    sync.Pool // of zstd.Ctx
+	buffers sync.Pool // of *bytes.Buffer
+}
+
+func (p *pooledZstdCompressor) Compress(msg []byte) ([]byte, error) {
+	if int64(len(msg)) > p.maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrMsgTooLarge, len(msg), p.maxSize)
+	}
+
+	ctx := p.ctxs.Get().(zstd.Ctx)
+	defer p.ctxs.Put(ctx)
+
+	return ctx.CompressLevel(nil, msg, p.level)
+}
+
+func (p *pooledZstdCompressor) Decompress(msg []byte) ([]byte, error) {
+	if err := checkZstdMagic(msg); err != nil {
+		return nil, err
+	}
+	if err := checkZstdFrame(newZstdFrameParser(), msg); err != nil {
+		return nil, err
+	}
+
+	buf := p.buffers.Get().(*bytes.Buffer)
+	defer func() {
+		buf.Reset()
+		p.buffers.Put(buf)
+	}()
+
+	// [zstd.Ctx] isn't used because it doesn't bound the decompressed size.
+	reader := zstd.NewReader(bytes.NewReader(msg))
+	defer reader.Close()
+
+	// See [zstdCompressor.Decompress] for why maxSize + 1 bytes are read.
+	limitReader := io.LimitReader(reader, p.maxSize+1)
+	if _, err := buf.ReadFrom(limitReader); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+	if int64(buf.Len()) > p.maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrDecompressedMsgTooLarge, buf.Len(), p.maxSize)
+	}
+
+	// The buffer is returned to the pool, so the result must be copied out.
+	decompressed := make([]byte, buf.Len())
+	copy(decompressed, buf.Bytes())
+	return decompressed, nil
+}
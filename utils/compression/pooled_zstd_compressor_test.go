@@ -0,0 +1,95 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build cgo
+// +build cgo
+
+package compression
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+// BenchmarkZstdBufferStrategies compares the allocation strategies of the
+// zstd Compressors by compressing and decompressing a msg per op:
+//
+//   - fresh allocates new output buffers for every call.
+//   - pool reuses contexts and buffers through a [sync.Pool].
+//   - reuse appends into buffers owned by each goroutine.
+//
+// The goroutine count is a multiple of GOMAXPROCS, see
+// [testing.B.SetParallelism].
+func BenchmarkZstdBufferStrategies(b *testing.B) {
+	zstdCompressor, err := newZstdCompressor(maxMessageSize, zstdDefaultCompression)
+	require.NoError(b, err)
+	pooledCompressor, err := NewPooledZstdCompressor(maxMessageSize)
+	require.NoError(b, err)
+
+	roundTrip := func(compressor Compressor) func(msg []byte) error {
+		return func(msg []byte) error {
+			compressed, err := compressor.Compress(msg)
+			if err != nil {
+				return err
+			}
+			_, err = compressor.Decompress(compressed)
+			return err
+		}
+	}
+	strategies := map[string]func() func(msg []byte) error{
+		"fresh": func() func(msg []byte) error {
+			return roundTrip(zstdCompressor)
+		},
+		"pool": func() func(msg []byte) error {
+			return roundTrip(pooledCompressor)
+		},
+		"reuse": func() func(msg []byte) error {
+			var compressed, decompressed []byte
+			return func(msg []byte) error {
+				var err error
+				compressed, err = zstdCompressor.AppendCompress(compressed[:0], msg)
+				if err != nil {
+					return err
+				}
+				decompressed, err = zstdCompressor.AppendDecompress(decompressed[:0], compressed)
+				return err
+			}
+		},
+	}
+
+	rng := rand.New(rand.NewSource(0)) // #nosec G404
+	sizes := []int{
+		256,
+		units.KiB,
+		64 * units.KiB,
+		units.MiB,
+	}
+	for name, newStrategy := range strategies {
+		for _, size := range sizes {
+			for _, parallelism := range []int{1, 4, 16} {
+				b.Run(fmt.Sprintf("%s_%d_x%d", name, size, parallelism), func(b *testing.B) {
+					msg := newTestText(rng, size)
+
+					b.SetBytes(int64(size))
+					b.SetParallelism(parallelism)
+					b.ReportAllocs()
+					b.RunParallel(func(pb *testing.PB) {
+						// Each goroutine gets its own buffers.
+						op := newStrategy()
+						for pb.Next() {
+							if err := op(msg); err != nil {
+								b.Error(err)
+								return
+							}
+						}
+					})
+				})
+			}
+		}
+	}
+}
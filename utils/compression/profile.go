@@ -0,0 +1,69 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+// Names of the profiles that can be passed to [NewCompressorForProfile].
+const (
+	GossipProfile  = "gossip"
+	BlockProfile   = "block"
+	ArchiveProfile = "archive"
+)
+
+var (
+	ErrUnknownProfile = errors.New("unknown compression profile")
+
+	// profiles are presets of an algorithm and its settings, so that
+	// operators only need to configure a single name.
+	profiles = map[string]profile{
+		// Gossip is small and latency sensitive, so it is compressed with
+		// snappy, and tiny messages are sent as is.
+		GossipProfile: {
+			maxSize: 2 * units.MiB,
+			newCompressor: func(maxSize int64) (Compressor, error) {
+				snappyCompressor, err := NewCompressorByName(SnappyName, maxSize)
+				if err != nil {
+					return nil, err
+				}
+				return NewThresholdCompressor(64, snappyCompressor), nil
+			},
+		},
+		BlockProfile: {
+			maxSize: 10 * units.MiB,
+			newCompressor: func(maxSize int64) (Compressor, error) {
+				return NewZstdCompressorWithLevel(maxSize, 3)
+			},
+		},
+		// Archives are compressed once and stored, so they are worth the
+		// slowest level.
+		ArchiveProfile: {
+			maxSize: units.GiB,
+			newCompressor: func(maxSize int64) (Compressor, error) {
+				return NewZstdCompressorWithLevel(maxSize, zstdBestCompression)
+			},
+		},
+	}
+)
+
+type profile struct {
+	maxSize       int64
+	newCompressor Factory
+}
+
+// NewCompressorForProfile returns the Compressor configured by the profile
+// name, which must be one of [GossipProfile], [BlockProfile] or
+// [ArchiveProfile].
+func NewCompressorForProfile(name string) (Compressor, error) {
+	p, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProfile, name)
+	}
+	return p.newCompressor(p.maxSize)
+}
@@ -0,0 +1,55 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestNewCompressorForProfile(t *testing.T) {
+	msgs := [][]byte{
+		{1},
+		newTestDictionaryMessage(0),
+		bytes.Repeat([]byte("avalanche"), 100*units.KiB),
+	}
+	for _, name := range []string{GossipProfile, BlockProfile, ArchiveProfile} {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := NewCompressorForProfile(name)
+			require.NoError(err)
+
+			for _, msg := range msgs {
+				compressed, err := compressor.Compress(msg)
+				require.NoError(err)
+
+				decompressed, err := compressor.Decompress(compressed)
+				require.NoError(err)
+				require.Equal(msg, decompressed)
+			}
+		})
+	}
+}
+
+func TestNewCompressorForProfileUnknown(t *testing.T) {
+	_, err := NewCompressorForProfile("unknown")
+	require.ErrorIs(t, err, ErrUnknownProfile)
+}
+
+func TestNewCompressorForProfileGossipMaxSize(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewCompressorForProfile(GossipProfile)
+	require.NoError(err)
+
+	// A peer can send any payload uncompressed, not only those below the
+	// threshold.
+	_, err = compressor.Decompress(withFlag(smallTag, make([]byte, 8*units.MiB)))
+	require.ErrorIs(err, ErrDecompressedMsgTooLarge)
+}
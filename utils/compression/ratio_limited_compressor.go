@@ -0,0 +1,85 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	_ Compressor     = (*ratioLimitedCompressor)(nil)
+	_ ConfigReporter = (*ratioLimitedCompressor)(nil)
+
+	ErrInvalidMaxRatio = errors.New("invalid max expansion ratio")
+	ErrRatioExceeded   = errors.New("max expansion ratio exceeded")
+)
+
+// NewRatioLimitedCompressor returns a Compressor that bounds decompressed
+// messages to maxRatio times the size of the compressed message, in addition
+// to maxSize. Decompression is aborted as soon as the bound is exceeded, which
+// catches small messages that expand far more than legitimate traffic but
+// stay under maxSize.
+//
+// If the ratio is exceeded, [ErrRatioExceeded] is returned along with
+// [ErrDecompressedMsgTooLarge]. The bound is enforced through the max size of
+// the compressors created by factory, so factory is called on every call to
+// Decompress and must be cheap.
+//
+// The reported [CompressorConfig] is that of the Compressor created with
+// maxSize, if it reports one.
+func NewRatioLimitedCompressor(factory Factory, maxSize int64, maxRatio float64) (Compressor, error) {
+	// The condition is negated so that NaN is rejected.
+	if !(maxRatio > 0) {
+		return nil, fmt.Errorf("%w: %f <= 0", ErrInvalidMaxRatio, maxRatio)
+	}
+	compressor, err := factory(maxSize)
+	if err != nil {
+		return nil, err
+	}
+	return &ratioLimitedCompressor{
+		factory:    factory,
+		compressor: compressor,
+		maxSize:    maxSize,
+		maxRatio:   maxRatio,
+	}, nil
+}
+
+type ratioLimitedCompressor struct {
+	factory    Factory
+	compressor Compressor
+	maxSize    int64
+	maxRatio   float64
+}
+
+func (r *ratioLimitedCompressor) Compress(msg []byte) ([]byte, error) {
+	return r.compressor.Compress(msg)
+}
+
+func (r *ratioLimitedCompressor) Decompress(msg []byte) ([]byte, error) {
+	ratioLimit := r.maxRatio * float64(len(msg))
+	if ratioLimit >= float64(r.maxSize) {
+		// The ratio is less restrictive than maxSize.
+		return r.compressor.Decompress(msg)
+	}
+
+	// Factories may reject a max size of 0, so empty outputs are allowed through
+	// a limit of 1 byte.
+	compressor, err := r.factory(max(int64(ratioLimit), 1))
+	if err != nil {
+		return nil, err
+	}
+	decompressed, err := compressor.Decompress(msg)
+	if errors.Is(err, ErrDecompressedMsgTooLarge) {
+		return nil, fmt.Errorf("%w: (> %.2f * %d): %w", ErrRatioExceeded, r.maxRatio, len(msg), err)
+	}
+	return decompressed, err
+}
+
+func (r *ratioLimitedCompressor) Config() CompressorConfig {
+	if reporter, ok := r.compressor.(ConfigReporter); ok {
+		return reporter.Config()
+	}
+	return CompressorConfig{}
+}
@@ -0,0 +1,105 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestRatioLimitedCompressor(t *testing.T) {
+	tests := []struct {
+		name        string
+		msg         []byte
+		expectedErr error
+	}{
+		{
+			name: "benign",
+			msg:  append(utils.RandomBytes(units.KiB), newTestDictionaryMessage(0)...),
+		},
+		{
+			// Zeros compress far better than legitimate traffic, while
+			// staying well under maxSize.
+			name:        "bomb",
+			msg:         make([]byte, maxMessageSize/2),
+			expectedErr: ErrRatioExceeded,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := NewRatioLimitedCompressor(NewZstdCompressor, maxMessageSize, 10)
+			require.NoError(err)
+
+			compressed, err := compressor.Compress(test.msg)
+			require.NoError(err)
+
+			decompressed, err := compressor.Decompress(compressed)
+			require.ErrorIs(err, test.expectedErr)
+			if test.expectedErr != nil {
+				require.ErrorIs(err, ErrDecompressedMsgTooLarge)
+				return
+			}
+			require.Equal(test.msg, decompressed)
+		})
+	}
+}
+
+func TestRatioLimitedCompressorMaxSize(t *testing.T) {
+	require := require.New(t)
+
+	const maxSize = units.KiB
+	compressor, err := NewRatioLimitedCompressor(NewZstdCompressor, maxSize, math.MaxFloat64)
+	require.NoError(err)
+
+	unlimited, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	compressed, err := unlimited.Compress(bytes.Repeat([]byte{1}, maxSize+1))
+	require.NoError(err)
+
+	// maxSize is still enforced when the ratio isn't exceeded.
+	_, err = compressor.Decompress(compressed)
+	require.ErrorIs(err, ErrDecompressedMsgTooLarge)
+	require.NotErrorIs(err, ErrRatioExceeded)
+}
+
+func TestRatioLimitedCompressorSmallLimit(t *testing.T) {
+	require := require.New(t)
+
+	factory := func(maxSize int64) (Compressor, error) {
+		if maxSize <= 0 {
+			return nil, ErrInvalidMaxSizeCompressor
+		}
+		return NewZstdCompressor(maxSize)
+	}
+	compressor, err := NewRatioLimitedCompressor(factory, maxMessageSize, 0.01)
+	require.NoError(err)
+
+	// The ratio allows less than a byte, which must not be passed to the
+	// factory as a max size of 0.
+	compressed, err := compressor.Compress(nil)
+	require.NoError(err)
+	decompressed, err := compressor.Decompress(compressed)
+	require.NoError(err)
+	require.Empty(decompressed)
+
+	compressed, err = compressor.Compress(make([]byte, 2))
+	require.NoError(err)
+	_, err = compressor.Decompress(compressed)
+	require.ErrorIs(err, ErrRatioExceeded)
+}
+
+func TestNewRatioLimitedCompressorInvalidRatio(t *testing.T) {
+	for _, maxRatio := range []float64{-1, 0, math.NaN()} {
+		_, err := NewRatioLimitedCompressor(NewZstdCompressor, maxMessageSize, maxRatio)
+		require.ErrorIs(t, err, ErrInvalidMaxRatio)
+	}
+}
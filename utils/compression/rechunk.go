@@ -0,0 +1,71 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// Rechunk reads a stream of frames, as read by a [FrameReader], from src and
+// writes the same bytes to dst as frames that each decompress to dstChunk
+// bytes, except for the last frame which may be shorter. Frames are compressed
+// and decompressed with c.
+//
+// Each source frame must decompress to at most srcChunk bytes, so at most
+// srcChunk + dstChunk decompressed bytes are held in memory at once. Frames
+// are written to dst as soon as they are complete.
+func Rechunk(src io.Reader, dst io.Writer, srcChunk, dstChunk int, c Compressor) error {
+	for _, chunk := range []int{srcChunk, dstChunk} {
+		// Frames that don't shrink are sent raw, with a flag byte.
+		if chunk <= 0 || uint64(chunk) >= math.MaxUint32 {
+			return fmt.Errorf("%w: %d", ErrInvalidFrameSize, chunk)
+		}
+	}
+
+	var (
+		reader  = NewFrameReader(src, c, uint32(srcChunk)+1)
+		pending = make([]byte, 0, srcChunk+dstChunk)
+		frame   []byte
+	)
+	// writeFrame writes msg to dst as a single frame.
+	writeFrame := func(msg []byte) error {
+		var err error
+		frame, err = appendFrame(frame[:0], c, msg, uint32(dstChunk)+1)
+		if err != nil {
+			return err
+		}
+		_, err = dst.Write(frame)
+		return err
+	}
+
+	for {
+		msg, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if len(msg) > srcChunk {
+			return fmt.Errorf("%w: frame decompressed to (%d) > (%d)", ErrInvalidFormat, len(msg), srcChunk)
+		}
+
+		pending = append(pending, msg...)
+		written := 0
+		for len(pending)-written >= dstChunk {
+			if err := writeFrame(pending[written : written+dstChunk]); err != nil {
+				return err
+			}
+			written += dstChunk
+		}
+		pending = append(pending[:0], pending[written:]...)
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+	return writeFrame(pending)
+}
@@ -0,0 +1,120 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestRechunk(t *testing.T) {
+	require := require.New(t)
+
+	const (
+		srcChunk = 64 * units.KiB
+		dstChunk = 256 * units.KiB
+	)
+	compressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+
+	// The snapshot doesn't end on a frame boundary of either size.
+	rng := rand.New(rand.NewSource(0)) // #nosec G404
+	snapshot := newTestText(rng, units.MiB+units.KiB)
+
+	var src []byte
+	for start := 0; start < len(snapshot); start += srcChunk {
+		end := min(start+srcChunk, len(snapshot))
+		src, err = appendFrame(src, compressor, snapshot[start:end], maxMessageSize)
+		require.NoError(err)
+	}
+
+	var dst bytes.Buffer
+	require.NoError(Rechunk(bytes.NewReader(src), &dst, srcChunk, dstChunk, compressor))
+
+	var (
+		reader = NewFrameReader(&dst, compressor, maxMessageSize)
+		frames [][]byte
+	)
+	for {
+		frame, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(err)
+		frames = append(frames, frame)
+	}
+	require.Len(frames, 5)
+	for _, frame := range frames[:len(frames)-1] {
+		require.Len(frame, dstChunk)
+	}
+	require.Len(frames[len(frames)-1], units.KiB)
+	require.Equal(snapshot, bytes.Join(frames, nil))
+}
+
+func TestRechunkEmpty(t *testing.T) {
+	require := require.New(t)
+
+	var dst bytes.Buffer
+	require.NoError(Rechunk(bytes.NewReader(nil), &dst, units.KiB, units.KiB, NewNoCompressor()))
+	require.Zero(dst.Len())
+}
+
+func TestRechunkErrors(t *testing.T) {
+	compressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(t, err)
+
+	// Compresses below the source chunk size, but decompresses above it.
+	oversized, err := appendFrame(nil, compressor, bytes.Repeat([]byte{1}, 2*units.KiB), maxMessageSize)
+	require.NoError(t, err)
+	truncated, err := appendFrame(nil, compressor, newTestDictionaryMessage(0), maxMessageSize)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		src         []byte
+		srcChunk    int
+		dstChunk    int
+		expectedErr error
+	}{
+		{
+			name:        "invalid source chunk",
+			srcChunk:    0,
+			dstChunk:    units.KiB,
+			expectedErr: ErrInvalidFrameSize,
+		},
+		{
+			name:        "invalid destination chunk",
+			srcChunk:    units.KiB,
+			dstChunk:    -1,
+			expectedErr: ErrInvalidFrameSize,
+		},
+		{
+			name:        "source frame too large",
+			src:         oversized,
+			srcChunk:    units.KiB,
+			dstChunk:    units.KiB,
+			expectedErr: ErrInvalidFormat,
+		},
+		{
+			name:        "truncated source",
+			src:         truncated[:len(truncated)-1],
+			srcChunk:    units.KiB,
+			dstChunk:    units.KiB,
+			expectedErr: io.ErrUnexpectedEOF,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var dst bytes.Buffer
+			err := Rechunk(bytes.NewReader(test.src), &dst, test.srcChunk, test.dstChunk, compressor)
+			require.ErrorIs(t, err, test.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"golang.org/x/exp/maps"
+
+	"github.com/ava-labs/avalanchego/utils/set"
+)
+
+// Names the compressors without a [Type] are registered with.
+const (
+	SnappyName  = "snappy"
+	S2Name      = "s2"
+	DeflateName = "deflate"
+)
+
+var (
+	ErrUnknownCompressor   = errors.New("unknown compressor")
+	ErrDuplicateCompressor = errors.New("duplicate compressor")
+	ErrNoCommonCompressor  = errors.New("no common compressor")
+	ErrAlgorithmNotAllowed = errors.New("compression algorithm not allowed")
+
+	registryLock sync.RWMutex
+	registry     = map[string]Factory{
+		TypeNone.String(): func(int64) (Compressor, error) {
+			return NewNoCompressor(), nil
+		},
+		TypeZstd.String(): NewZstdCompressor,
+		SnappyName:        NewSnappyCompressor,
+		S2Name:            NewS2Compressor,
+		DeflateName:       NewDeflateCompressor,
+	}
+)
+
+// Factory creates a Compressor that bounds messages to maxSize bytes.
+type Factory func(maxSize int64) (Compressor, error)
+
+// RegisterCompressor makes the compressor created by factory available
+// through [NewCompressorByName]. Names must be unique.
+func RegisterCompressor(name string, factory Factory) error {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if _, ok := registry[name]; ok {
+		return fmt.Errorf("%w: %q", ErrDuplicateCompressor, name)
+	}
+	registry[name] = factory
+	return nil
+}
+
+// NewCompressorByName creates the compressor registered with name.
+func NewCompressorByName(name string, maxSize int64) (Compressor, error) {
+	registryLock.RLock()
+	factory, ok := registry[name]
+	registryLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCompressor, name)
+	}
+	return factory(maxSize)
+}
+
+// SelectCompressor returns the first compressor in peerPreferred that is also
+// in localSupported, along with its name so that it can be acknowledged to the
+// peer. If there is no such compressor, no compression is used.
+//
+// Names in localSupported must be registered, see [NewCompressorByName].
+func SelectCompressor(localSupported, peerPreferred []string, maxSize int64) (Compressor, string, error) {
+	compressor, name, err := SelectCommonCompressor(localSupported, peerPreferred, maxSize)
+	if errors.Is(err, ErrNoCommonCompressor) {
+		return NewNoCompressor(), TypeNone.String(), nil
+	}
+	return compressor, name, err
+}
+
+// SelectCommonCompressor is like [SelectCompressor], but returns
+// [ErrNoCommonCompressor] rather than falling back to no compression.
+func SelectCommonCompressor(localSupported, peerPreferred []string, maxSize int64) (Compressor, string, error) {
+	for _, name := range peerPreferred {
+		if !slices.Contains(localSupported, name) {
+			continue
+		}
+		compressor, err := NewCompressorByName(name, maxSize)
+		if err != nil {
+			return nil, "", err
+		}
+		return compressor, name, nil
+	}
+	return nil, "", fmt.Errorf("%w: local %q, peer %q", ErrNoCommonCompressor, localSupported, peerPreferred)
+}
+
+// RestrictedRegistry is a view of the registered compressors that only
+// creates the compressors of an allowlist, which guarantees that a deployment
+// only uses approved algorithms even if more are registered.
+type RestrictedRegistry struct {
+	allowed set.Set[string]
+}
+
+// NewRestrictedRegistry returns a RestrictedRegistry that only allows the
+// compressors registered with the allowed names.
+func NewRestrictedRegistry(allowed ...string) *RestrictedRegistry {
+	return &RestrictedRegistry{
+		allowed: set.Of(allowed...),
+	}
+}
+
+// NewCompressorByName creates the compressor registered with name, as
+// [NewCompressorByName] does. If name isn't allowed, [ErrAlgorithmNotAllowed]
+// is returned, even if it isn't registered.
+func (r *RestrictedRegistry) NewCompressorByName(name string, maxSize int64) (Compressor, error) {
+	if !r.allowed.Contains(name) {
+		return nil, fmt.Errorf("%w: %q", ErrAlgorithmNotAllowed, name)
+	}
+	return NewCompressorByName(name, maxSize)
+}
+
+// registeredNames returns the names of all registered compressors in sorted
+// order.
+func registeredNames() []string {
+	registryLock.RLock()
+	names := maps.Keys(registry)
+	registryLock.RUnlock()
+
+	slices.Sort(names)
+	return names
+}
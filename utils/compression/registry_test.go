@@ -0,0 +1,204 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestNewCompressorByName(t *testing.T) {
+	for _, name := range []string{TypeNone.String(), TypeZstd.String(), SnappyName, S2Name, DeflateName} {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := NewCompressorByName(name, maxMessageSize)
+			require.NoError(err)
+
+			msg := []byte("avalanche")
+			compressed, err := compressor.Compress(msg)
+			require.NoError(err)
+
+			decompressed, err := compressor.Decompress(compressed)
+			require.NoError(err)
+			require.Equal(msg, decompressed)
+		})
+	}
+}
+
+// TestRegisteredCompressorsRoundTrip checks the [Compressor] contract for every
+// registered compressor, so that newly registered algorithms are covered
+// without having to be listed here.
+func TestRegisteredCompressorsRoundTrip(t *testing.T) {
+	inputs := map[string][]byte{
+		"empty":            {},
+		"tiny":             {0},
+		"max size":         bytes.Repeat([]byte{1}, maxMessageSize),
+		"large repetitive": bytes.Repeat([]byte("avalanche"), 100*units.KiB),
+		"random":           utils.RandomBytes(units.MiB),
+	}
+	for _, name := range registeredNames() {
+		t.Run(name, func(t *testing.T) {
+			compressor, err := NewCompressorByName(name, maxMessageSize)
+			require.NoError(t, err)
+
+			for inputName, msg := range inputs {
+				t.Run(inputName, func(t *testing.T) {
+					require := require.New(t)
+
+					compressed, err := compressor.Compress(msg)
+					require.NoError(err)
+
+					decompressed, err := compressor.Decompress(compressed)
+					require.NoError(err)
+					if len(msg) == 0 {
+						// Compressors may return either nil or an empty slice.
+						require.Empty(decompressed)
+					} else {
+						require.Equal(msg, decompressed)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestNewCompressorByNameUnknown(t *testing.T) {
+	_, err := NewCompressorByName("unknown", maxMessageSize)
+	require.ErrorIs(t, err, ErrUnknownCompressor)
+}
+
+func TestRestrictedRegistry(t *testing.T) {
+	restricted := NewRestrictedRegistry(TypeZstd.String(), "unknown")
+	tests := []struct {
+		name        string
+		expectedErr error
+	}{
+		{
+			name: TypeZstd.String(),
+		},
+		{
+			name:        SnappyName,
+			expectedErr: ErrAlgorithmNotAllowed,
+		},
+		{
+			// Allowed names must still be registered.
+			name:        "unknown",
+			expectedErr: ErrUnknownCompressor,
+		},
+		{
+			name:        "disallowed unknown",
+			expectedErr: ErrAlgorithmNotAllowed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := restricted.NewCompressorByName(test.name, maxMessageSize)
+			require.ErrorIs(err, test.expectedErr)
+			if test.expectedErr == nil {
+				expected, err := NewCompressorByName(test.name, maxMessageSize)
+				require.NoError(err)
+				require.IsType(expected, compressor)
+			}
+		})
+	}
+}
+
+func TestRegisterCompressor(t *testing.T) {
+	require := require.New(t)
+
+	const name = "test"
+	t.Cleanup(func() {
+		registryLock.Lock()
+		defer registryLock.Unlock()
+
+		delete(registry, name)
+	})
+
+	var registeredMaxSize int64
+	require.NoError(RegisterCompressor(name, func(maxSize int64) (Compressor, error) {
+		registeredMaxSize = maxSize
+		return NewNoCompressor(), nil
+	}))
+
+	compressor, err := NewCompressorByName(name, maxMessageSize)
+	require.NoError(err)
+	require.Equal(NewNoCompressor(), compressor)
+	require.Equal(int64(maxMessageSize), registeredMaxSize)
+
+	err = RegisterCompressor(name, NewZstdCompressor)
+	require.ErrorIs(err, ErrDuplicateCompressor)
+}
+
+func TestSelectCompressor(t *testing.T) {
+	tests := []struct {
+		name         string
+		local        []string
+		peer         []string
+		expectedName string
+		expectedErr  error
+	}{
+		{
+			name:         "peer preference wins",
+			local:        []string{SnappyName, TypeZstd.String()},
+			peer:         []string{TypeZstd.String(), SnappyName},
+			expectedName: TypeZstd.String(),
+		},
+		{
+			name:         "partial overlap",
+			local:        []string{SnappyName},
+			peer:         []string{TypeZstd.String(), SnappyName},
+			expectedName: SnappyName,
+		},
+		{
+			name:         "no overlap",
+			local:        []string{SnappyName},
+			peer:         []string{TypeZstd.String()},
+			expectedName: TypeNone.String(),
+			expectedErr:  ErrNoCommonCompressor,
+		},
+		{
+			name:         "no peer preferences",
+			local:        []string{SnappyName},
+			expectedName: TypeNone.String(),
+			expectedErr:  ErrNoCommonCompressor,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, name, err := SelectCompressor(test.local, test.peer, maxMessageSize)
+			require.NoError(err)
+			require.Equal(test.expectedName, name)
+
+			msg := []byte("avalanche")
+			compressed, err := compressor.Compress(msg)
+			require.NoError(err)
+			expected, err := NewCompressorByName(name, maxMessageSize)
+			require.NoError(err)
+			decompressed, err := expected.Decompress(compressed)
+			require.NoError(err)
+			require.Equal(msg, decompressed)
+
+			_, name, err = SelectCommonCompressor(test.local, test.peer, maxMessageSize)
+			require.ErrorIs(err, test.expectedErr)
+			if test.expectedErr != nil {
+				require.Empty(name)
+			}
+		})
+	}
+}
+
+func TestSelectCompressorUnregistered(t *testing.T) {
+	_, _, err := SelectCompressor([]string{"unregistered"}, []string{"unregistered"}, maxMessageSize)
+	require.ErrorIs(t, err, ErrUnknownCompressor)
+}
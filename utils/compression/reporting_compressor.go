@@ -0,0 +1,109 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+)
+
+var _ Compressor = (*reportingCompressor)(nil)
+
+// NewReportingCompressor returns a Compressor that logs name, the algorithm
+// used by compressor, the first time it is used. After every statsInterval
+// successful calls, the ratio of the compressed to the uncompressed size of
+// those calls is logged. Nothing is logged per msg, so a reporting Compressor
+// can be created per peer to diagnose interop issues.
+//
+// If statsInterval isn't positive, only the first use is logged.
+func NewReportingCompressor(compressor Compressor, name string, log logging.Logger, statsInterval int) Compressor {
+	return &reportingCompressor{
+		compressor:    compressor,
+		name:          name,
+		log:           log,
+		statsInterval: statsInterval,
+	}
+}
+
+type reportingCompressor struct {
+	compressor    Compressor
+	name          string
+	log           logging.Logger
+	statsInterval int
+
+	firstUse sync.Once
+
+	lock         sync.Mutex
+	msgs         int
+	uncompressed int
+	compressed   int
+}
+
+func (r *reportingCompressor) Compress(msg []byte) ([]byte, error) {
+	r.reportFirstUse()
+	compressed, err := r.compressor.Compress(msg)
+	if err != nil {
+		return nil, err
+	}
+	r.record(len(msg), len(compressed))
+	return compressed, nil
+}
+
+func (r *reportingCompressor) Decompress(msg []byte) ([]byte, error) {
+	r.reportFirstUse()
+	decompressed, err := r.compressor.Decompress(msg)
+	if err != nil {
+		return nil, err
+	}
+	r.record(len(decompressed), len(msg))
+	return decompressed, nil
+}
+
+func (r *reportingCompressor) reportFirstUse() {
+	r.firstUse.Do(func() {
+		r.log.Info("using compressor",
+			zap.String("compressor", r.name),
+		)
+	})
+}
+
+// record adds a msg to the current interval and logs the stats of the interval
+// once it is complete.
+func (r *reportingCompressor) record(uncompressed, compressed int) {
+	if r.statsInterval <= 0 {
+		return
+	}
+
+	r.lock.Lock()
+	r.msgs++
+	r.uncompressed += uncompressed
+	r.compressed += compressed
+	if r.msgs < r.statsInterval {
+		r.lock.Unlock()
+		return
+	}
+	var (
+		totalUncompressed = r.uncompressed
+		totalCompressed   = r.compressed
+	)
+	r.msgs = 0
+	r.uncompressed = 0
+	r.compressed = 0
+	r.lock.Unlock()
+
+	var ratio float64
+	if totalUncompressed > 0 {
+		ratio = float64(totalCompressed) / float64(totalUncompressed)
+	}
+	r.log.Debug("compression stats",
+		zap.String("compressor", r.name),
+		zap.Int("numMsgs", r.statsInterval),
+		zap.Int("uncompressedBytes", totalUncompressed),
+		zap.Int("compressedBytes", totalCompressed),
+		zap.Float64("ratio", ratio),
+	)
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+)
+
+type loggedMsg struct {
+	msg    string
+	fields []zap.Field
+}
+
+// recordingLogger records the msgs logged at the info and debug levels.
+type recordingLogger struct {
+	logging.NoLog
+	info  []loggedMsg
+	debug []loggedMsg
+}
+
+func (r *recordingLogger) Info(msg string, fields ...zap.Field) {
+	r.info = append(r.info, loggedMsg{msg: msg, fields: fields})
+}
+
+func (r *recordingLogger) Debug(msg string, fields ...zap.Field) {
+	r.debug = append(r.debug, loggedMsg{msg: msg, fields: fields})
+}
+
+func TestReportingCompressor(t *testing.T) {
+	require := require.New(t)
+
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	var (
+		log        = &recordingLogger{}
+		compressor = NewReportingCompressor(zstdCompressor, "zstd", log, 3)
+		msg        = bytes.Repeat([]byte("avalanche"), 100)
+	)
+
+	// Two compressions and a decompression complete the first interval.
+	compressed, err := compressor.Compress(msg)
+	require.NoError(err)
+	_, err = compressor.Compress(msg)
+	require.NoError(err)
+	require.Len(log.info, 1)
+	require.Empty(log.debug)
+	_, err = compressor.Decompress(compressed)
+	require.NoError(err)
+
+	// Failed calls aren't counted.
+	_, err = compressor.Decompress(nil)
+	require.ErrorIs(err, ErrInvalidFormat)
+
+	require.Equal([]loggedMsg{{
+		msg:    "using compressor",
+		fields: []zap.Field{zap.String("compressor", "zstd")},
+	}}, log.info)
+	require.Equal([]loggedMsg{{
+		msg: "compression stats",
+		fields: []zap.Field{
+			zap.String("compressor", "zstd"),
+			zap.Int("numMsgs", 3),
+			zap.Int("uncompressedBytes", 3*len(msg)),
+			zap.Int("compressedBytes", 3*len(compressed)),
+			zap.Float64("ratio", float64(len(compressed))/float64(len(msg))),
+		},
+	}}, log.debug)
+
+	// Stats are only logged once the next interval is complete.
+	for range 2 {
+		_, err := compressor.Compress(msg)
+		require.NoError(err)
+	}
+	require.Len(log.info, 1)
+	require.Len(log.debug, 1)
+	_, err = compressor.Compress(msg)
+	require.NoError(err)
+	require.Len(log.debug, 2)
+}
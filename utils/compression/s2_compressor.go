@@ -0,0 +1,132 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/klauspost/compress/s2"
+)
+
+var (
+	_ Compressor       = (*s2Compressor)(nil)
+	_ StreamCompressor = (*s2Compressor)(nil)
+	_ AppendCompressor = (*s2Compressor)(nil)
+	_ SizeEstimator    = (*s2Compressor)(nil)
+	_ ConfigReporter   = (*s2Compressor)(nil)
+)
+
+// NewS2Compressor returns a Compressor that uses the s2 block format. S2 is
+// an extension of snappy that typically compresses better at similar speeds.
+//
+// Like snappy, s2 blocks do not start with a magic number, so callers that may
+// receive payloads in multiple formats must tag s2 payloads out of band.
+// Streams use the s2 framing format, which does start with a stream
+// identifier.
+func NewS2Compressor(maxSize int64) (Compressor, error) {
+	if maxSize > s2.MaxBlockSize {
+		// Larger blocks can't be represented by the s2 block format.
+		return nil, ErrInvalidMaxSizeCompressor
+	}
+
+	return &s2Compressor{
+		maxSize: maxSize,
+	}, nil
+}
+
+type s2Compressor struct {
+	maxSize int64
+}
+
+func (s *s2Compressor) Compress(msg []byte) ([]byte, error) {
+	if int64(len(msg)) > s.maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrMsgTooLarge, len(msg), s.maxSize)
+	}
+	return s2.Encode(nil, msg), nil
+}
+
+func (s *s2Compressor) Decompress(msg []byte) ([]byte, error) {
+	// The decompressed length is written in the block header, so oversized
+	// payloads can be rejected before any allocation is made.
+	decompressedLen, err := s2.DecodedLen(msg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+	if int64(decompressedLen) > s.maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrDecompressedMsgTooLarge, decompressedLen, s.maxSize)
+	}
+	decompressed, err := s2.Decode(nil, msg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+	return decompressed, nil
+}
+
+func (s *s2Compressor) CompressStream(dst io.Writer, src io.Reader) error {
+	writer := s2.NewWriter(dst)
+	exceeded, err := copyLimited(writer, src, s.maxSize)
+	if err != nil {
+		_ = writer.Close()
+		return err
+	}
+	if exceeded {
+		_ = writer.Close()
+		return fmt.Errorf("%w: (> %d)", ErrMsgTooLarge, s.maxSize)
+	}
+	return writer.Close()
+}
+
+func (s *s2Compressor) DecompressStream(dst io.Writer, src io.Reader) error {
+	source := &sourceReader{reader: src}
+	exceeded, err := copyLimited(dst, s2.NewReader(source), s.maxSize)
+	if err != nil {
+		return source.wrapErr(err)
+	}
+	if exceeded {
+		return fmt.Errorf("%w: (> %d)", ErrDecompressedMsgTooLarge, s.maxSize)
+	}
+	return nil
+}
+
+func (s *s2Compressor) AppendCompress(dst, msg []byte) ([]byte, error) {
+	if int64(len(msg)) > s.maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrMsgTooLarge, len(msg), s.maxSize)
+	}
+
+	// s2 only writes into the provided buffer if its length is at least the
+	// worst case encoded size.
+	dst = slices.Grow(dst, s2.MaxEncodedLen(len(msg)))
+	compressed := s2.Encode(dst[len(dst):cap(dst)], msg)
+	return dst[:len(dst)+len(compressed)], nil
+}
+
+func (s *s2Compressor) AppendDecompress(dst, msg []byte) ([]byte, error) {
+	decompressedLen, err := s2.DecodedLen(msg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+	if int64(decompressedLen) > s.maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrDecompressedMsgTooLarge, decompressedLen, s.maxSize)
+	}
+
+	dst = slices.Grow(dst, decompressedLen)
+	decompressed, err := s2.Decode(dst[len(dst):cap(dst)], msg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+	return dst[:len(dst)+len(decompressed)], nil
+}
+
+func (s *s2Compressor) Config() CompressorConfig {
+	return CompressorConfig{
+		MaxSize: s.maxSize,
+	}
+}
+
+// EstimateCompressedSize returns -1 if msg is too large to be encoded.
+func (*s2Compressor) EstimateCompressedSize(msg []byte) int {
+	return s2.MaxEncodedLen(len(msg))
+}
@@ -0,0 +1,79 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestS2CompressorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  []byte
+	}{
+		{
+			name: "random",
+			msg:  utils.RandomBytes(units.KiB),
+		},
+		{
+			name: "repetitive",
+			msg:  bytes.Repeat([]byte("avalanche"), units.KiB),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := NewS2Compressor(maxMessageSize)
+			require.NoError(err)
+
+			compressed, err := compressor.Compress(test.msg)
+			require.NoError(err)
+
+			decompressed, err := compressor.Decompress(compressed)
+			require.NoError(err)
+			require.Equal(test.msg, decompressed)
+		})
+	}
+}
+
+func TestS2CompressorRepetitiveInputShrinks(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewS2Compressor(maxMessageSize)
+	require.NoError(err)
+
+	msg := bytes.Repeat([]byte("avalanche"), units.KiB)
+	compressed, err := compressor.Compress(msg)
+	require.NoError(err)
+	require.Less(len(compressed), len(msg)/10)
+}
+
+func TestS2CompressorSmallerThanSnappy(t *testing.T) {
+	require := require.New(t)
+
+	s2Compressor, err := NewS2Compressor(maxMessageSize)
+	require.NoError(err)
+	snappyCompressor, err := NewSnappyCompressor(maxMessageSize)
+	require.NoError(err)
+
+	msg := benchmarkPayloads["json"]
+	s2Compressed, err := s2Compressor.Compress(msg)
+	require.NoError(err)
+	snappyCompressed, err := snappyCompressor.Compress(msg)
+	require.NoError(err)
+	require.Less(len(s2Compressed), len(snappyCompressed))
+}
+
+func TestNewS2CompressorMaxSize(t *testing.T) {
+	_, err := NewS2Compressor(s2.MaxBlockSize + 1)
+	require.ErrorIs(t, err, ErrInvalidMaxSizeCompressor)
+}
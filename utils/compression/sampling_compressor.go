@@ -0,0 +1,50 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+)
+
+var _ Compressor = (*samplingCompressor)(nil)
+
+// NewSamplingCompressor returns a Compressor that passes a copy of each msg
+// given to Compress to sink with probability rate, which must be in [0, 1].
+// This allows real payloads to be captured to train dictionaries or tune
+// compression levels.
+//
+// sink is called synchronously before msg is compressed, so it should hand the
+// sample off rather than block. Decompress isn't sampled.
+func NewSamplingCompressor(compressor Compressor, rate float64, sink func([]byte)) (Compressor, error) {
+	// The condition is negated so that NaN is rejected.
+	if !(rate >= 0 && rate <= 1) {
+		return nil, fmt.Errorf("%w: %f not in [0, 1]", ErrInvalidRate, rate)
+	}
+	return &samplingCompressor{
+		compressor: compressor,
+		rate:       rate,
+		sink:       sink,
+	}, nil
+}
+
+type samplingCompressor struct {
+	compressor Compressor
+	rate       float64
+	sink       func([]byte)
+}
+
+func (s *samplingCompressor) Compress(msg []byte) ([]byte, error) {
+	// Float64 returns values in [0, 1), so a rate of 1 samples every msg and a
+	// rate of 0 samples none.
+	if rand.Float64() < s.rate { // #nosec G404
+		s.sink(slices.Clone(msg))
+	}
+	return s.compressor.Compress(msg)
+}
+
+func (s *samplingCompressor) Decompress(msg []byte) ([]byte, error) {
+	return s.compressor.Decompress(msg)
+}
@@ -0,0 +1,76 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSamplingCompressor(t *testing.T) {
+	tests := []struct {
+		name            string
+		rate            float64
+		expectedSampled int
+	}{
+		{
+			name:            "all",
+			rate:            1,
+			expectedSampled: 100,
+		},
+		{
+			name:            "none",
+			rate:            0,
+			expectedSampled: 0,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			var samples [][]byte
+			compressor, err := NewSamplingCompressor(NewNoCompressor(), test.rate, func(sample []byte) {
+				samples = append(samples, sample)
+			})
+			require.NoError(err)
+
+			for i := 0; i < 100; i++ {
+				msg := newTestDictionaryMessage(i)
+				_, err := compressor.Compress(msg)
+				require.NoError(err)
+			}
+			require.Len(samples, test.expectedSampled)
+			for i, sample := range samples {
+				require.Equal(newTestDictionaryMessage(i), sample)
+			}
+		})
+	}
+}
+
+func TestSamplingCompressorCopiesInput(t *testing.T) {
+	require := require.New(t)
+
+	var sample []byte
+	compressor, err := NewSamplingCompressor(NewNoCompressor(), 1, func(s []byte) {
+		sample = s
+	})
+	require.NoError(err)
+
+	msg := []byte("avalanche")
+	_, err = compressor.Compress(msg)
+	require.NoError(err)
+
+	// Modifying the caller's buffer must not modify the sample.
+	msg[0] = 'A'
+	require.Equal([]byte("avalanche"), sample)
+}
+
+func TestNewSamplingCompressorInvalidRate(t *testing.T) {
+	for _, rate := range []float64{-0.1, 1.1, math.NaN()} {
+		_, err := NewSamplingCompressor(NewNoCompressor(), rate, func([]byte) {})
+		require.ErrorIs(t, err, ErrInvalidRate)
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+)
+
+const (
+	// The seek table is stored in a skippable frame with this magic number,
+	// followed by the footer, which ends with zstdSeekableMagic.
+	zstdSeekTableMagic = zstdSkippableMagic + 0xE
+	zstdSeekableMagic  = 0x8F92EAB1
+
+	zstdSkippableHeaderLen = 2 * wrappers.IntLen
+	// The footer is the number of frames, the descriptor and the magic number.
+	zstdSeekTableFooterLen = 2*wrappers.IntLen + 1
+	// Entries are the compressed and decompressed size of a frame, optionally
+	// followed by a checksum.
+	zstdSeekTableEntryLen         = 2 * wrappers.IntLen
+	zstdSeekTableChecksumEntryLen = 3 * wrappers.IntLen
+
+	zstdSeekTableChecksumFlag  = 1 << 7
+	zstdSeekTableReservedFlags = 0x7C
+
+	zstdSeekableDefaultFrameSize = 64 * units.KiB
+)
+
+var (
+	_ Compressor = (*SeekableZstdCompressor)(nil)
+
+	ErrInvalidRange = errors.New("invalid range")
+
+	errMissingSeekTable = fmt.Errorf("%w: missing seek table", ErrInvalidFormat)
+)
+
+// SeekableZstdCompressor compresses msgs into the zstd seekable format, which
+// splits the msg into independent zstd frames and appends a seek table in a
+// skippable frame, so that ranges of the msg can be decompressed without
+// decompressing all of it.
+//
+// The seek table is written without checksums. Checksums in seek tables
+// written by other implementations aren't verified.
+type SeekableZstdCompressor struct {
+	maxSize    int64
+	frameSize  int
+	compressor Compressor
+}
+
+// NewSeekableZstdCompressor returns a SeekableZstdCompressor that splits msgs
+// into frames of frameSize uncompressed bytes. Smaller frames allow smaller
+// ranges to be decompressed, at the cost of compression ratio. If frameSize
+// is 0, frames of 64 KiB are used.
+func NewSeekableZstdCompressor(maxSize int64, frameSize int) (*SeekableZstdCompressor, error) {
+	if frameSize == 0 {
+		frameSize = zstdSeekableDefaultFrameSize
+	}
+	// Frame sizes are recorded as uint32s in the seek table.
+	if frameSize < 0 || uint64(frameSize) > math.MaxUint32 {
+		return nil, fmt.Errorf("%w: %d not in [1, %d]", ErrInvalidFrameSize, frameSize, uint64(math.MaxUint32))
+	}
+	compressor, err := NewZstdCompressor(maxSize)
+	if err != nil {
+		return nil, err
+	}
+	return &SeekableZstdCompressor{
+		maxSize:    maxSize,
+		frameSize:  frameSize,
+		compressor: compressor,
+	}, nil
+}
+
+func (s *SeekableZstdCompressor) Compress(msg []byte) ([]byte, error) {
+	if int64(len(msg)) > s.maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrMsgTooLarge, len(msg), s.maxSize)
+	}
+
+	var (
+		numFrames  = (len(msg) + s.frameSize - 1) / s.frameSize
+		seekTable  = make([]byte, 0, zstdSkippableHeaderLen+numFrames*zstdSeekTableEntryLen+zstdSeekTableFooterLen)
+		compressed []byte
+	)
+	seekTable = binary.LittleEndian.AppendUint32(seekTable, zstdSeekTableMagic)
+	seekTable = binary.LittleEndian.AppendUint32(seekTable, uint32(cap(seekTable)-zstdSkippableHeaderLen))
+	for start := 0; start < len(msg); start += s.frameSize {
+		end := min(start+s.frameSize, len(msg))
+		frame, err := s.compressor.Compress(msg[start:end])
+		if err != nil {
+			return nil, err
+		}
+		compressed = append(compressed, frame...)
+		seekTable = binary.LittleEndian.AppendUint32(seekTable, uint32(len(frame)))
+		seekTable = binary.LittleEndian.AppendUint32(seekTable, uint32(end-start))
+	}
+	seekTable = binary.LittleEndian.AppendUint32(seekTable, uint32(numFrames))
+	seekTable = append(seekTable, 0)
+	seekTable = binary.LittleEndian.AppendUint32(seekTable, zstdSeekableMagic)
+	return append(compressed, seekTable...), nil
+}
+
+func (s *SeekableZstdCompressor) Decompress(msg []byte) ([]byte, error) {
+	frames, err := s.parseSeekTable(msg)
+	if err != nil {
+		return nil, err
+	}
+	if len(frames) == 0 {
+		return []byte{}, nil
+	}
+	last := frames[len(frames)-1]
+	return s.decompressFrames(msg, frames, 0, last.decompressedOffset+last.decompressedSize)
+}
+
+// DecompressRange returns the length bytes of the decompressed msg that start
+// at offset. Only the frames that overlap the range are decompressed. If the
+// range isn't within the decompressed msg, [ErrInvalidRange] is returned.
+func (s *SeekableZstdCompressor) DecompressRange(msg []byte, offset, length int64) ([]byte, error) {
+	frames, err := s.parseSeekTable(msg)
+	if err != nil {
+		return nil, err
+	}
+	var decompressedLen int64
+	if len(frames) > 0 {
+		last := frames[len(frames)-1]
+		decompressedLen = last.decompressedOffset + last.decompressedSize
+	}
+	if offset < 0 || length < 0 || length > decompressedLen-offset {
+		return nil, fmt.Errorf("%w: [%d, %d + %d) not in [0, %d)", ErrInvalidRange, offset, offset, length, decompressedLen)
+	}
+	if length == 0 {
+		return []byte{}, nil
+	}
+
+	// Find the first frame that ends after offset.
+	first := sort.Search(len(frames), func(i int) bool {
+		return frames[i].decompressedOffset+frames[i].decompressedSize > offset
+	})
+	return s.decompressFrames(msg, frames[first:], offset, length)
+}
+
+// decompressFrames decompresses frames until length bytes starting at offset
+// have been decompressed.
+func (s *SeekableZstdCompressor) decompressFrames(msg []byte, frames []seekableFrame, offset, length int64) ([]byte, error) {
+	var (
+		end          = offset + length
+		decompressed = make([]byte, 0, length)
+	)
+	for _, frame := range frames {
+		if frame.decompressedOffset >= end {
+			break
+		}
+		compressedFrame := msg[frame.compressedOffset : frame.compressedOffset+frame.compressedSize]
+		decompressedFrame, err := s.compressor.Decompress(compressedFrame)
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(decompressedFrame)) != frame.decompressedSize {
+			return nil, fmt.Errorf("%w: frame decompressed to %d bytes but the seek table declares %d", ErrInvalidFormat, len(decompressedFrame), frame.decompressedSize)
+		}
+
+		start := max(offset-frame.decompressedOffset, 0)
+		stop := min(end-frame.decompressedOffset, frame.decompressedSize)
+		decompressed = append(decompressed, decompressedFrame[start:stop]...)
+	}
+	return decompressed, nil
+}
+
+type seekableFrame struct {
+	compressedOffset   int64
+	compressedSize     int64
+	decompressedOffset int64
+	decompressedSize   int64
+}
+
+// parseSeekTable returns the frames described by the seek table at the end of
+// msg, after checking that they cover msg up to the seek table and that they
+// don't decompress to more than maxSize bytes.
+func (s *SeekableZstdCompressor) parseSeekTable(msg []byte) ([]seekableFrame, error) {
+	if len(msg) < zstdSkippableHeaderLen+zstdSeekTableFooterLen {
+		return nil, errMissingSeekTable
+	}
+	footer := msg[len(msg)-zstdSeekTableFooterLen:]
+	if magic := binary.LittleEndian.Uint32(footer[wrappers.IntLen+1:]); magic != zstdSeekableMagic {
+		return nil, fmt.Errorf("%w: unknown seekable magic number: %#08x", errMissingSeekTable, magic)
+	}
+	descriptor := footer[wrappers.IntLen]
+	if descriptor&zstdSeekTableReservedFlags != 0 {
+		return nil, fmt.Errorf("%w: reserved bit set in seek table descriptor", ErrInvalidFormat)
+	}
+	entryLen := zstdSeekTableEntryLen
+	if descriptor&zstdSeekTableChecksumFlag != 0 {
+		entryLen = zstdSeekTableChecksumEntryLen
+	}
+
+	numFrames := uint64(binary.LittleEndian.Uint32(footer))
+	tableLen := numFrames*uint64(entryLen) + zstdSeekTableFooterLen
+	if tableLen > uint64(len(msg)-zstdSkippableHeaderLen) {
+		return nil, fmt.Errorf("%w: seek table of %d frames is longer than msg", ErrInvalidFormat, numFrames)
+	}
+	tableStart := len(msg) - int(tableLen) - zstdSkippableHeaderLen
+	header := msg[tableStart:]
+	if magic := binary.LittleEndian.Uint32(header); magic != zstdSeekTableMagic {
+		return nil, fmt.Errorf("%w: unknown seek table magic number: %#08x", ErrInvalidFormat, magic)
+	}
+	if size := binary.LittleEndian.Uint32(header[wrappers.IntLen:]); uint64(size) != tableLen {
+		return nil, fmt.Errorf("%w: seek table frame size (%d) != (%d)", ErrInvalidFormat, size, tableLen)
+	}
+
+	var (
+		entries            = msg[tableStart+zstdSkippableHeaderLen : len(msg)-zstdSeekTableFooterLen]
+		frames             = make([]seekableFrame, numFrames)
+		compressedOffset   int64
+		decompressedOffset int64
+	)
+	for i := range frames {
+		entry := entries[i*entryLen:]
+		frame := seekableFrame{
+			compressedOffset:   compressedOffset,
+			compressedSize:     int64(binary.LittleEndian.Uint32(entry)),
+			decompressedOffset: decompressedOffset,
+			decompressedSize:   int64(binary.LittleEndian.Uint32(entry[wrappers.IntLen:])),
+		}
+		compressedOffset += frame.compressedSize
+		decompressedOffset += frame.decompressedSize
+		if compressedOffset > int64(tableStart) {
+			return nil, fmt.Errorf("%w: frames extend past the seek table", ErrInvalidFormat)
+		}
+		if decompressedOffset > s.maxSize {
+			return nil, fmt.Errorf("%w: (> %d)", ErrDecompressedMsgTooLarge, s.maxSize)
+		}
+		frames[i] = frame
+	}
+	if compressedOffset != int64(tableStart) {
+		return nil, fmt.Errorf("%w: %w: %d bytes before the seek table", ErrInvalidFormat, ErrTrailingData, int64(tableStart)-compressedOffset)
+	}
+	return frames, nil
+}
@@ -0,0 +1,151 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestSeekableZstdCompressor(t *testing.T) {
+	compressor, err := NewSeekableZstdCompressor(maxMessageSize, 64*units.KiB)
+	require.NoError(t, err)
+
+	rng := rand.New(rand.NewSource(0)) // #nosec G404
+	msg := newTestText(rng, units.MiB+1)
+	compressed, err := compressor.Compress(msg)
+	require.NoError(t, err)
+
+	decompressed, err := compressor.Decompress(compressed)
+	require.NoError(t, err)
+	require.Equal(t, msg, decompressed)
+
+	ranges := []struct {
+		name   string
+		offset int64
+		length int64
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:   "within a frame",
+			offset: 10,
+			length: 100,
+		},
+		{
+			name:   "across frames",
+			offset: 64*units.KiB - 10,
+			length: 64*units.KiB + 20,
+		},
+		{
+			name:   "last byte",
+			offset: units.MiB,
+			length: 1,
+		},
+		{
+			name:   "everything",
+			length: units.MiB + 1,
+		},
+	}
+	for _, r := range ranges {
+		t.Run(r.name, func(t *testing.T) {
+			decompressedRange, err := compressor.DecompressRange(compressed, r.offset, r.length)
+			require.NoError(t, err)
+			require.Equal(t, msg[r.offset:r.offset+r.length], decompressedRange)
+		})
+	}
+}
+
+func TestSeekableZstdCompressorInvalidRange(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewSeekableZstdCompressor(maxMessageSize, units.KiB)
+	require.NoError(err)
+	compressed, err := compressor.Compress(make([]byte, 10*units.KiB))
+	require.NoError(err)
+
+	_, err = compressor.DecompressRange(compressed, -1, 1)
+	require.ErrorIs(err, ErrInvalidRange)
+	_, err = compressor.DecompressRange(compressed, 10*units.KiB, 1)
+	require.ErrorIs(err, ErrInvalidRange)
+}
+
+func TestSeekableZstdCompressorEmpty(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewSeekableZstdCompressor(maxMessageSize, 0)
+	require.NoError(err)
+	compressed, err := compressor.Compress(nil)
+	require.NoError(err)
+	require.Len(compressed, zstdSkippableHeaderLen+zstdSeekTableFooterLen)
+
+	decompressed, err := compressor.Decompress(compressed)
+	require.NoError(err)
+	require.Empty(decompressed)
+}
+
+func TestSeekableZstdCompressorInvalidFormat(t *testing.T) {
+	compressor, err := NewSeekableZstdCompressor(maxMessageSize, units.KiB)
+	require.NoError(t, err)
+	compressed, err := compressor.Compress(bytes.Repeat([]byte("avalanche"), units.KiB))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		msg         []byte
+		expectedErr error
+	}{
+		{
+			name:        "empty",
+			msg:         []byte{},
+			expectedErr: ErrInvalidFormat,
+		},
+		{
+			name:        "not seekable",
+			msg:         bytes.Repeat([]byte{0xff}, 20),
+			expectedErr: ErrInvalidFormat,
+		},
+		{
+			name:        "leading data",
+			msg:         append([]byte{0}, compressed...),
+			expectedErr: ErrTrailingData,
+		},
+		{
+			name:        "missing frame",
+			msg:         compressed[1:],
+			expectedErr: ErrInvalidFormat,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := compressor.Decompress(test.msg)
+			require.ErrorIs(t, err, test.expectedErr)
+		})
+	}
+}
+
+func TestSeekableZstdCompressorTooLarge(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewSeekableZstdCompressor(maxMessageSize, units.KiB)
+	require.NoError(err)
+	compressed, err := compressor.Compress(make([]byte, 2*units.KiB))
+	require.NoError(err)
+
+	smallCompressor, err := NewSeekableZstdCompressor(units.KiB, units.KiB)
+	require.NoError(err)
+	_, err = smallCompressor.Decompress(compressed)
+	require.ErrorIs(err, ErrDecompressedMsgTooLarge)
+}
+
+func TestNewSeekableZstdCompressorInvalidFrameSize(t *testing.T) {
+	_, err := NewSeekableZstdCompressor(maxMessageSize, -1)
+	require.ErrorIs(t, err, ErrInvalidFrameSize)
+}
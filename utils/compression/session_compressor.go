@@ -0,0 +1,123 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"compress/flate"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/exp/maps"
+)
+
+// NoDictionary is the dictionary ID that [SessionCompressor.Negotiate] returns
+// when the peers don't share a dictionary.
+const NoDictionary uint32 = 0
+
+var (
+	_ Compressor = (*SessionCompressor)(nil)
+
+	ErrAlreadyNegotiated = errors.New("dictionary already negotiated")
+)
+
+// SessionCompressor compresses the msgs of a connection with a dictionary that
+// both peers agreed on during their handshake. Each peer advertises the IDs of
+// its dictionaries, and both call Negotiate with the IDs advertised by the
+// other. The choice of dictionary only depends on which IDs are shared, so
+// both peers agree on it regardless of the order the IDs were advertised in.
+//
+// Msgs are compressed as by [NewDeflateCompressorWithDictionary], so they
+// don't identify their dictionary, and until Negotiate is called, no
+// dictionary is used. Sessions created with the same dictionaries share their
+// deflate state, so a SessionCompressor can be created per connection.
+//
+// SessionCompressor is safe for concurrent use.
+type SessionCompressor struct {
+	maxSize    int64
+	localDicts map[uint32][]byte
+
+	lock         sync.RWMutex
+	negotiated   bool
+	dictionaryID uint32
+	compressor   *deflateCompressor
+}
+
+// NewSessionCompressor returns a SessionCompressor that can use the
+// dictionaries in localDicts, keyed by their ID. [NoDictionary] can't be used
+// as an ID.
+func NewSessionCompressor(maxSize int64, localDicts map[uint32][]byte) (*SessionCompressor, error) {
+	if _, ok := localDicts[NoDictionary]; ok {
+		return nil, fmt.Errorf("%w: ID %d is reserved", ErrInvalidDictionary, NoDictionary)
+	}
+	compressor, err := newDeflateCompressor(maxSize, flate.DefaultCompression, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionCompressor{
+		maxSize:    maxSize,
+		localDicts: maps.Clone(localDicts),
+		compressor: compressor,
+	}, nil
+}
+
+// Negotiate selects the lowest of peerDictIDs that is also a local dictionary
+// and compresses the rest of the session with it. IDs that aren't known
+// locally are ignored, and if no dictionary is shared, [NoDictionary] is
+// returned and no dictionary is used.
+//
+// Msgs don't identify their dictionary, so the choice must not depend on
+// which peer is calling. Peers that want to steer the choice should assign
+// lower IDs to their preferred dictionaries.
+//
+// Changing dictionaries mid-session would desynchronize the peers, so once
+// Negotiate has succeeded, it can't be called again.
+func (s *SessionCompressor) Negotiate(peerDictIDs []uint32) (uint32, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.negotiated {
+		return NoDictionary, fmt.Errorf("%w: using %d", ErrAlreadyNegotiated, s.dictionaryID)
+	}
+
+	selectedID := NoDictionary
+	for _, id := range peerDictIDs {
+		if _, ok := s.localDicts[id]; ok && (selectedID == NoDictionary || id < selectedID) {
+			selectedID = id
+		}
+	}
+	if selectedID != NoDictionary {
+		compressor, err := newDeflateDictionaryCompressor(s.maxSize, s.localDicts[selectedID])
+		if err != nil {
+			return NoDictionary, err
+		}
+		s.compressor = compressor
+	}
+	s.negotiated = true
+	s.dictionaryID = selectedID
+	return selectedID, nil
+}
+
+// DictionaryID returns the ID of the dictionary in use, or [NoDictionary].
+func (s *SessionCompressor) DictionaryID() uint32 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.dictionaryID
+}
+
+func (s *SessionCompressor) Compress(msg []byte) ([]byte, error) {
+	return s.current().Compress(msg)
+}
+
+func (s *SessionCompressor) Decompress(msg []byte) ([]byte, error) {
+	return s.current().Decompress(msg)
+}
+
+func (s *SessionCompressor) current() *deflateCompressor {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.compressor
+}
@@ -0,0 +1,155 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionCompressorNegotiate(t *testing.T) {
+	localDicts := map[uint32][]byte{
+		1: testDictionary,
+		2: otherTestDictionary,
+	}
+	tests := []struct {
+		name         string
+		peerDictIDs  []uint32
+		expectedID   uint32
+		expectedDict []byte
+	}{
+		{
+			name:         "shared dictionary",
+			peerDictIDs:  []uint32{1},
+			expectedID:   1,
+			expectedDict: testDictionary,
+		},
+		{
+			name:         "lowest shared ID",
+			peerDictIDs:  []uint32{2, 1},
+			expectedID:   1,
+			expectedDict: testDictionary,
+		},
+		{
+			name:         "unknown ID is skipped",
+			peerDictIDs:  []uint32{3, 1},
+			expectedID:   1,
+			expectedDict: testDictionary,
+		},
+		{
+			name:        "only unknown IDs",
+			peerDictIDs: []uint32{3, 4},
+			expectedID:  NoDictionary,
+		},
+		{
+			name:        "no IDs",
+			peerDictIDs: nil,
+			expectedID:  NoDictionary,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			session, err := NewSessionCompressor(maxMessageSize, localDicts)
+			require.NoError(err)
+
+			id, err := session.Negotiate(test.peerDictIDs)
+			require.NoError(err)
+			require.Equal(test.expectedID, id)
+			require.Equal(test.expectedID, session.DictionaryID())
+
+			// The session compresses exactly as a deflate compressor with the
+			// negotiated dictionary.
+			var expected Compressor
+			if test.expectedDict == nil {
+				expected, err = NewDeflateCompressor(maxMessageSize)
+			} else {
+				expected, err = NewDeflateCompressorWithDictionary(maxMessageSize, test.expectedDict)
+			}
+			require.NoError(err)
+			msg := newTestDictionaryMessage(0)
+			compressed, err := session.Compress(msg)
+			require.NoError(err)
+			expectedCompressed, err := expected.Compress(msg)
+			require.NoError(err)
+			require.Equal(expectedCompressed, compressed)
+
+			decompressed, err := session.Decompress(compressed)
+			require.NoError(err)
+			require.Equal(msg, decompressed)
+		})
+	}
+}
+
+func TestSessionCompressorHandshake(t *testing.T) {
+	require := require.New(t)
+
+	local, err := NewSessionCompressor(maxMessageSize, map[uint32][]byte{
+		1: testDictionary,
+		2: otherTestDictionary,
+	})
+	require.NoError(err)
+	peer, err := NewSessionCompressor(maxMessageSize, map[uint32][]byte{
+		2: otherTestDictionary,
+	})
+	require.NoError(err)
+
+	// Each side selects from the IDs advertised by the other.
+	localID, err := local.Negotiate([]uint32{2})
+	require.NoError(err)
+	peerID, err := peer.Negotiate([]uint32{1, 2})
+	require.NoError(err)
+	require.Equal(localID, peerID)
+
+	msg := newTestDictionaryMessage(0)
+	compressed, err := local.Compress(msg)
+	require.NoError(err)
+	decompressed, err := peer.Decompress(compressed)
+	require.NoError(err)
+	require.Equal(msg, decompressed)
+
+	// The dictionary can't change mid-session.
+	_, err = local.Negotiate([]uint32{1})
+	require.ErrorIs(err, ErrAlreadyNegotiated)
+	require.Equal(uint32(2), local.DictionaryID())
+}
+
+func TestSessionCompressorHandshakeOppositeOrders(t *testing.T) {
+	require := require.New(t)
+
+	dicts := map[uint32][]byte{
+		1: testDictionary,
+		2: otherTestDictionary,
+	}
+	local, err := NewSessionCompressor(maxMessageSize, dicts)
+	require.NoError(err)
+	peer, err := NewSessionCompressor(maxMessageSize, dicts)
+	require.NoError(err)
+
+	// Both dictionaries are shared, but advertised in opposite orders.
+	localID, err := local.Negotiate([]uint32{2, 1})
+	require.NoError(err)
+	peerID, err := peer.Negotiate([]uint32{1, 2})
+	require.NoError(err)
+	require.Equal(uint32(1), localID)
+	require.Equal(localID, peerID)
+
+	for _, sessions := range [][2]*SessionCompressor{{local, peer}, {peer, local}} {
+		msg := newTestDictionaryMessage(0)
+		compressed, err := sessions[0].Compress(msg)
+		require.NoError(err)
+		decompressed, err := sessions[1].Decompress(compressed)
+		require.NoError(err)
+		require.Equal(msg, decompressed)
+	}
+}
+
+func TestNewSessionCompressorReservedID(t *testing.T) {
+	_, err := NewSessionCompressor(maxMessageSize, map[uint32][]byte{
+		NoDictionary: testDictionary,
+	})
+	require.ErrorIs(t, err, ErrInvalidDictionary)
+}
@@ -0,0 +1,111 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+)
+
+var (
+	_ Compressor = (*sizePrefixedCompressor)(nil)
+	_ Compressor = (*zstdSizePrefixedCompressor)(nil)
+
+	// zstdMagicLength is the length that a prefix starting with the zstd frame
+	// magic number would declare.
+	zstdMagicLength = binary.BigEndian.Uint32(binary.LittleEndian.AppendUint32(nil, zstdFrameMagic))
+)
+
+// NewSizePrefixedCompressor returns a Compressor that prefixes messages
+// compressed by compressor with their uncompressed length, encoded as a
+// big-endian uint32. Decompress allocates the output buffer once, with the
+// declared length, rather than growing it while decompressing.
+//
+// Declared lengths larger than maxSize are rejected before any allocation is
+// made, and messages that don't decompress to their declared length are
+// rejected with [ErrInvalidFormat].
+func NewSizePrefixedCompressor(compressor AppendCompressor, maxSize int64) (Compressor, error) {
+	if maxSize > math.MaxUint32 {
+		// Larger lengths can't be encoded in the prefix.
+		return nil, ErrInvalidMaxSizeCompressor
+	}
+	return &sizePrefixedCompressor{
+		compressor: compressor,
+		maxSize:    maxSize,
+	}, nil
+}
+
+type sizePrefixedCompressor struct {
+	compressor AppendCompressor
+	maxSize    int64
+}
+
+func (s *sizePrefixedCompressor) Compress(msg []byte) ([]byte, error) {
+	if int64(len(msg)) > s.maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrMsgTooLarge, len(msg), s.maxSize)
+	}
+	dst := binary.BigEndian.AppendUint32(nil, uint32(len(msg)))
+	return s.compressor.AppendCompress(dst, msg)
+}
+
+func (s *sizePrefixedCompressor) Decompress(msg []byte) ([]byte, error) {
+	if len(msg) < wrappers.IntLen {
+		return nil, fmt.Errorf("%w: missing length prefix", ErrInvalidFormat)
+	}
+	declaredLen := binary.BigEndian.Uint32(msg)
+	if int64(declaredLen) > s.maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrDecompressedMsgTooLarge, declaredLen, s.maxSize)
+	}
+
+	decompressed, err := s.compressor.AppendDecompress(make([]byte, 0, declaredLen), msg[wrappers.IntLen:])
+	if err != nil {
+		return nil, err
+	}
+	if len(decompressed) != int(declaredLen) {
+		return nil, fmt.Errorf("%w: decompressed length %d != declared length %d", ErrInvalidFormat, len(decompressed), declaredLen)
+	}
+	return decompressed, nil
+}
+
+// NewZstdSizePrefixedCompressor returns a size prefixed zstd Compressor, as
+// returned by [NewSizePrefixedCompressor], whose Decompress also accepts bare
+// zstd frames, as produced by [NewZstdCompressor]. This allows peers to
+// migrate to size prefixed messages while still accepting messages from peers
+// that haven't.
+//
+// Bare frames are recognized by the zstd magic number. A size prefix can only
+// start with the same bytes if it declares a length of 0x28B52FFD bytes, so
+// maxSize must be less than that for every msg to be unambiguous.
+func NewZstdSizePrefixedCompressor(maxSize int64) (Compressor, error) {
+	if maxSize >= int64(zstdMagicLength) {
+		return nil, fmt.Errorf("%w: (%d) >= (%d)", ErrInvalidMaxSizeCompressor, maxSize, zstdMagicLength)
+	}
+	zstdCompressor, err := NewZstdCompressor(maxSize)
+	if err != nil {
+		return nil, err
+	}
+	prefixed, err := NewSizePrefixedCompressor(zstdCompressor.(AppendCompressor), maxSize)
+	if err != nil {
+		return nil, err
+	}
+	return &zstdSizePrefixedCompressor{
+		Compressor: prefixed,
+		bare:       zstdCompressor,
+	}, nil
+}
+
+type zstdSizePrefixedCompressor struct {
+	Compressor
+	bare Compressor
+}
+
+func (z *zstdSizePrefixedCompressor) Decompress(msg []byte) ([]byte, error) {
+	if isZstdFrame(msg) {
+		return z.bare.Decompress(msg)
+	}
+	return z.Compressor.Decompress(msg)
+}
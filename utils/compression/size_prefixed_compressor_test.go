@@ -0,0 +1,123 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestSizePrefixedCompressor(t *testing.T) {
+	for name, newCompressorFunc := range newCompressorFuncs {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			inner, err := newCompressorFunc(maxMessageSize)
+			require.NoError(err)
+			compressor, err := NewSizePrefixedCompressor(inner.(AppendCompressor), maxMessageSize)
+			require.NoError(err)
+
+			msg := bytes.Repeat([]byte("avalanche"), units.KiB)
+			compressed, err := compressor.Compress(msg)
+			require.NoError(err)
+			require.Equal(uint32(len(msg)), binary.BigEndian.Uint32(compressed))
+
+			decompressed, err := compressor.Decompress(compressed)
+			require.NoError(err)
+			require.Equal(msg, decompressed)
+			require.Equal(len(msg), cap(decompressed))
+		})
+	}
+}
+
+func TestSizePrefixedCompressorInvalid(t *testing.T) {
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(t, err)
+	compressor, err := NewSizePrefixedCompressor(zstdCompressor.(AppendCompressor), maxMessageSize)
+	require.NoError(t, err)
+
+	msg := bytes.Repeat([]byte("avalanche"), units.KiB)
+	compressed, err := compressor.Compress(msg)
+	require.NoError(t, err)
+
+	withDeclaredLen := func(declaredLen uint32) []byte {
+		modified := bytes.Clone(compressed)
+		binary.BigEndian.PutUint32(modified, declaredLen)
+		return modified
+	}
+
+	tests := []struct {
+		name        string
+		msg         []byte
+		expectedErr error
+	}{
+		{
+			name:        "missing prefix",
+			msg:         compressed[:3],
+			expectedErr: ErrInvalidFormat,
+		},
+		{
+			name:        "declared length too large",
+			msg:         withDeclaredLen(maxMessageSize + 1),
+			expectedErr: ErrDecompressedMsgTooLarge,
+		},
+		{
+			name:        "declared length too small",
+			msg:         withDeclaredLen(uint32(len(msg) - 1)),
+			expectedErr: ErrInvalidFormat,
+		},
+		{
+			name:        "declared length too long",
+			msg:         withDeclaredLen(uint32(len(msg) + 1)),
+			expectedErr: ErrInvalidFormat,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := compressor.Decompress(test.msg)
+			require.ErrorIs(t, err, test.expectedErr)
+		})
+	}
+}
+
+func TestNewSizePrefixedCompressorMaxSize(t *testing.T) {
+	_, err := NewSizePrefixedCompressor(&noCompressor{}, math.MaxUint32+1)
+	require.ErrorIs(t, err, ErrInvalidMaxSizeCompressor)
+}
+
+func TestZstdSizePrefixedCompressor(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewZstdSizePrefixedCompressor(maxMessageSize)
+	require.NoError(err)
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+
+	msg := newTestDictionaryMessage(0)
+	prefixed, err := compressor.Compress(msg)
+	require.NoError(err)
+	require.False(isZstdFrame(prefixed))
+	bare, err := zstdCompressor.Compress(msg)
+	require.NoError(err)
+
+	for _, compressed := range [][]byte{prefixed, bare} {
+		decompressed, err := compressor.Decompress(compressed)
+		require.NoError(err)
+		require.Equal(msg, decompressed)
+	}
+}
+
+func TestNewZstdSizePrefixedCompressorMaxSize(t *testing.T) {
+	_, err := NewZstdSizePrefixedCompressor(0x28B52FFD)
+	require.ErrorIs(t, err, ErrInvalidMaxSizeCompressor)
+
+	_, err = NewZstdSizePrefixedCompressor(0x28B52FFD - 1)
+	require.NoError(t, err)
+}
@@ -0,0 +1,54 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import "fmt"
+
+var _ Compressor = (*skipCompressedCompressor)(nil)
+
+// NewSkipCompressedCompressor returns a Compressor that doesn't spend time
+// compressing msgs for which detect returns true, such as msgs that are already
+// compressed. Those msgs are sent as is, prefixed with the same flag byte as
+// [NewAutoCompressor] uses, and all other msgs are compressed with compressor.
+//
+// [DetectAlgorithm] can be used to skip msgs that are already zstd, snappy or
+// s2 compressed:
+//
+//	func(msg []byte) bool {
+//		_, ok := DetectAlgorithm(msg)
+//		return ok
+//	}
+//
+// If compressor implements [ConfigReporter], skipped msgs are still limited to
+// its max size.
+func NewSkipCompressedCompressor(compressor Compressor, detect func([]byte) bool) Compressor {
+	return &skipCompressedCompressor{
+		compressor: compressor,
+		detect:     detect,
+	}
+}
+
+type skipCompressedCompressor struct {
+	compressor Compressor
+	detect     func([]byte) bool
+}
+
+func (s *skipCompressedCompressor) Compress(msg []byte) ([]byte, error) {
+	if !s.detect(msg) {
+		compressed, err := s.compressor.Compress(msg)
+		if err != nil {
+			return nil, err
+		}
+		return withFlag(compressedFlag, compressed), nil
+	}
+
+	if maxSize, ok := reportedMaxSize(s.compressor); ok && int64(len(msg)) > maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrMsgTooLarge, len(msg), maxSize)
+	}
+	return withFlag(rawFlag, msg), nil
+}
+
+func (s *skipCompressedCompressor) Decompress(msg []byte) ([]byte, error) {
+	return decompressFlagged(s.compressor, msg)
+}
@@ -0,0 +1,78 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestSkipCompressedCompressor(t *testing.T) {
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(t, err)
+
+	alreadyCompressed, err := zstdCompressor.Compress(bytes.Repeat([]byte("avalanche"), units.KiB))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		msg          []byte
+		expectedFlag byte
+	}{
+		{
+			name:         "already compressed",
+			msg:          alreadyCompressed,
+			expectedFlag: rawFlag,
+		},
+		{
+			name:         "uncompressed",
+			msg:          bytes.Repeat([]byte("avalanche"), units.KiB),
+			expectedFlag: compressedFlag,
+		},
+		{
+			name:         "empty",
+			msg:          []byte{},
+			expectedFlag: compressedFlag,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			var detected int
+			compressor := NewSkipCompressedCompressor(zstdCompressor, func(msg []byte) bool {
+				detected++
+				_, ok := DetectAlgorithm(msg)
+				return ok
+			})
+
+			compressed, err := compressor.Compress(test.msg)
+			require.NoError(err)
+			require.Equal(1, detected)
+			require.Equal(test.expectedFlag, compressed[0])
+			if test.expectedFlag == rawFlag {
+				require.Equal(test.msg, compressed[1:])
+			}
+
+			decompressed, err := compressor.Decompress(compressed)
+			require.NoError(err)
+			require.Equal(test.msg, decompressed)
+		})
+	}
+}
+
+func TestSkipCompressedCompressorMaxSize(t *testing.T) {
+	zstdCompressor, err := NewZstdCompressor(units.KiB)
+	require.NoError(t, err)
+	compressor := NewSkipCompressedCompressor(zstdCompressor, func([]byte) bool {
+		return true
+	})
+
+	_, err = compressor.Compress(make([]byte, units.KiB+1))
+	require.ErrorIs(t, err, ErrMsgTooLarge)
+}
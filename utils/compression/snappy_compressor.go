@@ -0,0 +1,132 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+
+	"github.com/golang/snappy"
+)
+
+var (
+	_ Compressor       = (*snappyCompressor)(nil)
+	_ StreamCompressor = (*snappyCompressor)(nil)
+	_ AppendCompressor = (*snappyCompressor)(nil)
+	_ SizeEstimator    = (*snappyCompressor)(nil)
+	_ ConfigReporter   = (*snappyCompressor)(nil)
+)
+
+// NewSnappyCompressor returns a Compressor that uses the snappy block format.
+//
+// Snappy blocks do not start with a magic number, so callers that may receive
+// payloads in multiple formats must tag snappy payloads out of band. Streams
+// use the snappy framing format, which does start with a stream identifier.
+func NewSnappyCompressor(maxSize int64) (Compressor, error) {
+	if maxSize > math.MaxUint32 {
+		// The snappy block format encodes the decompressed length as a
+		// uint32, so larger messages can't be represented.
+		return nil, ErrInvalidMaxSizeCompressor
+	}
+
+	return &snappyCompressor{
+		maxSize: maxSize,
+	}, nil
+}
+
+type snappyCompressor struct {
+	maxSize int64
+}
+
+func (s *snappyCompressor) Compress(msg []byte) ([]byte, error) {
+	if int64(len(msg)) > s.maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrMsgTooLarge, len(msg), s.maxSize)
+	}
+	return snappy.Encode(nil, msg), nil
+}
+
+func (s *snappyCompressor) Decompress(msg []byte) ([]byte, error) {
+	// The decompressed length is written in the block header, so oversized
+	// payloads can be rejected before any allocation is made.
+	decompressedLen, err := snappy.DecodedLen(msg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+	if int64(decompressedLen) > s.maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrDecompressedMsgTooLarge, decompressedLen, s.maxSize)
+	}
+	decompressed, err := snappy.Decode(nil, msg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+	return decompressed, nil
+}
+
+func (s *snappyCompressor) CompressStream(dst io.Writer, src io.Reader) error {
+	writer := snappy.NewBufferedWriter(dst)
+	exceeded, err := copyLimited(writer, src, s.maxSize)
+	if err != nil {
+		_ = writer.Close()
+		return err
+	}
+	if exceeded {
+		_ = writer.Close()
+		return fmt.Errorf("%w: (> %d)", ErrMsgTooLarge, s.maxSize)
+	}
+	return writer.Close()
+}
+
+func (s *snappyCompressor) DecompressStream(dst io.Writer, src io.Reader) error {
+	source := &sourceReader{reader: src}
+	exceeded, err := copyLimited(dst, snappy.NewReader(source), s.maxSize)
+	if err != nil {
+		return source.wrapErr(err)
+	}
+	if exceeded {
+		return fmt.Errorf("%w: (> %d)", ErrDecompressedMsgTooLarge, s.maxSize)
+	}
+	return nil
+}
+
+func (s *snappyCompressor) AppendCompress(dst, msg []byte) ([]byte, error) {
+	if int64(len(msg)) > s.maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrMsgTooLarge, len(msg), s.maxSize)
+	}
+
+	// snappy only writes into the provided buffer if its length is at least
+	// the worst case encoded size.
+	dst = slices.Grow(dst, snappy.MaxEncodedLen(len(msg)))
+	compressed := snappy.Encode(dst[len(dst):cap(dst)], msg)
+	return dst[:len(dst)+len(compressed)], nil
+}
+
+func (s *snappyCompressor) AppendDecompress(dst, msg []byte) ([]byte, error) {
+	decompressedLen, err := snappy.DecodedLen(msg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+	if int64(decompressedLen) > s.maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrDecompressedMsgTooLarge, decompressedLen, s.maxSize)
+	}
+
+	dst = slices.Grow(dst, decompressedLen)
+	decompressed, err := snappy.Decode(dst[len(dst):cap(dst)], msg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+	return dst[:len(dst)+len(decompressed)], nil
+}
+
+func (s *snappyCompressor) Config() CompressorConfig {
+	return CompressorConfig{
+		MaxSize: s.maxSize,
+	}
+}
+
+// EstimateCompressedSize returns -1 if msg is too large to be encoded.
+func (*snappyCompressor) EstimateCompressedSize(msg []byte) int {
+	return snappy.MaxEncodedLen(len(msg))
+}
@@ -0,0 +1,63 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestSnappyCompressorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  []byte
+	}{
+		{
+			name: "random",
+			msg:  utils.RandomBytes(units.KiB),
+		},
+		{
+			name: "repetitive",
+			msg:  bytes.Repeat([]byte("avalanche"), units.KiB),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := NewSnappyCompressor(maxMessageSize)
+			require.NoError(err)
+
+			compressed, err := compressor.Compress(test.msg)
+			require.NoError(err)
+
+			decompressed, err := compressor.Decompress(compressed)
+			require.NoError(err)
+			require.Equal(test.msg, decompressed)
+		})
+	}
+}
+
+func TestSnappyCompressorRepetitiveInputShrinks(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewSnappyCompressor(maxMessageSize)
+	require.NoError(err)
+
+	msg := bytes.Repeat([]byte("avalanche"), units.KiB)
+	compressed, err := compressor.Compress(msg)
+	require.NoError(err)
+	require.Less(len(compressed), len(msg)/10)
+}
+
+func TestNewSnappyCompressorMaxSize(t *testing.T) {
+	_, err := NewSnappyCompressor(math.MaxUint32 + 1)
+	require.ErrorIs(t, err, ErrInvalidMaxSizeCompressor)
+}
@@ -0,0 +1,82 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import "sync/atomic"
+
+var _ Compressor = (*StatsCompressor)(nil)
+
+// Stats are cumulative counters of the work done by a [StatsCompressor].
+// Calls are counted whether or not they succeed, bytes only on success.
+type Stats struct {
+	CompressCalls      uint64
+	CompressBytesIn    uint64
+	CompressBytesOut   uint64
+	DecompressCalls    uint64
+	DecompressBytesIn  uint64
+	DecompressBytesOut uint64
+}
+
+// Ratio returns the number of uncompressed bytes per compressed byte produced
+// by Compress. If nothing has been compressed, 0 is returned.
+func (s Stats) Ratio() float64 {
+	if s.CompressBytesOut == 0 {
+		return 0
+	}
+	return float64(s.CompressBytesIn) / float64(s.CompressBytesOut)
+}
+
+// StatsCompressor wraps a Compressor and counts the calls and bytes that pass
+// through it.
+type StatsCompressor struct {
+	compressor Compressor
+
+	compressCalls      atomic.Uint64
+	compressBytesIn    atomic.Uint64
+	compressBytesOut   atomic.Uint64
+	decompressCalls    atomic.Uint64
+	decompressBytesIn  atomic.Uint64
+	decompressBytesOut atomic.Uint64
+}
+
+func NewStatsCompressor(compressor Compressor) *StatsCompressor {
+	return &StatsCompressor{
+		compressor: compressor,
+	}
+}
+
+func (s *StatsCompressor) Compress(msg []byte) ([]byte, error) {
+	s.compressCalls.Add(1)
+	compressed, err := s.compressor.Compress(msg)
+	if err != nil {
+		return nil, err
+	}
+	s.compressBytesIn.Add(uint64(len(msg)))
+	s.compressBytesOut.Add(uint64(len(compressed)))
+	return compressed, nil
+}
+
+func (s *StatsCompressor) Decompress(msg []byte) ([]byte, error) {
+	s.decompressCalls.Add(1)
+	decompressed, err := s.compressor.Decompress(msg)
+	if err != nil {
+		return nil, err
+	}
+	s.decompressBytesIn.Add(uint64(len(msg)))
+	s.decompressBytesOut.Add(uint64(len(decompressed)))
+	return decompressed, nil
+}
+
+// Stats returns the current counters. Each counter is read atomically, but
+// the counters are not read as a single snapshot.
+func (s *StatsCompressor) Stats() Stats {
+	return Stats{
+		CompressCalls:      s.compressCalls.Load(),
+		CompressBytesIn:    s.compressBytesIn.Load(),
+		CompressBytesOut:   s.compressBytesOut.Load(),
+		DecompressCalls:    s.decompressCalls.Load(),
+		DecompressBytesIn:  s.decompressBytesIn.Load(),
+		DecompressBytesOut: s.decompressBytesOut.Load(),
+	}
+}
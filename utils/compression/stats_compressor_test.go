@@ -0,0 +1,52 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsCompressor(t *testing.T) {
+	require := require.New(t)
+
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	compressor := NewStatsCompressor(zstdCompressor)
+	require.Zero(compressor.Stats().Ratio())
+
+	var (
+		msgs = [][]byte{
+			bytes.Repeat([]byte{1}, 1024),
+			bytes.Repeat([]byte{2}, 2048),
+		}
+		expected Stats
+	)
+	for _, msg := range msgs {
+		compressed, err := compressor.Compress(msg)
+		require.NoError(err)
+
+		decompressed, err := compressor.Decompress(compressed)
+		require.NoError(err)
+		require.Equal(msg, decompressed)
+
+		expected.CompressCalls++
+		expected.CompressBytesIn += uint64(len(msg))
+		expected.CompressBytesOut += uint64(len(compressed))
+		expected.DecompressCalls++
+		expected.DecompressBytesIn += uint64(len(compressed))
+		expected.DecompressBytesOut += uint64(len(msg))
+	}
+
+	// Failed calls are counted, but their bytes are not.
+	_, err = compressor.Compress(make([]byte, maxMessageSize+1))
+	require.ErrorIs(err, ErrMsgTooLarge)
+	expected.CompressCalls++
+
+	stats := compressor.Stats()
+	require.Equal(expected, stats)
+	require.Greater(stats.Ratio(), 1.0)
+}
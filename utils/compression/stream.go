@@ -0,0 +1,92 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+var ErrClosed = errors.New("closed")
+
+// CompressReader compresses everything read from r using the stream format of
+// c and returns the compressed bytes. r is consumed incrementally, so callers
+// don't need to buffer the uncompressed input.
+func CompressReader(c StreamCompressor, r io.Reader) ([]byte, error) {
+	var compressed bytes.Buffer
+	if err := c.CompressStream(&compressed, r); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+// DecompressDecode decompresses msg, which must be in the stream format of c,
+// and passes the decompressed stream to decode, so that structured msgs can be
+// decoded without buffering their full plaintext. For zstd and deflate, the
+// stream format is the format produced by their [Compressor].
+//
+// Whatever decode doesn't read is still decompressed, so that a truncated or
+// invalid msg is reported even if decode stops reading early. If decode
+// errors, its error is returned.
+func DecompressDecode(c StreamCompressor, msg []byte, decode func(io.Reader) error) error {
+	var (
+		reader, writer = io.Pipe()
+		decompressErr  = make(chan error, 1)
+	)
+	go func() {
+		err := c.DecompressStream(writer, bytes.NewReader(msg))
+		// Closing with a nil error reports [io.EOF] to the reader.
+		_ = writer.CloseWithError(err)
+		decompressErr <- err
+	}()
+
+	if err := decode(reader); err != nil {
+		// Unblock the decompression if it is waiting for decode to read.
+		_ = reader.CloseWithError(err)
+		<-decompressErr
+		return err
+	}
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		<-decompressErr
+		return err
+	}
+	return <-decompressErr
+}
+
+// DecompressPartial decompresses msg, which must be in the stream format of c.
+// Unlike Decompress, which is all or nothing, if decompression fails the
+// output produced before the failure is returned along with the error, so
+// that the valid prefix of a corrupted msg can be salvaged for forensics.
+//
+// The prefix is only as fine grained as the blocks of the format, and a
+// corrupted block may produce output before the corruption is detected, so
+// the returned prefix must not be trusted.
+func DecompressPartial(c StreamCompressor, msg []byte) ([]byte, error) {
+	var decompressed bytes.Buffer
+	err := c.DecompressStream(&decompressed, bytes.NewReader(msg))
+	return decompressed.Bytes(), err
+}
+
+// DecompressToWriter decompresses msg, which must be in the stream format of
+// c, directly to w and returns the number of bytes written. The output is
+// written in bounded pieces as it is produced, so the full decompressed msg is
+// never held in memory. Flushing w, if it buffers, is left to the caller.
+func DecompressToWriter(c StreamCompressor, w io.Writer, msg []byte) (int64, error) {
+	counter := &countingWriter{writer: w}
+	err := c.DecompressStream(counter, bytes.NewReader(msg))
+	return counter.written, err
+}
+
+// countingWriter counts the bytes written to an underlying writer.
+type countingWriter struct {
+	writer  io.Writer
+	written int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.writer.Write(p)
+	c.written += int64(n)
+	return n, err
+}
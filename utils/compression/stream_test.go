@@ -0,0 +1,214 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestCompressReader(t *testing.T) {
+	msg := bytes.Repeat([]byte("avalanche"), 100*units.KiB)
+	for name, newCompressorFunc := range newCompressorFuncs {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := newCompressorFunc(maxMessageSize)
+			require.NoError(err)
+			streamCompressor := compressor.(StreamCompressor)
+
+			// The source only returns a single byte per read.
+			compressed, err := CompressReader(streamCompressor, iotest.OneByteReader(bytes.NewReader(msg)))
+			require.NoError(err)
+
+			var decompressed bytes.Buffer
+			require.NoError(streamCompressor.DecompressStream(&decompressed, bytes.NewReader(compressed)))
+			require.Equal(msg, decompressed.Bytes())
+		})
+	}
+}
+
+func TestCompressReaderTooLarge(t *testing.T) {
+	compressor, err := NewZstdCompressor(units.KiB)
+	require.NoError(t, err)
+
+	_, err = CompressReader(compressor.(StreamCompressor), bytes.NewReader(make([]byte, units.KiB+1)))
+	require.ErrorIs(t, err, ErrMsgTooLarge)
+}
+
+func TestDecompressDecode(t *testing.T) {
+	require := require.New(t)
+
+	type block struct {
+		Height   uint64 `json:"height"`
+		ParentID string `json:"parentID"`
+	}
+	blocks := make([]block, 10_000)
+	for i := range blocks {
+		blocks[i] = block{
+			Height:   uint64(i),
+			ParentID: fmt.Sprintf("%x", i*i),
+		}
+	}
+	msg, err := json.Marshal(blocks)
+	require.NoError(err)
+
+	compressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	compressed, err := compressor.Compress(msg)
+	require.NoError(err)
+
+	var decoded []block
+	require.NoError(DecompressDecode(compressor.(StreamCompressor), compressed, func(r io.Reader) error {
+		return json.NewDecoder(r).Decode(&decoded)
+	}))
+	require.Equal(blocks, decoded)
+}
+
+func TestDecompressDecodeErrors(t *testing.T) {
+	compressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(t, err)
+	compressed, err := compressor.Compress(utils.RandomBytes(units.MiB))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		msg         []byte
+		decode      func(io.Reader) error
+		expectedErr error
+	}{
+		{
+			name: "decode error",
+			msg:  compressed,
+			decode: func(io.Reader) error {
+				return errTest
+			},
+			expectedErr: errTest,
+		},
+		{
+			name: "truncated without reading",
+			msg:  compressed[:len(compressed)-1],
+			decode: func(io.Reader) error {
+				return nil
+			},
+			expectedErr: ErrTruncatedStream,
+		},
+		{
+			name: "truncated while reading",
+			msg:  compressed[:len(compressed)-1],
+			decode: func(r io.Reader) error {
+				_, err := io.Copy(io.Discard, r)
+				return err
+			},
+			expectedErr: ErrTruncatedStream,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := DecompressDecode(compressor.(StreamCompressor), test.msg, test.decode)
+			require.ErrorIs(t, err, test.expectedErr)
+		})
+	}
+}
+
+func TestDecompressPartial(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	msg := utils.RandomBytes(units.MiB)
+	compressed, err := compressor.Compress(msg)
+	require.NoError(err)
+
+	// A valid frame followed by garbage.
+	corrupted := append(compressed, 0xff, 0xff, 0xff, 0xff, 0xff)
+
+	decompressed, err := DecompressPartial(compressor.(StreamCompressor), corrupted)
+	require.ErrorIs(err, ErrInvalidFormat)
+	require.Equal(msg, decompressed)
+
+	// Decompress is all or nothing.
+	decompressed, err = compressor.Decompress(corrupted)
+	require.ErrorIs(err, ErrInvalidFormat)
+	require.Nil(decompressed)
+
+	decompressed, err = DecompressPartial(compressor.(StreamCompressor), compressed)
+	require.NoError(err)
+	require.Equal(msg, decompressed)
+}
+
+// maxWriteRecorder records the largest write made to it.
+type maxWriteRecorder struct {
+	bytes.Buffer
+	maxWrite int
+}
+
+func (m *maxWriteRecorder) Write(p []byte) (int, error) {
+	m.maxWrite = max(m.maxWrite, len(p))
+	return m.Buffer.Write(p)
+}
+
+// maxReadRecorder records the largest read from the underlying reader. It
+// doesn't implement [io.WriterTo], so [io.Copy] must read from it.
+func TestDecompressToWriter(t *testing.T) {
+	msg := bytes.Repeat([]byte("avalanche"), units.MiB/8)
+	for _, name := range []string{"zstd", "zstd_pooled", "zstd_go", "deflate"} {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			compressor, err := newCompressorFuncs[name](maxMessageSize)
+			require.NoError(err)
+			compressed, err := compressor.Compress(msg)
+			require.NoError(err)
+			expected, err := compressor.Decompress(compressed)
+			require.NoError(err)
+
+			var (
+				dst      = &maxWriteRecorder{}
+				buffered = bufio.NewWriter(dst)
+			)
+			written, err := DecompressToWriter(compressor.(StreamCompressor), buffered, compressed)
+			require.NoError(err)
+			require.NoError(buffered.Flush())
+			require.Equal(int64(len(expected)), written)
+			require.Equal(expected, dst.Bytes())
+
+			// The output reaches dst in pieces rather than as one write.
+			require.Less(dst.maxWrite, len(msg))
+		})
+	}
+}
+
+func TestDecompressToWriterTooLarge(t *testing.T) {
+	require := require.New(t)
+
+	compressor, err := NewZstdCompressor(2 * units.KiB)
+	require.NoError(err)
+	compressed, err := compressor.Compress(make([]byte, 2*units.KiB))
+	require.NoError(err)
+
+	small, err := NewZstdCompressor(units.KiB)
+	require.NoError(err)
+	written, err := DecompressToWriter(small.(StreamCompressor), io.Discard, compressed)
+	require.ErrorIs(err, ErrDecompressedMsgTooLarge)
+	require.Equal(int64(units.KiB), written)
+}
+
+type errReader struct {
+	err error
+}
+
+func (e errReader) Read([]byte) (int, error) {
+	return 0, e.err
+}
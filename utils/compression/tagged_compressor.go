@@ -0,0 +1,167 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+)
+
+var (
+	_ Compressor = (*taggedCompressor)(nil)
+
+	ErrUnknownTag         = errors.New("unknown compressor tag")
+	ErrUnsupportedVersion = errors.New("unsupported tagged format version")
+)
+
+// taggedVersion is the version of the tagged format that is written. Each
+// version of the format is:
+//
+//	Version | Format
+//	--------|----------------------------------------
+//	1       | version, algorithm id, compressed msg
+//
+// To change the format, a new version is added and decoding of both versions
+// is supported until every node writes the new version. Only then is the
+// written version bumped.
+const taggedVersion byte = 1
+
+// NewTaggedCompressor returns a Compressor that prefixes messages compressed
+// by compressor with the version of the tagged format and id, so that the
+// algorithm can be identified by a [TaggedDecompressor]. Messages with a
+// version that isn't supported are rejected with [ErrUnsupportedVersion].
+//
+// The meaning of id is up to the caller, which must assign each algorithm a
+// distinct id and keep the assignment stable across releases.
+func NewTaggedCompressor(compressor Compressor, id byte) Compressor {
+	return &taggedCompressor{
+		compressor: compressor,
+		id:         id,
+	}
+}
+
+type taggedCompressor struct {
+	compressor Compressor
+	id         byte
+}
+
+func (t *taggedCompressor) Compress(msg []byte) ([]byte, error) {
+	compressed, err := t.compressor.Compress(msg)
+	if err != nil {
+		return nil, err
+	}
+	tagged := make([]byte, 2+len(compressed))
+	tagged[0] = taggedVersion
+	tagged[1] = t.id
+	copy(tagged[2:], compressed)
+	return tagged, nil
+}
+
+func (t *taggedCompressor) Decompress(msg []byte) ([]byte, error) {
+	id, payload, err := splitTag(msg)
+	if err != nil {
+		return nil, err
+	}
+	if id != t.id {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownTag, id)
+	}
+	return t.compressor.Decompress(payload)
+}
+
+// TaggedDecompressor decompresses messages produced by tagged compressors by
+// dispatching on their tag.
+type TaggedDecompressor struct {
+	compressors map[byte]Compressor
+}
+
+// NewTaggedDecompressor returns a TaggedDecompressor that decompresses
+// messages tagged with a key of compressors using the corresponding
+// Compressor.
+//
+// Invariant: compressors must not be modified after being provided.
+func NewTaggedDecompressor(compressors map[byte]Compressor) *TaggedDecompressor {
+	return &TaggedDecompressor{
+		compressors: compressors,
+	}
+}
+
+// TaggedDecompress decompresses msg with the compressor registered for its
+// tag.
+func (t *TaggedDecompressor) TaggedDecompress(msg []byte) ([]byte, error) {
+	id, payload, err := splitTag(msg)
+	if err != nil {
+		return nil, err
+	}
+	compressor, ok := t.compressors[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownTag, id)
+	}
+	return compressor.Decompress(payload)
+}
+
+// DecompressTaggedSequence decompresses every entry of a sequence built with
+// [AppendTaggedSequence], dispatching each entry on its tag.
+func (t *TaggedDecompressor) DecompressTaggedSequence(msg []byte) ([][]byte, error) {
+	packer := wrappers.Packer{
+		Bytes: msg,
+	}
+	var msgs [][]byte
+	for packer.Offset < len(msg) {
+		id := packer.UnpackByte()
+		payload := packer.UnpackBytes()
+		if packer.Errored() {
+			return nil, fmt.Errorf("%w: entry %d: %w", ErrInvalidFormat, len(msgs), packer.Err)
+		}
+
+		compressor, ok := t.compressors[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: entry %d: %d", ErrUnknownTag, len(msgs), id)
+		}
+		decompressed, err := compressor.Decompress(payload)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", len(msgs), err)
+		}
+		msgs = append(msgs, decompressed)
+	}
+	return msgs, nil
+}
+
+// AppendTaggedSequence appends tagged, a message produced by a tagged
+// compressor, to sequence. Each entry of the sequence is its tag followed by
+// the length-prefixed compressed payload. The version of the tagged format is
+// checked, but isn't repeated in every entry.
+func AppendTaggedSequence(sequence, tagged []byte) ([]byte, error) {
+	id, payload, err := splitTag(tagged)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(payload)) > math.MaxUint32 {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrMsgTooLarge, len(payload), uint64(math.MaxUint32))
+	}
+
+	sequence = slices.Grow(sequence, wrappers.ByteLen+wrappers.IntLen+len(payload))
+	sequence = append(sequence, id)
+	sequence = binary.BigEndian.AppendUint32(sequence, uint32(len(payload)))
+	return append(sequence, payload...), nil
+}
+
+// splitTag returns the algorithm id and the compressed payload of a tagged
+// msg.
+func splitTag(msg []byte) (byte, []byte, error) {
+	if len(msg) == 0 {
+		return 0, nil, fmt.Errorf("%w: missing version", ErrInvalidFormat)
+	}
+	if version := msg[0]; version != taggedVersion {
+		return 0, nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+	if len(msg) == 1 {
+		return 0, nil, fmt.Errorf("%w: missing tag", ErrInvalidFormat)
+	}
+	return msg[1], msg[2:], nil
+}
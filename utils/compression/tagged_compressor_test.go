@@ -0,0 +1,220 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	zstdTag byte = iota + 1
+	snappyTag
+	unknownTag
+)
+
+func newTestTaggedCompressors(t *testing.T) map[byte]Compressor {
+	require := require.New(t)
+
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	snappyCompressor, err := NewSnappyCompressor(maxMessageSize)
+	require.NoError(err)
+
+	return map[byte]Compressor{
+		zstdTag:   zstdCompressor,
+		snappyTag: snappyCompressor,
+	}
+}
+
+func TestTaggedDecompress(t *testing.T) {
+	var (
+		compressors  = newTestTaggedCompressors(t)
+		decompressor = NewTaggedDecompressor(compressors)
+		msg          = bytes.Repeat([]byte("avalanche"), 1024)
+	)
+	for id, compressor := range compressors {
+		tagged := NewTaggedCompressor(compressor, id)
+
+		compressed, err := tagged.Compress(msg)
+		require.NoError(t, err)
+		require.Equal(t, taggedVersion, compressed[0])
+		require.Equal(t, id, compressed[1])
+
+		decompressed, err := decompressor.TaggedDecompress(compressed)
+		require.NoError(t, err)
+		require.Equal(t, msg, decompressed)
+
+		decompressed, err = tagged.Decompress(compressed)
+		require.NoError(t, err)
+		require.Equal(t, msg, decompressed)
+	}
+}
+
+func TestTaggedDecompressErrors(t *testing.T) {
+	var (
+		compressors  = newTestTaggedCompressors(t)
+		decompressor = NewTaggedDecompressor(compressors)
+	)
+
+	compressed, err := NewTaggedCompressor(compressors[zstdTag], unknownTag).Compress([]byte{1, 2, 3})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		msg         []byte
+		expectedErr error
+	}{
+		{
+			name:        "empty",
+			msg:         nil,
+			expectedErr: ErrInvalidFormat,
+		},
+		{
+			name:        "missing tag",
+			msg:         []byte{taggedVersion},
+			expectedErr: ErrInvalidFormat,
+		},
+		{
+			name:        "unknown tag",
+			msg:         compressed,
+			expectedErr: ErrUnknownTag,
+		},
+		{
+			name:        "future version",
+			msg:         append([]byte{taggedVersion + 1}, compressed[1:]...),
+			expectedErr: ErrUnsupportedVersion,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := decompressor.TaggedDecompress(test.msg)
+			require.ErrorIs(t, err, test.expectedErr)
+		})
+	}
+}
+
+func TestTaggedDecompressVersions(t *testing.T) {
+	var (
+		compressors  = newTestTaggedCompressors(t)
+		decompressor = NewTaggedDecompressor(compressors)
+		msg          = bytes.Repeat([]byte("avalanche"), 1024)
+	)
+	for id, compressor := range compressors {
+		payload, err := compressor.Compress(msg)
+		require.NoError(t, err)
+
+		// The current version is built by hand so that a change to the
+		// written format is caught.
+		current := append([]byte{taggedVersion, id}, payload...)
+		decompressed, err := decompressor.TaggedDecompress(current)
+		require.NoError(t, err)
+		require.Equal(t, msg, decompressed)
+
+		future := append([]byte{taggedVersion + 1, id}, payload...)
+		_, err = decompressor.TaggedDecompress(future)
+		require.ErrorIs(t, err, ErrUnsupportedVersion)
+		_, err = NewTaggedCompressor(compressor, id).Decompress(future)
+		require.ErrorIs(t, err, ErrUnsupportedVersion)
+	}
+}
+
+func TestTaggedCompressorRejectsOtherTags(t *testing.T) {
+	require := require.New(t)
+
+	compressors := newTestTaggedCompressors(t)
+	compressed, err := NewTaggedCompressor(compressors[snappyTag], snappyTag).Compress([]byte{1, 2, 3})
+	require.NoError(err)
+
+	_, err = NewTaggedCompressor(compressors[zstdTag], zstdTag).Decompress(compressed)
+	require.ErrorIs(err, ErrUnknownTag)
+}
+
+func TestDecompressTaggedSequence(t *testing.T) {
+	require := require.New(t)
+
+	const deflateTag = unknownTag + 1
+	compressors := newTestTaggedCompressors(t)
+	deflateCompressor, err := NewDeflateCompressor(maxMessageSize)
+	require.NoError(err)
+	compressors[deflateTag] = deflateCompressor
+	decompressor := NewTaggedDecompressor(compressors)
+
+	var (
+		msgs = [][]byte{
+			newTestDictionaryMessage(0),
+			{},
+			newTestDictionaryMessage(1),
+			newTestDictionaryMessage(2),
+		}
+		tags     = []byte{deflateTag, zstdTag, deflateTag, snappyTag}
+		sequence []byte
+	)
+	for i, msg := range msgs {
+		tagged, err := NewTaggedCompressor(compressors[tags[i]], tags[i]).Compress(msg)
+		require.NoError(err)
+		sequence, err = AppendTaggedSequence(sequence, tagged)
+		require.NoError(err)
+	}
+
+	decompressed, err := decompressor.DecompressTaggedSequence(sequence)
+	require.NoError(err)
+	require.Len(decompressed, len(msgs))
+	for i, msg := range msgs {
+		if len(msg) == 0 {
+			require.Empty(decompressed[i])
+		} else {
+			require.Equal(msg, decompressed[i])
+		}
+	}
+
+	empty, err := decompressor.DecompressTaggedSequence(nil)
+	require.NoError(err)
+	require.Empty(empty)
+}
+
+func TestDecompressTaggedSequenceErrors(t *testing.T) {
+	compressors := newTestTaggedCompressors(t)
+	decompressor := NewTaggedDecompressor(compressors)
+
+	tagged, err := NewTaggedCompressor(compressors[zstdTag], zstdTag).Compress(newTestDictionaryMessage(0))
+	require.NoError(t, err)
+	entry, err := AppendTaggedSequence(nil, tagged)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		sequence    []byte
+		expectedErr error
+	}{
+		{
+			name:        "missing length",
+			sequence:    append(bytes.Clone(entry), zstdTag, 0, 0),
+			expectedErr: ErrInvalidFormat,
+		},
+		{
+			name:        "length past the end",
+			sequence:    entry[:len(entry)-1],
+			expectedErr: ErrInvalidFormat,
+		},
+		{
+			name:        "max length",
+			sequence:    []byte{zstdTag, 0xff, 0xff, 0xff, 0xff},
+			expectedErr: ErrInvalidFormat,
+		},
+		{
+			name:        "unknown tag",
+			sequence:    append([]byte{unknownTag}, entry[1:]...),
+			expectedErr: ErrUnknownTag,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := decompressor.DecompressTaggedSequence(test.sequence)
+			require.ErrorIs(t, err, test.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"golang.org/x/time/rate"
+)
+
+var (
+	_ Compressor = (*throttledCompressor)(nil)
+
+	ErrInvalidRate = errors.New("invalid rate")
+)
+
+// NewThrottledCompressor returns a Compressor that limits the number of bytes
+// produced by Decompress to bytesPerSec, with bursts of up to one second.
+//
+// The decompressed size is only known once a message has been decompressed,
+// so each call is charged after the fact by blocking before it returns. This
+// bounds the sustained rate at which a hostile peer can make us expand
+// payloads. Compress isn't throttled.
+//
+// The wait can't be cancelled: Decompress blocks until the rate allows the
+// message, even if the caller has given up on it. The burst is capped at
+// [math.MaxInt] bytes.
+func NewThrottledCompressor(compressor Compressor, bytesPerSec int64) (Compressor, error) {
+	if bytesPerSec <= 0 {
+		return nil, fmt.Errorf("%w: %d <= 0", ErrInvalidRate, bytesPerSec)
+	}
+	return &throttledCompressor{
+		compressor: compressor,
+		limiter:    rate.NewLimiter(rate.Limit(bytesPerSec), int(min(bytesPerSec, math.MaxInt))),
+	}, nil
+}
+
+type throttledCompressor struct {
+	compressor Compressor
+	limiter    *rate.Limiter
+}
+
+func (t *throttledCompressor) Compress(msg []byte) ([]byte, error) {
+	return t.compressor.Compress(msg)
+}
+
+func (t *throttledCompressor) Decompress(msg []byte) ([]byte, error) {
+	decompressed, err := t.compressor.Decompress(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	// WaitN rejects requests larger than the burst, so large messages are
+	// charged one burst at a time. Decompress has no context, so the wait
+	// isn't cancellable.
+	for remaining := len(decompressed); remaining > 0; {
+		n := min(remaining, t.limiter.Burst())
+		if err := t.limiter.WaitN(context.Background(), n); err != nil {
+			return nil, err
+		}
+		remaining -= n
+	}
+	return decompressed, nil
+}
@@ -0,0 +1,61 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestThrottledCompressor(t *testing.T) {
+	require := require.New(t)
+
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+
+	const bytesPerSec = 10 * units.MiB
+	compressor, err := NewThrottledCompressor(zstdCompressor, bytesPerSec)
+	require.NoError(err)
+
+	msg := bytes.Repeat([]byte("avalanche"), units.MiB/8)
+	compressed, err := compressor.Compress(msg)
+	require.NoError(err)
+
+	// The first second of output is allowed as a burst, after which the
+	// remaining bytes are released at the configured rate.
+	const numMsgs = 15
+	start := time.Now()
+	for range numMsgs {
+		decompressed, err := compressor.Decompress(compressed)
+		require.NoError(err)
+		require.Equal(msg, decompressed)
+	}
+	elapsed := time.Since(start)
+
+	expected := time.Duration(numMsgs*len(msg)-bytesPerSec) * time.Second / bytesPerSec
+	require.GreaterOrEqual(elapsed, expected*9/10)
+}
+
+func TestThrottledCompressorDecompressError(t *testing.T) {
+	require := require.New(t)
+
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+
+	compressor, err := NewThrottledCompressor(zstdCompressor, units.KiB)
+	require.NoError(err)
+
+	_, err = compressor.Decompress([]byte{0xff, 0xff, 0xff, 0xff, 0xff})
+	require.ErrorIs(err, ErrInvalidFormat)
+}
+
+func TestNewThrottledCompressorInvalidRate(t *testing.T) {
+	_, err := NewThrottledCompressor(NewNoCompressor(), 0)
+	require.ErrorIs(t, err, ErrInvalidRate)
+}
@@ -0,0 +1,94 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	// zstdFrameMagic is the little-endian magic number that zstd frames start
+	// with.
+	zstdFrameMagic = 0xFD2FB528
+	// zstdDictionaryMagic is the little-endian magic number that formatted
+	// zstd dictionaries start with.
+	zstdDictionaryMagic = 0xEC30A437
+)
+
+// The range of zstd compression levels, which are the same as those of the
+// reference zstd library.
+const (
+	zstdBestSpeed          = 1
+	zstdDefaultCompression = 5
+	zstdBestCompression    = 20
+)
+
+var (
+	ErrInvalidMaxSizeCompressor = errors.New("invalid compressor max size")
+	ErrInvalidCompressionLevel  = errors.New("invalid compression level")
+	ErrDecompressedMsgTooLarge  = errors.New("decompressed msg too large")
+	ErrMsgTooLarge              = errors.New("msg too large to be compressed")
+	ErrInvalidFormat            = errors.New("invalid compressed format")
+	ErrInvalidDictionary        = errors.New("invalid dictionary")
+
+	errEmptyMsg = fmt.Errorf("%w: empty msg", ErrInvalidFormat)
+)
+
+// zstdImplementation is implemented by both the cgo and the pure Go zstd
+// Compressors.
+type zstdImplementation interface {
+	Compressor
+	StreamCompressor
+	AppendCompressor
+	SizeEstimator
+	WriterCompressor
+	ConfigReporter
+}
+
+// NewZstdCompressor returns a zstd Compressor that compresses with the default
+// level.
+//
+// When built with cgo, the Compressor uses the reference zstd library, which
+// is the fastest implementation. Otherwise, a pure Go implementation is used.
+// Both implementations produce and accept the same format, so they can be mixed
+// on a network, but the pure Go implementation is slower and its output isn't
+// byte for byte identical.
+//
+// The same applies to [NewPooledZstdCompressor],
+// [NewZstdCompressorWithDictionary] and the zstd stream types, such as
+// [CompressWriter].
+func NewZstdCompressor(maxSize int64) (Compressor, error) {
+	return NewZstdCompressorWithLevel(maxSize, zstdDefaultCompression)
+}
+
+// NewZstdCompressorWithLevel returns a zstd Compressor that compresses with
+// the provided level. The level must be in the range [1, 20]. See
+// [NewZstdCompressor] for which implementation is used.
+func NewZstdCompressorWithLevel(maxSize int64, level int) (Compressor, error) {
+	z, err := newDefaultZstdCompressor(maxSize, level)
+	if err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+// isZstdFrame returns true if msg starts with the zstd frame magic number.
+func isZstdFrame(msg []byte) bool {
+	return len(msg) >= 4 && binary.LittleEndian.Uint32(msg) == zstdFrameMagic
+}
+
+// zstdDictionaryID returns the ID of dict, which must be a formatted zstd
+// dictionary with a non-zero ID.
+func zstdDictionaryID(dict []byte) (uint32, error) {
+	if len(dict) < 8 || binary.LittleEndian.Uint32(dict) != zstdDictionaryMagic {
+		return 0, fmt.Errorf("%w: not a formatted zstd dictionary", ErrInvalidDictionary)
+	}
+	dictionaryID := binary.LittleEndian.Uint32(dict[4:])
+	if dictionaryID == 0 {
+		return 0, fmt.Errorf("%w: missing dictionary ID", ErrInvalidDictionary)
+	}
+	return dictionaryID, nil
+}
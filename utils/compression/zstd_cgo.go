@@ -0,0 +1,15 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build cgo
+// +build cgo
+
+package compression
+
+func newDefaultZstdCompressor(maxSize int64, level int) (zstdImplementation, error) {
+	z, err := newZstdCompressor(maxSize, level)
+	if err != nil {
+		return nil, err
+	}
+	return z, nil
+}
@@ -0,0 +1,75 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build cgo
+// +build cgo
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+// TestZstdInterop checks that the cgo and pure Go zstd implementations can
+// decompress each other's output.
+func TestZstdInterop(t *testing.T) {
+	cgoCompressor, err := newZstdCompressor(maxMessageSize, zstdDefaultCompression)
+	require.NoError(t, err)
+	goCompressor, err := newGoZstdCompressor(maxMessageSize, zstdDefaultCompression)
+	require.NoError(t, err)
+	testZstdInterop(t, cgoCompressor, goCompressor)
+}
+
+// TestZstdDictionaryInterop checks that the cgo and pure Go zstd
+// implementations can decompress each other's output when using a dictionary.
+func TestZstdDictionaryInterop(t *testing.T) {
+	cgoCompressor, err := NewZstdCompressorWithDictionary(maxMessageSize, testDictionary)
+	require.NoError(t, err)
+	goCompressor, err := newGoZstdDictionaryCompressor(maxMessageSize, zstdDefaultCompression, testDictionary)
+	require.NoError(t, err)
+	testZstdInterop(t, cgoCompressor, goCompressor)
+}
+
+func testZstdInterop(t *testing.T, cgoCompressor, goCompressor Compressor) {
+	implementations := map[string]Compressor{
+		"cgo": cgoCompressor,
+		"go":  goCompressor,
+	}
+	msgs := map[string][]byte{
+		"empty":            {},
+		"tiny":             {1},
+		"large repetitive": bytes.Repeat([]byte("avalanche"), 100*units.KiB),
+		"random":           utils.RandomBytes(units.MiB),
+		"dictionary":       newTestDictionaryMessage(0),
+	}
+	for from, compressor := range implementations {
+		for to, decompressor := range implementations {
+			for msgName, msg := range msgs {
+				t.Run(from+" to "+to+"/"+msgName, func(t *testing.T) {
+					require := require.New(t)
+
+					// Empty results are compared as non-nil slices, as the
+					// implementations differ in whether they return nil.
+
+					compressed, err := compressor.Compress(msg)
+					require.NoError(err)
+					decompressed, err := decompressor.Decompress(compressed)
+					require.NoError(err)
+					require.Equal(msg, append([]byte{}, decompressed...))
+
+					var stream bytes.Buffer
+					require.NoError(compressor.(StreamCompressor).CompressStream(&stream, bytes.NewReader(msg)))
+					var streamDecompressed bytes.Buffer
+					require.NoError(decompressor.(StreamCompressor).DecompressStream(&streamDecompressed, &stream))
+					require.Equal(msg, append([]byte{}, streamDecompressed.Bytes()...))
+				})
+			}
+		}
+	}
+}
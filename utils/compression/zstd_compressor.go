@@ -1,27 +1,34 @@
 // Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
 // See the file LICENSE for licensing terms.
 
+//go:build cgo
+// +build cgo
+
 package compression
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
 	"io"
 	"math"
+	"slices"
 
 	"github.com/DataDog/zstd"
 )
 
 var (
-	_ Compressor = (*zstdCompressor)(nil)
-
-	ErrInvalidMaxSizeCompressor = errors.New("invalid compressor max size")
-	ErrDecompressedMsgTooLarge  = errors.New("decompressed msg too large")
-	ErrMsgTooLarge              = errors.New("msg too large to be compressed")
+	_ Compressor       = (*zstdCompressor)(nil)
+	_ StreamCompressor = (*zstdCompressor)(nil)
+	_ AppendCompressor = (*zstdCompressor)(nil)
+	_ SizeEstimator    = (*zstdCompressor)(nil)
+	_ WriterCompressor = (*zstdCompressor)(nil)
+	_ ConfigReporter   = (*zstdCompressor)(nil)
 )
 
-func NewZstdCompressor(maxSize int64) (Compressor, error) {
+func newZstdCompressor(maxSize int64, level int) (*zstdCompressor, error) {
+	if level < zstd.BestSpeed || level > zstd.BestCompression {
+		return nil, fmt.Errorf("%w: %d not in [%d, %d]", ErrInvalidCompressionLevel, level, zstd.BestSpeed, zstd.BestCompression)
+	}
 	if maxSize == math.MaxInt64 {
 		// "Decompress" creates "io.LimitReader" with max size + 1:
 		// if the max size + 1 overflows, "io.LimitReader" reads nothing
@@ -32,21 +39,34 @@ func NewZstdCompressor(maxSize int64) (Compressor, error) {
 
 	return &zstdCompressor{
 		maxSize: maxSize,
+		level:   level,
 	}, nil
 }
 
 type zstdCompressor struct {
 	maxSize int64
+	level   int
 }
 
 func (z *zstdCompressor) Compress(msg []byte) ([]byte, error) {
 	if int64(len(msg)) > z.maxSize {
 		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrMsgTooLarge, len(msg), z.maxSize)
 	}
-	return zstd.Compress(nil, msg)
+	return zstd.CompressLevel(nil, msg, z.level)
 }
 
+// Decompress expects msg to contain exactly one zstd frame. Trailing data,
+// including additional frames, is rejected with [ErrInvalidFormat].
 func (z *zstdCompressor) Decompress(msg []byte) ([]byte, error) {
+	// Obviously invalid msgs are rejected before any decoding state is
+	// allocated.
+	if err := checkZstdMagic(msg); err != nil {
+		return nil, err
+	}
+	if err := checkZstdFrame(newZstdFrameParser(), msg); err != nil {
+		return nil, err
+	}
+
 	reader := zstd.NewReader(bytes.NewReader(msg))
 	defer reader.Close()
 
@@ -56,10 +76,83 @@ func (z *zstdCompressor) Decompress(msg []byte) ([]byte, error) {
 	limitReader := io.LimitReader(reader, z.maxSize+1)
 	decompressed, err := io.ReadAll(limitReader)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
 	}
 	if int64(len(decompressed)) > z.maxSize {
 		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrDecompressedMsgTooLarge, len(decompressed), z.maxSize)
 	}
 	return decompressed, nil
 }
+
+func (z *zstdCompressor) CompressStream(dst io.Writer, src io.Reader) error {
+	writer := zstd.NewWriterLevel(dst, z.level)
+	exceeded, err := copyLimited(writer, src, z.maxSize)
+	if err != nil {
+		_ = writer.Close()
+		return err
+	}
+	if exceeded {
+		_ = writer.Close()
+		return fmt.Errorf("%w: (> %d)", ErrMsgTooLarge, z.maxSize)
+	}
+	return writer.Close()
+}
+
+func (z *zstdCompressor) DecompressStream(dst io.Writer, src io.Reader) error {
+	return decompressZstdStream(dst, src, z.maxSize, newZstdFrameParser(), func(src io.Reader) io.ReadCloser {
+		return zstd.NewReader(src)
+	})
+}
+
+func (z *zstdCompressor) AppendCompress(dst, msg []byte) ([]byte, error) {
+	if int64(len(msg)) > z.maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrMsgTooLarge, len(msg), z.maxSize)
+	}
+
+	// zstd only writes into the provided buffer if it has enough capacity for
+	// the worst case compressed size.
+	dst = slices.Grow(dst, zstd.CompressBound(len(msg)))
+	compressed, err := zstd.CompressLevel(dst[len(dst):], msg, z.level)
+	if err != nil {
+		return nil, err
+	}
+	return dst[:len(dst)+len(compressed)], nil
+}
+
+func (z *zstdCompressor) AppendDecompress(dst, msg []byte) ([]byte, error) {
+	if err := checkZstdMagic(msg); err != nil {
+		return nil, err
+	}
+	if err := checkZstdFrame(newZstdFrameParser(), msg); err != nil {
+		return nil, err
+	}
+
+	reader := zstd.NewReader(bytes.NewReader(msg))
+	defer reader.Close()
+
+	decompressed, exceeded, err := appendLimited(dst, reader, z.maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+	if exceeded {
+		return nil, fmt.Errorf("%w: (> %d)", ErrDecompressedMsgTooLarge, z.maxSize)
+	}
+	return decompressed, nil
+}
+
+func (z *zstdCompressor) Writer() *MessageWriter {
+	buf := &bytes.Buffer{}
+	writer := zstd.NewWriterLevel(buf, z.level)
+	return newMessageWriter(buf, writer, writer.Close, z.maxSize)
+}
+
+func (z *zstdCompressor) Config() CompressorConfig {
+	return CompressorConfig{
+		MaxSize: z.maxSize,
+		Level:   z.level,
+	}
+}
+
+func (*zstdCompressor) EstimateCompressedSize(msg []byte) int {
+	return zstd.CompressBound(len(msg))
+}
@@ -0,0 +1,72 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestNewZstdCompressorWithLevel(t *testing.T) {
+	tests := []struct {
+		level       int
+		expectedErr error
+	}{
+		{
+			level:       zstdBestSpeed - 1,
+			expectedErr: ErrInvalidCompressionLevel,
+		},
+		{
+			level: zstdBestSpeed,
+		},
+		{
+			level: zstdDefaultCompression,
+		},
+		{
+			level: zstdBestCompression,
+		},
+		{
+			level:       zstdBestCompression + 1,
+			expectedErr: ErrInvalidCompressionLevel,
+		},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.level), func(t *testing.T) {
+			_, err := NewZstdCompressorWithLevel(maxMessageSize, test.level)
+			require.ErrorIs(t, err, test.expectedErr)
+		})
+	}
+}
+
+func TestZstdCompressorLevels(t *testing.T) {
+	require := require.New(t)
+
+	var msg []byte
+	for i := 0; len(msg) < units.MiB; i++ {
+		msg = fmt.Appendf(msg, `{"height":%d,"parentID":"%x"}`, i, i*i)
+	}
+
+	fastCompressor, err := NewZstdCompressorWithLevel(maxMessageSize, zstdBestSpeed)
+	require.NoError(err)
+	bestCompressor, err := NewZstdCompressorWithLevel(maxMessageSize, zstdBestCompression)
+	require.NoError(err)
+
+	fastCompressed, err := fastCompressor.Compress(msg)
+	require.NoError(err)
+	bestCompressed, err := bestCompressor.Compress(msg)
+	require.NoError(err)
+	require.Less(len(bestCompressed), len(fastCompressed))
+
+	// The level only affects compression, so either compressor can decompress
+	// the output of the other.
+	for _, compressed := range [][]byte{fastCompressed, bestCompressed} {
+		decompressed, err := fastCompressor.Decompress(compressed)
+		require.NoError(err)
+		require.Equal(msg, decompressed)
+	}
+}
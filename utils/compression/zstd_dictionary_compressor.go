@@ -0,0 +1,190 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build cgo
+// +build cgo
+
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+
+	"github.com/DataDog/zstd"
+)
+
+var (
+	_ Compressor       = (*zstdDictionaryCompressor)(nil)
+	_ StreamCompressor = (*zstdDictionaryCompressor)(nil)
+	_ AppendCompressor = (*zstdDictionaryCompressor)(nil)
+	_ SizeEstimator    = (*zstdDictionaryCompressor)(nil)
+	_ ConfigReporter   = (*zstdDictionaryCompressor)(nil)
+)
+
+// NewZstdCompressorWithDictionary returns a zstd Compressor that uses dict as
+// a preset dictionary, which lets small messages that share structure with
+// dict compress significantly smaller.
+//
+// dict must be a formatted zstd dictionary with a non-zero ID, as produced by
+// `zstd --train`. Compressed frames record the dictionary ID, so messages
+// decompressed with a different dictionary, or without one, fail with
+// [ErrInvalidFormat]. Frames that record a different dictionary ID are
+// rejected with [ErrUnknownDictionary] before being decoded. Raw content
+// dictionaries are rejected because zstd can't detect when they are
+// mismatched.
+func NewZstdCompressorWithDictionary(maxSize int64, dict []byte) (Compressor, error) {
+	z, err := newZstdCompressor(maxSize, zstd.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	dictionaryID, err := zstdDictionaryID(dict)
+	if err != nil {
+		return nil, err
+	}
+
+	// The dictionary is copied so that the caller's slice can't be modified
+	// out from under any in-flight streams.
+	dict = slices.Clone(dict)
+	processor, err := zstd.NewBulkProcessor(dict, z.level)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDictionary, err)
+	}
+	return &zstdDictionaryCompressor{
+		maxSize:      z.maxSize,
+		level:        z.level,
+		dict:         dict,
+		dictionaryID: dictionaryID,
+		processor:    processor,
+	}, nil
+}
+
+type zstdDictionaryCompressor struct {
+	maxSize int64
+	level   int
+	dict    []byte
+	// dictionaryID is recorded in the header of the frames compressed with
+	// dict.
+	dictionaryID uint32
+	// processor holds the digested dictionary used for block compression.
+	processor *zstd.BulkProcessor
+}
+
+func (z *zstdDictionaryCompressor) Compress(msg []byte) ([]byte, error) {
+	if int64(len(msg)) > z.maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrMsgTooLarge, len(msg), z.maxSize)
+	}
+	compressed, err := z.processor.Compress(nil, msg)
+	// The bulk processor frees its C dictionaries when it is finalized, so it
+	// must be kept reachable until the cgo call has returned.
+	runtime.KeepAlive(z.processor)
+	return compressed, err
+}
+
+func (z *zstdDictionaryCompressor) Decompress(msg []byte) ([]byte, error) {
+	if err := checkZstdMagic(msg); err != nil {
+		return nil, err
+	}
+	if err := checkZstdFrame(z.newFrameParser(), msg); err != nil {
+		return nil, err
+	}
+
+	// The bulk processor allocates based on the size claimed by the frame
+	// header, so decompression is streamed to enforce maxSize.
+	reader := zstd.NewReaderDict(bytes.NewReader(msg), z.dict)
+	defer reader.Close()
+
+	// See [zstdCompressor.Decompress] for why maxSize + 1 bytes are read.
+	decompressed, err := io.ReadAll(io.LimitReader(reader, z.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+	if int64(len(decompressed)) > z.maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrDecompressedMsgTooLarge, len(decompressed), z.maxSize)
+	}
+	return decompressed, nil
+}
+
+func (z *zstdDictionaryCompressor) CompressStream(dst io.Writer, src io.Reader) error {
+	writer := zstd.NewWriterLevelDict(dst, z.level, z.dict)
+	exceeded, err := copyLimited(writer, src, z.maxSize)
+	if err != nil {
+		_ = writer.Close()
+		return err
+	}
+	if exceeded {
+		_ = writer.Close()
+		return fmt.Errorf("%w: (> %d)", ErrMsgTooLarge, z.maxSize)
+	}
+	return writer.Close()
+}
+
+func (z *zstdDictionaryCompressor) DecompressStream(dst io.Writer, src io.Reader) error {
+	return decompressZstdStream(dst, src, z.maxSize, z.newFrameParser(), func(src io.Reader) io.ReadCloser {
+		return zstd.NewReaderDict(src, z.dict)
+	})
+}
+
+func (z *zstdDictionaryCompressor) AppendCompress(dst, msg []byte) ([]byte, error) {
+	if int64(len(msg)) > z.maxSize {
+		return nil, fmt.Errorf("%w: (%d) > (%d)", ErrMsgTooLarge, len(msg), z.maxSize)
+	}
+
+	// The bulk processor only writes into the provided buffer if it has enough
+	// capacity for the worst case compressed size.
+	dst = slices.Grow(dst, zstd.CompressBound(len(msg)))
+	compressed, err := z.processor.Compress(dst[len(dst):], msg)
+	// See [zstdDictionaryCompressor.Compress].
+	runtime.KeepAlive(z.processor)
+	if err != nil {
+		return nil, err
+	}
+	return dst[:len(dst)+len(compressed)], nil
+}
+
+func (z *zstdDictionaryCompressor) AppendDecompress(dst, msg []byte) ([]byte, error) {
+	if err := checkZstdMagic(msg); err != nil {
+		return nil, err
+	}
+	if err := checkZstdFrame(z.newFrameParser(), msg); err != nil {
+		return nil, err
+	}
+
+	reader := zstd.NewReaderDict(bytes.NewReader(msg), z.dict)
+	defer reader.Close()
+
+	decompressed, exceeded, err := appendLimited(dst, reader, z.maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+	if exceeded {
+		return nil, fmt.Errorf("%w: (> %d)", ErrDecompressedMsgTooLarge, z.maxSize)
+	}
+	return decompressed, nil
+}
+
+// newFrameParser returns a parser that rejects frames compressed with a
+// different dictionary.
+func (z *zstdDictionaryCompressor) newFrameParser() *zstdFrameParser {
+	frames := newZstdFrameParser()
+	frames.checkDictionaryID = func(dictionaryID uint32) error {
+		if dictionaryID != z.dictionaryID {
+			return fmt.Errorf("%w: frame uses dictionary %d, expected %d", ErrUnknownDictionary, dictionaryID, z.dictionaryID)
+		}
+		return nil
+	}
+	return frames
+}
+
+func (z *zstdDictionaryCompressor) Config() CompressorConfig {
+	return CompressorConfig{
+		MaxSize: z.maxSize,
+		Level:   z.level,
+	}
+}
+
+func (*zstdDictionaryCompressor) EstimateCompressedSize(msg []byte) int {
+	return zstd.CompressBound(len(msg))
+}
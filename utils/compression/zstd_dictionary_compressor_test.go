@@ -0,0 +1,143 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	_ "embed"
+)
+
+var (
+	// testDictionary was trained by zstd on messages produced by
+	// newTestDictionaryMessage.
+	//
+	//go:embed zstd_dictionary_a.bin
+	testDictionary []byte
+
+	// otherTestDictionary was trained by zstd on unrelated messages and has a
+	// different dictionary ID than testDictionary.
+	//
+	//go:embed zstd_dictionary_b.bin
+	otherTestDictionary []byte
+)
+
+func newTestDictionaryMessage(i int) []byte {
+	return []byte(fmt.Sprintf(`{"chainID":"2q9e4r6Mu3U68nU1fYjgbR6JvwrRx36CohpAX5UQxse55x1Q5","requestID":%d,"deadline":%d,"containerIDs":[]}`, i, 10*i))
+}
+
+func TestZstdDictionarySmallMessagesShrink(t *testing.T) {
+	require := require.New(t)
+
+	withoutDictionary, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	withDictionary, err := NewZstdCompressorWithDictionary(maxMessageSize, testDictionary)
+	require.NoError(err)
+
+	// Use messages that weren't part of the training set.
+	for i := 5000; i < 5010; i++ {
+		msg := newTestDictionaryMessage(i)
+
+		compressed, err := withoutDictionary.Compress(msg)
+		require.NoError(err)
+		dictionaryCompressed, err := withDictionary.Compress(msg)
+		require.NoError(err)
+		require.Less(len(dictionaryCompressed), len(compressed)/2)
+
+		decompressed, err := withDictionary.Decompress(dictionaryCompressed)
+		require.NoError(err)
+		require.Equal(msg, decompressed)
+	}
+}
+
+func TestZstdDictionaryMismatch(t *testing.T) {
+	require := require.New(t)
+
+	withDictionary, err := NewZstdCompressorWithDictionary(maxMessageSize, testDictionary)
+	require.NoError(err)
+	compressed, err := withDictionary.Compress(newTestDictionaryMessage(0))
+	require.NoError(err)
+
+	withoutDictionary, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	withOtherDictionary, err := NewZstdCompressorWithDictionary(maxMessageSize, otherTestDictionary)
+	require.NoError(err)
+
+	for _, compressor := range []Compressor{withoutDictionary, withOtherDictionary} {
+		_, err = compressor.Decompress(compressed)
+		require.ErrorIs(err, ErrInvalidFormat)
+	}
+}
+
+func TestZstdDictionaryID(t *testing.T) {
+	require := require.New(t)
+
+	withDictionary, err := NewZstdCompressorWithDictionary(maxMessageSize, testDictionary)
+	require.NoError(err)
+	withOtherDictionary, err := NewZstdCompressorWithDictionary(maxMessageSize, otherTestDictionary)
+	require.NoError(err)
+
+	msg := newTestDictionaryMessage(0)
+	compressed, err := withDictionary.Compress(msg)
+	require.NoError(err)
+
+	// The matching dictionary is loaded.
+	decompressed, err := withDictionary.Decompress(compressed)
+	require.NoError(err)
+	require.Equal(msg, decompressed)
+
+	// The dictionary ID in the frame header doesn't match the loaded one.
+	_, err = withOtherDictionary.Decompress(compressed)
+	require.ErrorIs(err, ErrUnknownDictionary)
+	require.ErrorIs(err, ErrInvalidFormat)
+
+	_, err = withOtherDictionary.(AppendCompressor).AppendDecompress(nil, compressed)
+	require.ErrorIs(err, ErrUnknownDictionary)
+
+	err = withOtherDictionary.(StreamCompressor).DecompressStream(io.Discard, bytes.NewReader(compressed))
+	require.ErrorIs(err, ErrUnknownDictionary)
+
+	// Frames that don't record a dictionary ID can still be decompressed.
+	withoutDictionary, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	compressed, err = withoutDictionary.Compress(msg)
+	require.NoError(err)
+	decompressed, err = withDictionary.Decompress(compressed)
+	require.NoError(err)
+	require.Equal(msg, decompressed)
+}
+
+func TestNewZstdCompressorWithInvalidDictionary(t *testing.T) {
+	missingID := append([]byte{}, testDictionary...)
+	copy(missingID[4:8], []byte{0, 0, 0, 0})
+
+	tests := []struct {
+		name string
+		dict []byte
+	}{
+		{
+			name: "empty",
+			dict: nil,
+		},
+		{
+			name: "raw content",
+			dict: []byte(`{"chainID":"2q9e4r6Mu3U68nU1fYjgbR6JvwrRx36CohpAX5UQxse55x1Q5"}`),
+		},
+		{
+			name: "missing ID",
+			dict: missingID,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewZstdCompressorWithDictionary(maxMessageSize, test.dict)
+			require.ErrorIs(t, err, ErrInvalidDictionary)
+		})
+	}
+}
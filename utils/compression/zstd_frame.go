@@ -0,0 +1,315 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// Skippable frames start with a magic number in
+	// [zstdSkippableMagic, zstdSkippableMagic + 15].
+	zstdSkippableMagic     = 0x184D2A50
+	zstdSkippableMagicMask = 0xFFFFFFF0
+
+	zstdMagicLen       = 4
+	zstdBlockHeaderLen = 3
+	zstdChecksumLen    = 4
+	// zstdMaxFrameHeaderLen is the maximum length of the frame header fields
+	// that follow the frame header descriptor: a window descriptor, a 4 byte
+	// dictionary ID and an 8 byte frame content size.
+	zstdMaxFrameHeaderLen = 1 + 4 + 8
+)
+
+var (
+	_ io.Writer = (*zstdFrameParser)(nil)
+
+	zstdDictionaryIDLens = [4]int{0, 1, 2, 4}
+	// zstdFrameContentSizeLens is indexed by the frame content size flag. If
+	// the flag is 0, the field is only present in single segment frames.
+	zstdFrameContentSizeLens = [4]int{0, 2, 4, 8}
+
+	errZstdReservedBit     = errors.New("reserved bit set in zstd frame header")
+	errZstdReservedBlock   = errors.New("reserved zstd block type")
+	errZstdUnknownMagic    = errors.New("unknown zstd magic number")
+	errZstdIncompleteFrame = fmt.Errorf("%w: %w: incomplete zstd frame", ErrInvalidFormat, ErrTruncatedStream)
+	errZstdMissingChecksum = fmt.Errorf("%w: %w: %w: missing zstd frame checksum", ErrInvalidFormat, ErrTruncatedStream, ErrTruncatedTrailer)
+	errZstdMissingMagic    = fmt.Errorf("%w: %w", ErrInvalidFormat, errZstdUnknownMagic)
+)
+
+type zstdFrameState uint8
+
+const (
+	zstdMagicState zstdFrameState = iota
+	zstdSkippableSizeState
+	zstdFrameHeaderDescriptorState
+	zstdFrameHeaderState
+	zstdBlockHeaderState
+	zstdSkipState
+)
+
+// zstdFrameParser follows the structure of a sequence of zstd frames as the
+// compressed bytes are written to it, without decompressing them. This allows
+// truncated frames to be detected, which the zstd decoder silently accepts.
+type zstdFrameParser struct {
+	state zstdFrameState
+
+	// field accumulates the fixed length field that is currently being
+	// parsed.
+	field    [zstdMaxFrameHeaderLen]byte
+	fieldLen int
+	need     int
+
+	// skip is the number of bytes of the current block, checksum, or
+	// skippable frame that haven't been written yet. If endOfFrame is true,
+	// the frame is complete once they have been written.
+	skip       uint64
+	endOfFrame bool
+	// trailer is true if the bytes being skipped end with the checksum of the
+	// frame.
+	trailer bool
+
+	singleSegment    bool
+	dictionaryIDFlag byte
+	checksum         bool
+
+	// checkDictionaryID, if set, is called with the dictionary ID of each
+	// frame that records one. If it errors, the frame is invalid.
+	checkDictionaryID func(uint32) error
+
+	frames int
+	err    error
+}
+
+func newZstdFrameParser() *zstdFrameParser {
+	z := &zstdFrameParser{}
+	z.expect(zstdMagicState, zstdMagicLen)
+	return z
+}
+
+// Write never errors, so that the parser can be used in an [io.TeeReader].
+// Invalid frames are reported by complete.
+func (z *zstdFrameParser) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 && z.err == nil {
+		if z.state == zstdSkipState {
+			skipped := min(uint64(len(p)), z.skip)
+			p = p[skipped:]
+			z.skip -= skipped
+			if z.skip == 0 {
+				z.endSkip()
+			}
+			continue
+		}
+
+		copied := copy(z.field[z.fieldLen:z.need], p)
+		p = p[copied:]
+		z.fieldLen += copied
+		if z.fieldLen == z.need {
+			z.err = z.parseField(z.field[:z.need])
+		}
+	}
+	return n, nil
+}
+
+// complete returns nil if the bytes written so far form one or more complete
+// frames.
+//
+// Some senders drop the checksum that ends a frame while the blocks before it
+// are intact, which is reported as [ErrTruncatedTrailer] so that they can be
+// told apart from streams that were cut short elsewhere.
+func (z *zstdFrameParser) complete() error {
+	switch {
+	case z.err != nil:
+		return fmt.Errorf("%w: %w", ErrInvalidFormat, z.err)
+	case z.state == zstdSkipState && z.trailer && z.skip <= zstdChecksumLen:
+		return errZstdMissingChecksum
+	case z.frames == 0 || z.state != zstdMagicState || z.fieldLen != 0:
+		return errZstdIncompleteFrame
+	default:
+		return nil
+	}
+}
+
+func (z *zstdFrameParser) parseField(field []byte) error {
+	switch z.state {
+	case zstdMagicState:
+		switch magic := binary.LittleEndian.Uint32(field); {
+		case magic == zstdFrameMagic:
+			z.expect(zstdFrameHeaderDescriptorState, 1)
+		case magic&zstdSkippableMagicMask == zstdSkippableMagic:
+			z.expect(zstdSkippableSizeState, 4)
+		default:
+			return fmt.Errorf("%w: 0x%08x", errZstdUnknownMagic, magic)
+		}
+	case zstdSkippableSizeState:
+		z.trailer = false
+		z.skipThen(uint64(binary.LittleEndian.Uint32(field)), true)
+	case zstdFrameHeaderDescriptorState:
+		descriptor := field[0]
+		if descriptor&0x08 != 0 {
+			return errZstdReservedBit
+		}
+		frameContentSizeFlag := descriptor >> 6
+		z.singleSegment = descriptor&0x20 != 0
+		z.dictionaryIDFlag = descriptor & 0x03
+		z.checksum = descriptor&0x04 != 0
+
+		headerLen := zstdDictionaryIDLens[z.dictionaryIDFlag] + zstdFrameContentSizeLens[frameContentSizeFlag]
+		switch {
+		case !z.singleSegment:
+			// The window descriptor is only present in multi segment frames.
+			headerLen++
+		case frameContentSizeFlag == 0:
+			// Single segment frames always record their content size.
+			headerLen++
+		}
+		if headerLen == 0 {
+			z.expect(zstdBlockHeaderState, zstdBlockHeaderLen)
+		} else {
+			z.expect(zstdFrameHeaderState, headerLen)
+		}
+	case zstdFrameHeaderState:
+		if z.dictionaryIDFlag != 0 && z.checkDictionaryID != nil {
+			if !z.singleSegment {
+				// Skip the window descriptor.
+				field = field[1:]
+			}
+			var dictionaryID uint32
+			for i := zstdDictionaryIDLens[z.dictionaryIDFlag] - 1; i >= 0; i-- {
+				dictionaryID = dictionaryID<<8 | uint32(field[i])
+			}
+			if err := z.checkDictionaryID(dictionaryID); err != nil {
+				return err
+			}
+		}
+		z.expect(zstdBlockHeaderState, zstdBlockHeaderLen)
+	case zstdBlockHeaderState:
+		var (
+			header    = uint32(field[0]) | uint32(field[1])<<8 | uint32(field[2])<<16
+			lastBlock = header&0x01 != 0
+			blockType = (header >> 1) & 0x03
+			size      = uint64(header >> 3)
+		)
+		switch blockType {
+		case 1:
+			// RLE blocks contain a single byte, repeated size times.
+			size = 1
+		case 3:
+			return errZstdReservedBlock
+		}
+		z.trailer = lastBlock && z.checksum
+		if z.trailer {
+			size += zstdChecksumLen
+		}
+		z.skipThen(size, lastBlock)
+	}
+	return nil
+}
+
+// expect parses the next need bytes as the field of state.
+func (z *zstdFrameParser) expect(state zstdFrameState, need int) {
+	z.state = state
+	z.fieldLen = 0
+	z.need = need
+}
+
+// skipThen skips the next n bytes, after which either the frame is complete or
+// the next block header is parsed.
+func (z *zstdFrameParser) skipThen(n uint64, endOfFrame bool) {
+	z.skip = n
+	z.endOfFrame = endOfFrame
+	z.state = zstdSkipState
+	if n == 0 {
+		z.endSkip()
+	}
+}
+
+func (z *zstdFrameParser) endSkip() {
+	if !z.endOfFrame {
+		z.expect(zstdBlockHeaderState, zstdBlockHeaderLen)
+		return
+	}
+	z.frames++
+	z.expect(zstdMagicState, zstdMagicLen)
+}
+
+// checkZstdMagic returns an error if msg is too short to be a zstd frame or
+// doesn't start with a zstd magic number. Unlike [checkZstdFrame], it doesn't
+// allocate, so it is used to reject garbage before any parsing or decoding
+// state is created.
+//
+// Every zstd frame is non-empty, so an empty msg is rejected rather than being
+// treated as a stream of zero frames.
+func checkZstdMagic(msg []byte) error {
+	switch {
+	case len(msg) == 0:
+		return errEmptyMsg
+	case len(msg) < zstdMagicLen:
+		return errZstdIncompleteFrame
+	}
+	magic := binary.LittleEndian.Uint32(msg)
+	if magic != zstdFrameMagic && magic&zstdSkippableMagicMask != zstdSkippableMagic {
+		return errZstdMissingMagic
+	}
+	return nil
+}
+
+// checkZstdFrame returns an error unless msg consists of exactly one complete
+// frame, as parsed by frames. The zstd decoder decompresses as much of a
+// truncated frame as it can without reporting an error, and doesn't reliably
+// report data after the first frame, so msg must be checked before its output
+// is trusted.
+func checkZstdFrame(frames *zstdFrameParser, msg []byte) error {
+	_, _ = frames.Write(msg)
+	if err := frames.complete(); err != nil {
+		return err
+	}
+	if frames.frames != 1 {
+		return fmt.Errorf("%w: %w: %d frames", ErrInvalidFormat, ErrTrailingData, frames.frames)
+	}
+	return nil
+}
+
+// decompressZstdStream decompresses the zstd frames in src into dst using the
+// reader returned by newReader.
+//
+// The zstd decoder treats the end of its source as the end of the stream, even
+// if it is in the middle of a frame, so the frames are also parsed to detect
+// truncated streams.
+func decompressZstdStream(
+	dst io.Writer,
+	src io.Reader,
+	maxSize int64,
+	frames *zstdFrameParser,
+	newReader func(io.Reader) io.ReadCloser,
+) error {
+	source := &sourceReader{reader: src}
+	reader := newReader(io.TeeReader(source, frames))
+	defer reader.Close()
+
+	exceeded, err := copyLimited(dst, reader, maxSize)
+	if err != nil {
+		framesErr := frames.complete()
+		switch {
+		case source.err != nil:
+			return source.err
+		case frames.err != nil, source.eof && errors.Is(framesErr, ErrTruncatedTrailer):
+			// The frame structure explains why decoding failed.
+			return framesErr
+		case framesErr == nil:
+			// All the frames were read, so their contents are invalid.
+			return fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+		default:
+			return source.wrapErr(err)
+		}
+	}
+	if exceeded {
+		return fmt.Errorf("%w: (> %d)", ErrDecompressedMsgTooLarge, maxSize)
+	}
+	return frames.complete()
+}
@@ -0,0 +1,42 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build !cgo
+// +build !cgo
+
+package compression
+
+func newDefaultZstdCompressor(maxSize int64, level int) (zstdImplementation, error) {
+	z, err := newGoZstdCompressor(maxSize, level)
+	if err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+// NewPooledZstdCompressor returns a zstd Compressor that reuses compression
+// contexts and decompression buffers across calls.
+//
+// Without cgo, this is the pure Go Compressor returned by [NewZstdCompressor],
+// which always reuses its state.
+func NewPooledZstdCompressor(maxSize int64) (Compressor, error) {
+	return NewZstdCompressor(maxSize)
+}
+
+// NewZstdCompressorWithDictionary returns a zstd Compressor that uses dict as
+// a preset dictionary, which lets small messages that share structure with
+// dict compress significantly smaller.
+//
+// dict must be a formatted zstd dictionary with a non-zero ID, as produced by
+// `zstd --train`. Frames that record a different dictionary ID are rejected
+// with [ErrUnknownDictionary] before being decoded.
+//
+// Without cgo, the pure Go implementation is used, which produces and accepts
+// the same format as the cgo implementation.
+func NewZstdCompressorWithDictionary(maxSize int64, dict []byte) (Compressor, error) {
+	z, err := newGoZstdDictionaryCompressor(maxSize, zstdDefaultCompression, dict)
+	if err != nil {
+		return nil, err
+	}
+	return z, nil
+}
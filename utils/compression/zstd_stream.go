@@ -0,0 +1,302 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+// zstdStreamChunkSize is the size of the chunks that are passed to and read
+// from zstd streams when copying, which matches the block size of zstd.
+const zstdStreamChunkSize = 128 * units.KiB
+
+var (
+	_ io.WriteCloser = (*CompressWriter)(nil)
+	_ io.ReaderFrom  = (*CompressWriter)(nil)
+	_ io.ReadCloser  = (*DecompressReader)(nil)
+	_ io.WriterTo    = (*DecompressReader)(nil)
+	_ io.ReadWriter  = (*StreamDecompressor)(nil)
+
+	ErrNeedMoreInput = errors.New("need more input")
+)
+
+// zstdStreamWriter is the zstd encoder that a [CompressWriter] writes to.
+type zstdStreamWriter interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// pushDecoder is the zstd decoder of a [StreamDecompressor]. Read returns
+// [ErrNeedMoreInput] if no output can be produced from the bytes written so
+// far.
+type pushDecoder interface {
+	io.Writer
+	io.ReadCloser
+	// closeWrite signals that no more bytes will be written.
+	closeWrite()
+}
+
+// CompressWriter compresses the bytes written to it into a zstd frame that can
+// be decompressed by the zstd [Compressor].
+type CompressWriter struct {
+	writer zstdStreamWriter
+	hash   hash.Hash64
+	closed bool
+
+	// buf is allocated by the first call to ReadFrom and reused afterwards.
+	buf []byte
+}
+
+// NewCompressWriter returns a CompressWriter that writes the compressed bytes
+// to w. The frame is only complete once Close has been called, which doesn't
+// close w.
+func NewCompressWriter(w io.Writer) *CompressWriter {
+	return &CompressWriter{
+		writer: newZstdStreamWriter(w),
+	}
+}
+
+// NewHashingCompressWriter returns a CompressWriter that also writes the
+// compressed bytes to h, so that the compressed output can be hashed as it is
+// produced. h can be any 64 bit hash, such as CRC-64 or xxHash.
+func NewHashingCompressWriter(w io.Writer, h hash.Hash64) *CompressWriter {
+	return &CompressWriter{
+		writer: newZstdStreamWriter(io.MultiWriter(w, h)),
+		hash:   h,
+	}
+}
+
+func (c *CompressWriter) Write(p []byte) (int, error) {
+	// The zstd encoder is released on Close, so it must not be used
+	// afterwards.
+	if c.closed {
+		return 0, ErrClosed
+	}
+	return c.writer.Write(p)
+}
+
+// ReadFrom compresses everything read from r until EOF and returns the number
+// of bytes read. r is read in chunks of a full zstd block, rather than the
+// smaller chunks used by [io.Copy], which uses ReadFrom when copying to c.
+func (c *CompressWriter) ReadFrom(r io.Reader) (int64, error) {
+	if c.closed {
+		return 0, ErrClosed
+	}
+
+	if c.buf == nil {
+		c.buf = make([]byte, zstdStreamChunkSize)
+	}
+
+	var read int64
+	for {
+		n, err := r.Read(c.buf)
+		read += int64(n)
+		if n > 0 {
+			if _, err := c.writer.Write(c.buf[:n]); err != nil {
+				return read, err
+			}
+		}
+		switch {
+		case err == io.EOF:
+			return read, nil
+		case err != nil:
+			return read, err
+		}
+	}
+}
+
+// Flush writes all the bytes written so far to the underlying writer in a form
+// that can be decompressed without waiting for the rest of the frame. Flushing
+// frequently reduces latency at the cost of compression ratio.
+func (c *CompressWriter) Flush() error {
+	if c.closed {
+		return ErrClosed
+	}
+	return c.writer.Flush()
+}
+
+// Sum returns the hash of the compressed bytes written to the underlying
+// writer so far, which covers the complete frame once Close has been called.
+// If the CompressWriter wasn't created with [NewHashingCompressWriter], Sum
+// returns 0.
+func (c *CompressWriter) Sum() uint64 {
+	if c.hash == nil {
+		return 0
+	}
+	return c.hash.Sum64()
+}
+
+// Close completes the zstd frame and releases the resources held by the
+// writer.
+func (c *CompressWriter) Close() error {
+	if c.closed {
+		return ErrClosed
+	}
+	c.closed = true
+	return c.writer.Close()
+}
+
+// DecompressReader decompresses zstd frames read from an underlying reader. If
+// the underlying reader ends in the middle of a frame, Read returns
+// [ErrTruncatedStream] rather than [io.EOF].
+//
+// The size of the decompressed stream isn't bounded, so callers reading from
+// untrusted sources should limit how much they read.
+type DecompressReader struct {
+	source *sourceReader
+	frames *zstdFrameParser
+	reader io.ReadCloser
+
+	// buf is allocated by the first call to WriteTo and reused afterwards.
+	buf []byte
+}
+
+// NewDecompressReader returns a DecompressReader that reads compressed bytes
+// from r. Close releases the resources held by the reader, but doesn't close
+// r.
+func NewDecompressReader(r io.Reader) *DecompressReader {
+	var (
+		source = &sourceReader{reader: r}
+		frames = newZstdFrameParser()
+	)
+	return &DecompressReader{
+		source: source,
+		frames: frames,
+		reader: newZstdStreamReader(io.TeeReader(source, frames)),
+	}
+}
+
+func (d *DecompressReader) Read(p []byte) (int, error) {
+	n, err := d.reader.Read(p)
+	switch {
+	case err == io.EOF:
+		if err := d.frames.complete(); err != nil {
+			return n, err
+		}
+		return n, io.EOF
+	case err != nil:
+		return n, d.source.wrapErr(err)
+	default:
+		return n, nil
+	}
+}
+
+// WriteTo writes the decompressed stream to w until the end of the stream and
+// returns the number of bytes written. The output is written in chunks of a
+// full zstd block, rather than the smaller chunks used by [io.Copy], which uses
+// WriteTo when copying from d.
+func (d *DecompressReader) WriteTo(w io.Writer) (int64, error) {
+	if d.buf == nil {
+		d.buf = make([]byte, zstdStreamChunkSize)
+	}
+
+	var written int64
+	for {
+		n, err := d.Read(d.buf)
+		if n > 0 {
+			wrote, err := w.Write(d.buf[:n])
+			written += int64(wrote)
+			if err != nil {
+				return written, err
+			}
+			if wrote < n {
+				return written, io.ErrShortWrite
+			}
+		}
+		switch {
+		case err == io.EOF:
+			return written, nil
+		case err != nil:
+			return written, err
+		}
+	}
+}
+
+func (d *DecompressReader) Close() error {
+	return d.reader.Close()
+}
+
+// StreamDecompressor incrementally decompresses zstd frames that are fed to it
+// with Write. Read returns the output that can be produced from the bytes
+// written so far, which allows messages to be processed as they arrive.
+//
+// A StreamDecompressor isn't safe for concurrent use. As with
+// [DecompressReader], the size of the decompressed stream isn't bounded, and
+// if the input ends in the middle of a frame, Read returns
+// [ErrTruncatedStream] rather than [io.EOF].
+type StreamDecompressor struct {
+	decoder     pushDecoder
+	frames      *zstdFrameParser
+	inputClosed bool
+	closed      bool
+}
+
+func NewStreamDecompressor() *StreamDecompressor {
+	return &StreamDecompressor{
+		decoder: newPushDecoder(),
+		frames:  newZstdFrameParser(),
+	}
+}
+
+// Write buffers compressed bytes to be decompressed by later calls to Read.
+func (s *StreamDecompressor) Write(p []byte) (int, error) {
+	if s.closed || s.inputClosed {
+		return 0, ErrClosed
+	}
+	_, _ = s.frames.Write(p)
+	return s.decoder.Write(p)
+}
+
+// CloseWrite signals that no more compressed bytes will be written. Once all
+// the output has been read, Read returns [io.EOF].
+func (s *StreamDecompressor) CloseWrite() error {
+	if s.closed || s.inputClosed {
+		return ErrClosed
+	}
+	s.inputClosed = true
+	s.decoder.closeWrite()
+	return nil
+}
+
+// Read reads decompressed bytes into p. If no output can be produced until
+// more compressed bytes are written, [ErrNeedMoreInput] is returned and Read
+// can be called again after the next Write.
+func (s *StreamDecompressor) Read(p []byte) (int, error) {
+	// The zstd decoder is released on Close, so it must not be used
+	// afterwards.
+	if s.closed {
+		return 0, ErrClosed
+	}
+
+	n, err := s.decoder.Read(p)
+	switch {
+	case err == nil, err == ErrNeedMoreInput:
+		return n, err
+	case s.inputClosed && s.frames.err == nil:
+		// The zstd decoder may treat the end of its input as the end of the
+		// stream, even if it is in the middle of a frame, so the frames
+		// explain whether the stream was cut short.
+		if err := s.frames.complete(); err != nil {
+			return n, err
+		}
+	}
+	if err != io.EOF {
+		return n, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+	return n, io.EOF
+}
+
+// Close releases the resources held by the StreamDecompressor.
+func (s *StreamDecompressor) Close() error {
+	if s.closed {
+		return ErrClosed
+	}
+	s.closed = true
+	return s.decoder.Close()
+}
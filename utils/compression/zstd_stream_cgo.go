@@ -0,0 +1,77 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build cgo
+// +build cgo
+
+package compression
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/DataDog/zstd"
+)
+
+var _ pushDecoder = (*zstdPushDecoder)(nil)
+
+func newZstdStreamWriter(w io.Writer) zstdStreamWriter {
+	return zstd.NewWriterLevel(w, zstd.DefaultCompression)
+}
+
+func newZstdStreamReader(r io.Reader) io.ReadCloser {
+	return zstd.NewReader(r)
+}
+
+func newPushDecoder() pushDecoder {
+	z := &zstdPushDecoder{}
+	z.reader = zstd.NewReader(&z.input)
+	return z
+}
+
+// zstdPushDecoder decodes its input with a zstd reader, which keeps its state
+// when its source errors, so reading can resume once more input is available.
+type zstdPushDecoder struct {
+	input  pendingInput
+	reader io.ReadCloser
+}
+
+func (z *zstdPushDecoder) Write(p []byte) (int, error) {
+	return z.input.buf.Write(p)
+}
+
+func (z *zstdPushDecoder) closeWrite() {
+	z.input.closed = true
+}
+
+func (z *zstdPushDecoder) Read(p []byte) (int, error) {
+	n, err := z.reader.Read(p)
+	if z.input.starved {
+		z.input.starved = false
+		return n, ErrNeedMoreInput
+	}
+	return n, err
+}
+
+func (z *zstdPushDecoder) Close() error {
+	return z.reader.Close()
+}
+
+// pendingInput is the source of a [zstdPushDecoder]. Rather than blocking
+// when it has no buffered bytes, it reports that it has been starved.
+type pendingInput struct {
+	buf     bytes.Buffer
+	closed  bool
+	starved bool
+}
+
+func (p *pendingInput) Read(b []byte) (int, error) {
+	if p.buf.Len() > 0 {
+		return p.buf.Read(b)
+	}
+	if p.closed {
+		return 0, io.EOF
+	}
+	p.starved = true
+	return 0, ErrNeedMoreInput
+}
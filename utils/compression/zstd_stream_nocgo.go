@@ -0,0 +1,182 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build !cgo
+// +build !cgo
+
+package compression
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var _ pushDecoder = (*goZstdPushDecoder)(nil)
+
+func newZstdStreamWriter(w io.Writer) zstdStreamWriter {
+	// The options only fail if they are invalid.
+	writer, _ := zstd.NewWriter(
+		w,
+		zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(zstdDefaultCompression)),
+		zstd.WithZeroFrames(true),
+		zstd.WithEncoderCRC(false),
+		zstd.WithEncoderConcurrency(1),
+	)
+	return writer
+}
+
+func newZstdStreamReader(r io.Reader) io.ReadCloser {
+	// The options only fail if they are invalid.
+	decoder, _ := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	return decoder.IOReadCloser()
+}
+
+func newPushDecoder() pushDecoder {
+	g := &goZstdPushDecoder{}
+	g.cond = sync.NewCond(&g.lock)
+	go g.run()
+	return g
+}
+
+// goZstdPushDecoder decodes its input with a zstd reader that runs in its own
+// goroutine. Unlike the cgo reader, the pure Go reader doesn't recover from
+// errors returned by its source, so rather than erroring when it runs out of
+// input, the source blocks until more bytes are written.
+//
+// With a concurrency of 1, the reader doesn't return any output while it
+// waits for the rest of a block, so once the source is blocked and all the
+// decoded bytes have been read, no output can be produced until the next
+// Write.
+type goZstdPushDecoder struct {
+	lock sync.Mutex
+	cond *sync.Cond
+
+	input       bytes.Buffer
+	inputClosed bool
+	// starved is true while the reader is blocked on an empty input.
+	starved bool
+
+	// requested is true while the reader is asked to decode more bytes into
+	// decoded.
+	requested bool
+	decoded   []byte
+	// err is the error that ended decoding, which is reported once decoded
+	// has been read.
+	err    error
+	closed bool
+}
+
+// run decodes the input on request until decoding fails or the decoder is
+// closed.
+func (g *goZstdPushDecoder) run() {
+	decoder, err := zstd.NewReader(goZstdPushSource{g}, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		g.lock.Lock()
+		g.err = err
+		g.cond.Broadcast()
+		g.lock.Unlock()
+		return
+	}
+	defer decoder.Close()
+
+	buf := make([]byte, zstdStreamChunkSize)
+	for {
+		g.lock.Lock()
+		for !g.requested && !g.closed {
+			g.cond.Wait()
+		}
+		if g.closed {
+			g.lock.Unlock()
+			return
+		}
+		g.lock.Unlock()
+
+		n, err := decoder.Read(buf)
+
+		g.lock.Lock()
+		g.requested = false
+		g.decoded = buf[:n]
+		g.err = err
+		g.cond.Broadcast()
+		g.lock.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (g *goZstdPushDecoder) Write(p []byte) (int, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.cond.Broadcast()
+	return g.input.Write(p)
+}
+
+func (g *goZstdPushDecoder) closeWrite() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.inputClosed = true
+	g.cond.Broadcast()
+}
+
+func (g *goZstdPushDecoder) Read(p []byte) (int, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	for {
+		switch {
+		case len(g.decoded) > 0:
+			n := copy(p, g.decoded)
+			g.decoded = g.decoded[n:]
+			return n, nil
+		case g.err != nil:
+			return 0, g.err
+		case g.requested && g.starved && g.input.Len() == 0 && !g.inputClosed:
+			return 0, ErrNeedMoreInput
+		case !g.requested:
+			g.requested = true
+			g.cond.Broadcast()
+		}
+		g.cond.Wait()
+	}
+}
+
+func (g *goZstdPushDecoder) Close() error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.closed = true
+	g.cond.Broadcast()
+	return nil
+}
+
+// goZstdPushSource is the source of the reader of a [goZstdPushDecoder].
+type goZstdPushSource struct {
+	g *goZstdPushDecoder
+}
+
+func (s goZstdPushSource) Read(p []byte) (int, error) {
+	g := s.g
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	for g.input.Len() == 0 && !g.inputClosed && !g.closed {
+		g.starved = true
+		g.cond.Broadcast()
+		g.cond.Wait()
+	}
+	g.starved = false
+	switch {
+	case g.closed:
+		return 0, ErrClosed
+	case g.input.Len() == 0:
+		return 0, io.EOF
+	default:
+		return g.input.Read(p)
+	}
+}
@@ -0,0 +1,343 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package compression
+
+import (
+	"bytes"
+	"errors"
+	"hash/crc64"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+type maxReadRecorder struct {
+	reader  io.Reader
+	maxRead int
+}
+
+func (m *maxReadRecorder) Read(p []byte) (int, error) {
+	m.maxRead = max(m.maxRead, len(p))
+	return m.reader.Read(p)
+}
+
+func TestCompressWriterDecompressReader(t *testing.T) {
+	require := require.New(t)
+
+	var (
+		msg        = utils.RandomBytes(units.MiB)
+		compressed bytes.Buffer
+		writer     = NewCompressWriter(&compressed)
+	)
+	for start := 0; start < len(msg); start += 100 * units.KiB {
+		chunk := msg[start:min(start+100*units.KiB, len(msg))]
+		n, err := writer.Write(chunk)
+		require.NoError(err)
+		require.Len(chunk, n)
+	}
+	require.NoError(writer.Close())
+
+	// The writer produces frames that the zstd Compressor can decompress.
+	compressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	decompressed, err := compressor.Decompress(compressed.Bytes())
+	require.NoError(err)
+	require.Equal(msg, decompressed)
+
+	reader := NewDecompressReader(&compressed)
+	decompressed, err = io.ReadAll(reader)
+	require.NoError(err)
+	require.Equal(msg, decompressed)
+	require.NoError(reader.Close())
+}
+
+func TestCompressWriterDecompressReaderCopy(t *testing.T) {
+	require := require.New(t)
+
+	// io.Copy reads and writes at most this much at a time if neither side
+	// implements a fast path.
+	const copyBufferSize = 32 * units.KiB
+
+	var (
+		msg        = bytes.Repeat([]byte("avalanche"), units.MiB/8)
+		src        = &maxReadRecorder{reader: bytes.NewReader(msg)}
+		compressed bytes.Buffer
+		writer     = NewCompressWriter(&compressed)
+	)
+	read, err := io.Copy(writer, src)
+	require.NoError(err)
+	require.Equal(int64(len(msg)), read)
+	require.Equal(zstdStreamChunkSize, src.maxRead)
+	require.NoError(writer.Close())
+
+	var (
+		reader = NewDecompressReader(&compressed)
+		dst    = &maxWriteRecorder{}
+	)
+	defer reader.Close()
+
+	written, err := io.Copy(dst, reader)
+	require.NoError(err)
+	require.Equal(int64(len(msg)), written)
+	require.Greater(dst.maxWrite, copyBufferSize)
+	require.Equal(msg, dst.Bytes())
+}
+
+func TestCompressWriterFlush(t *testing.T) {
+	require := require.New(t)
+
+	var (
+		compressed bytes.Buffer
+		writer     = NewCompressWriter(&compressed)
+		reader     = NewDecompressReader(&compressed)
+	)
+	defer reader.Close()
+
+	// Each flushed chunk must be decompressable before the frame is complete.
+	for i := 0; i < 3; i++ {
+		chunk := newTestDictionaryMessage(i)
+		_, err := writer.Write(chunk)
+		require.NoError(err)
+		require.NoError(writer.Flush())
+
+		decompressed := make([]byte, len(chunk))
+		_, err = io.ReadFull(reader, decompressed)
+		require.NoError(err)
+		require.Equal(chunk, decompressed)
+	}
+	require.NoError(writer.Close())
+
+	rest, err := io.ReadAll(reader)
+	require.NoError(err)
+	require.Empty(rest)
+}
+
+func TestHashingCompressWriter(t *testing.T) {
+	require := require.New(t)
+
+	var (
+		table      = crc64.MakeTable(crc64.ECMA)
+		compressed bytes.Buffer
+		writer     = NewHashingCompressWriter(&compressed, crc64.New(table))
+	)
+	for i := 0; i < 10; i++ {
+		_, err := writer.Write(utils.RandomBytes(100 * units.KiB))
+		require.NoError(err)
+	}
+	require.NoError(writer.Close())
+	require.Equal(crc64.Checksum(compressed.Bytes(), table), writer.Sum())
+
+	// Hashing is opt-in.
+	writer = NewCompressWriter(io.Discard)
+	require.NoError(writer.Close())
+	require.Zero(writer.Sum())
+}
+
+func TestCompressWriterClosed(t *testing.T) {
+	require := require.New(t)
+
+	writer := NewCompressWriter(io.Discard)
+	require.NoError(writer.Close())
+
+	_, err := writer.Write([]byte{1})
+	require.ErrorIs(err, ErrClosed)
+	_, err = writer.ReadFrom(bytes.NewReader([]byte{1}))
+	require.ErrorIs(err, ErrClosed)
+	require.ErrorIs(writer.Flush(), ErrClosed)
+	require.ErrorIs(writer.Close(), ErrClosed)
+}
+
+func TestDecompressReaderInvalidFormat(t *testing.T) {
+	reader := NewDecompressReader(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}))
+	defer reader.Close()
+
+	_, err := io.ReadAll(reader)
+	require.ErrorIs(t, err, ErrInvalidFormat)
+}
+
+func TestDecompressReaderTruncated(t *testing.T) {
+	require := require.New(t)
+
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	compressed, err := zstdCompressor.Compress(utils.RandomBytes(units.MiB))
+	require.NoError(err)
+
+	reader := NewDecompressReader(bytes.NewReader(compressed[:len(compressed)-1]))
+	defer reader.Close()
+
+	_, err = io.ReadAll(reader)
+	require.ErrorIs(err, ErrTruncatedStream)
+
+	reader = NewDecompressReader(bytes.NewReader(compressed[:len(compressed)-1]))
+	defer reader.Close()
+
+	_, err = reader.WriteTo(io.Discard)
+	require.ErrorIs(err, ErrTruncatedStream)
+}
+
+// shortWriter accepts at most limit bytes per write without returning an
+// error.
+type shortWriter struct {
+	limit int
+}
+
+func (s shortWriter) Write(p []byte) (int, error) {
+	return min(len(p), s.limit), nil
+}
+
+func TestDecompressReaderShortWrite(t *testing.T) {
+	require := require.New(t)
+
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	compressed, err := zstdCompressor.Compress(utils.RandomBytes(units.KiB))
+	require.NoError(err)
+
+	reader := NewDecompressReader(bytes.NewReader(compressed))
+	defer reader.Close()
+
+	written, err := reader.WriteTo(shortWriter{limit: 10})
+	require.ErrorIs(err, io.ErrShortWrite)
+	require.Equal(int64(10), written)
+}
+
+func TestDecompressReaderSourceError(t *testing.T) {
+	reader := NewDecompressReader(io.MultiReader(
+		bytes.NewReader(zstdZipBomb[:16]),
+		errReader{err: errTest},
+	))
+	defer reader.Close()
+
+	_, err := io.ReadAll(reader)
+	require.ErrorIs(t, err, errTest)
+}
+
+// readAvailable reads from s until it needs more input or reaches the end of
+// the stream.
+func readAvailable(t *testing.T, s *StreamDecompressor) ([]byte, bool) {
+	var (
+		decompressed []byte
+		buf          = make([]byte, 4*units.KiB)
+	)
+	for {
+		n, err := s.Read(buf)
+		decompressed = append(decompressed, buf[:n]...)
+		switch {
+		case errors.Is(err, ErrNeedMoreInput):
+			return decompressed, false
+		case err == io.EOF:
+			return decompressed, true
+		}
+		require.NoError(t, err)
+	}
+}
+
+func TestStreamDecompressor(t *testing.T) {
+	require := require.New(t)
+
+	msg := utils.RandomBytes(units.MiB)
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	compressed, err := zstdCompressor.Compress(msg)
+	require.NoError(err)
+
+	s := NewStreamDecompressor()
+	defer s.Close()
+
+	var decompressed []byte
+	for start := 0; start < len(compressed); start += 10 * units.KiB {
+		chunk := compressed[start:min(start+10*units.KiB, len(compressed))]
+		n, err := s.Write(chunk)
+		require.NoError(err)
+		require.Len(chunk, n)
+
+		available, done := readAvailable(t, s)
+		require.False(done)
+		decompressed = append(decompressed, available...)
+	}
+	require.NoError(s.CloseWrite())
+
+	rest, done := readAvailable(t, s)
+	require.True(done)
+	decompressed = append(decompressed, rest...)
+	require.Equal(msg, decompressed)
+}
+
+func TestStreamDecompressorPartialOutput(t *testing.T) {
+	require := require.New(t)
+
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	msg := utils.RandomBytes(units.MiB)
+	compressed, err := zstdCompressor.Compress(msg)
+	require.NoError(err)
+
+	s := NewStreamDecompressor()
+	defer s.Close()
+
+	// Random bytes are compressed into multiple zstd blocks, so some output is
+	// available once half of the frame has been written.
+	_, err = s.Write(compressed[:len(compressed)/2])
+	require.NoError(err)
+	available, done := readAvailable(t, s)
+	require.False(done)
+	require.NotEmpty(available)
+	require.Equal(msg[:len(available)], available)
+}
+
+func TestStreamDecompressorInvalidFormat(t *testing.T) {
+	require := require.New(t)
+
+	s := NewStreamDecompressor()
+	defer s.Close()
+
+	_, err := s.Write([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	require.NoError(err)
+	_, err = s.Read(make([]byte, units.KiB))
+	require.ErrorIs(err, ErrInvalidFormat)
+}
+
+func TestStreamDecompressorTruncated(t *testing.T) {
+	require := require.New(t)
+
+	zstdCompressor, err := NewZstdCompressor(maxMessageSize)
+	require.NoError(err)
+	compressed, err := zstdCompressor.Compress(utils.RandomBytes(units.MiB))
+	require.NoError(err)
+
+	s := NewStreamDecompressor()
+	defer s.Close()
+
+	_, err = s.Write(compressed[:len(compressed)-1])
+	require.NoError(err)
+	_, done := readAvailable(t, s)
+	require.False(done)
+	require.NoError(s.CloseWrite())
+
+	var readErr error
+	for readErr == nil {
+		_, readErr = s.Read(make([]byte, units.KiB))
+	}
+	require.ErrorIs(readErr, ErrTruncatedStream)
+}
+
+func TestStreamDecompressorClosed(t *testing.T) {
+	require := require.New(t)
+
+	s := NewStreamDecompressor()
+	require.NoError(s.CloseWrite())
+	_, err := s.Write([]byte{1})
+	require.ErrorIs(err, ErrClosed)
+	require.ErrorIs(s.CloseWrite(), ErrClosed)
+
+	require.NoError(s.Close())
+	_, err = s.Read(make([]byte, 1))
+	require.ErrorIs(err, ErrClosed)
+	require.ErrorIs(s.Close(), ErrClosed)
+}